@@ -1,5 +1,6 @@
 from cryptography.hazmat.primitives import hashes, serialization
 from cryptography.hazmat.primitives.asymmetric import padding
+from cryptography.hazmat.primitives.asymmetric.ed25519 import Ed25519PublicKey
 from cryptography.hazmat.backends import default_backend
 import base64
 
@@ -21,6 +22,10 @@ def sign_json_bytes(private_key, data_bytes: bytes) -> str:
 
 def verify_json_bytes(public_key, data_bytes: bytes, signature_b64: str) -> bool:
     try:
+        if isinstance(public_key, Ed25519PublicKey):
+            # Certificates from the NullBytes desktop app
+            public_key.verify(base64.b64decode(signature_b64), data_bytes)
+            return True
         public_key.verify(
             base64.b64decode(signature_b64),
             data_bytes,
@@ -25,6 +25,8 @@ def extract_payload_from_pdf_metadata(pdf_path: Path) -> dict | None:
 def main():
     parser = argparse.ArgumentParser(description="Offline PDF verifier for Secure Wipe Certificates")
     parser.add_argument("--pdf", required=True, help="Path to certificate PDF")
+    parser.add_argument("--key", default=PUBLIC_KEY_PEM,
+                        help="Trusted public key PEM (RSA, or Ed25519 from the desktop app's -export-public-key)")
     args = parser.parse_args()
 
     pdf_path = Path(args.pdf)
@@ -41,7 +43,7 @@ def main():
         print("[ERROR] Invalid payload format in PDF metadata.")
         return
 
-    public_key = load_public_key(PUBLIC_KEY_PEM)
+    public_key = load_public_key(args.key)
     ok = verify_json_bytes(public_key, canonical_json(payload_obj["cert"]), payload_obj["sig"])
 
     if ok:
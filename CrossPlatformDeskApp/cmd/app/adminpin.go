@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"data_wiper/internal/auth"
+	"data_wiper/internal/config"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// setAdminPIN reads a new administrator PIN, twice, from in and stores its
+// hash in the config file. When the administrator manages the policy
+// system-wide, the hash is printed for them to add to that file instead.
+func setAdminPIN(in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	readPIN := func(prompt string) (string, error) {
+		fmt.Fprint(out, prompt)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	pin, err := readPIN("New administrator PIN: ")
+	if err != nil {
+		return err
+	}
+	again, err := readPIN("Repeat PIN: ")
+	if err != nil {
+		return err
+	}
+	if pin != again {
+		return errors.New("PINs do not match")
+	}
+
+	hash, err := auth.HashPIN(pin)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.PolicySource != "" {
+		fmt.Fprintf(out, "The policy is set by the administrator in %s. Add the PIN there:\n\n  \"admin_pin_hash\": %q\n", cfg.PolicySource, hash)
+		return nil
+	}
+	cfg.Policy.AdminPINHash = hash
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	path, _ := config.Path()
+	fmt.Fprintf(out, "Administrator PIN saved to %s\n", path)
+	return nil
+}
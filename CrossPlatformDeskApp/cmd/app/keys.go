@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/x509"
+	"data_wiper/internal/certs"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// exportPublicKey writes the active signing key's public key as PEM, for
+// auditors who verify certificates.
+func exportPublicKey(out io.Writer) error {
+	keyring, err := certs.DefaultKeyring()
+	if err != nil {
+		return err
+	}
+	key, err := keyring.Active()
+	if err != nil {
+		return err
+	}
+	pem, err := certs.MarshalPublicKey(key.Public())
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(pem)
+	return err
+}
+
+// rotateSigningKey replaces the active signing key with a new one.
+func rotateSigningKey(out io.Writer) error {
+	keyring, err := certs.DefaultKeyring()
+	if err != nil {
+		return err
+	}
+	key, err := keyring.Rotate()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Certificates are now signed with key %s; older keys stay in %s for verification\n", key.ID, keyring.Dir)
+
+	// A machine running the local CA certifies its new key at once; others
+	// need a certificate from their CA again
+	_, ca, err := localCA()
+	if err != nil {
+		return err
+	}
+	if ca.Exists() {
+		if _, err := certifyActiveKey(keyring, ca); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Key %s is certified by the local CA\n", key.ID)
+	} else {
+		fmt.Fprintf(out, "If keys are certified by a CA, send it a new request from -signing-csr\n")
+	}
+	return nil
+}
+
+// printSigningCSR writes a certificate signing request for the active key,
+// for an organisation CA to certify.
+func printSigningCSR(out io.Writer) error {
+	keyring, err := certs.DefaultKeyring()
+	if err != nil {
+		return err
+	}
+	key, err := keyring.Active()
+	if err != nil {
+		return err
+	}
+	csr, err := certs.CSR(key, certs.KeySubject(key))
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(csr)
+	return err
+}
+
+// importSigningChain stores the chain a CA issued for a key in the
+// keyring, leaf first.
+func importSigningChain(path string, out io.Writer) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	chain, err := certs.ParseCertificates(data)
+	if err != nil {
+		return err
+	}
+	keyring, err := certs.DefaultKeyring()
+	if err != nil {
+		return err
+	}
+	if err := keyring.SetChain(chain); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Key %s is certified as %q by %q until %s\n", certs.PublicKeyID(chain[0].PublicKey), chain[0].Subject.String(), chain[0].Issuer.String(), chain[0].NotAfter.Format("2006-01-02"))
+	return nil
+}
+
+// initLocalCA creates this machine's local CA, certifies the active key
+// with it and writes the CA certificate for auditors to trust.
+func initLocalCA(organisation string, out io.Writer) error {
+	keyring, ca, err := localCA()
+	if err != nil {
+		return err
+	}
+	root, err := ca.Init(organisation, localCAValidity)
+	if err != nil {
+		return err
+	}
+	if _, err := certifyActiveKey(keyring, ca); err != nil {
+		return err
+	}
+	_, err = out.Write(certs.MarshalCertificates([]*x509.Certificate{root}))
+	return err
+}
+
+// issueCSR certifies another workstation's key with the local CA and
+// writes the chain to import there with -import-signing-chain.
+func issueCSR(path string, out io.Writer) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	_, ca, err := localCA()
+	if err != nil {
+		return err
+	}
+	if !ca.Exists() {
+		return fmt.Errorf("no local CA in %s; create one with -init-local-ca", ca.Dir)
+	}
+	chain, err := ca.IssueCSR(data)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(chain)
+	return err
+}
+
+// localCAValidity is how long a local CA's root certificate lasts.
+const localCAValidity = 10 * 365 * 24 * time.Hour
+
+func localCA() (*certs.Keyring, *certs.LocalCA, error) {
+	keyring, err := certs.DefaultKeyring()
+	if err != nil {
+		return nil, nil, err
+	}
+	dir, err := certs.LocalCADir()
+	if err != nil {
+		return nil, nil, err
+	}
+	return keyring, keyring.LocalCA(dir), nil
+}
+
+// certifyActiveKey issues the active key a certificate from ca.
+func certifyActiveKey(keyring *certs.Keyring, ca *certs.LocalCA) (*certs.Key, error) {
+	key, err := keyring.Active()
+	if err != nil {
+		return nil, err
+	}
+	chain, err := ca.Issue(key.Public(), certs.KeySubject(key))
+	if err != nil {
+		return nil, err
+	}
+	if err := keyring.SetChain(chain); err != nil {
+		return nil, err
+	}
+	key.Chain = chain
+	return key, nil
+}
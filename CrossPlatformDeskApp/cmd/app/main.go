@@ -2,9 +2,45 @@ package main
 
 import (r1 "github.com/gen2brain/raylib-go/raylib"
         "data_wiper/internal/ui"
+        "flag"
+        "fmt"
+        "os"
 )
 
 func main() {
+	setPIN := flag.Bool("set-admin-pin", false, "set the administrator PIN that unlocks internal disks, then exit")
+	exportKey := flag.Bool("export-public-key", false, "print the certificate signing public key as PEM, then exit")
+	rotateKey := flag.Bool("rotate-signing-key", false, "replace the certificate signing key with a new one, then exit")
+	signingCSR := flag.Bool("signing-csr", false, "print a certificate signing request for the signing key, for an organisation CA, then exit")
+	importChain := flag.String("import-signing-chain", "", "store the PEM certificate chain a CA issued for the signing key, then exit")
+	initCA := flag.String("init-local-ca", "", "create a local CA for the named organisation, certify the signing key and print the CA certificate, then exit")
+	issue := flag.String("issue-csr", "", "certify the key in a signing request from another workstation with the local CA and print its chain, then exit")
+	flag.Parse()
+	var command func() error
+	switch {
+	case *setPIN:
+		command = func() error { return setAdminPIN(os.Stdin, os.Stdout) }
+	case *exportKey:
+		command = func() error { return exportPublicKey(os.Stdout) }
+	case *rotateKey:
+		command = func() error { return rotateSigningKey(os.Stdout) }
+	case *signingCSR:
+		command = func() error { return printSigningCSR(os.Stdout) }
+	case *importChain != "":
+		command = func() error { return importSigningChain(*importChain, os.Stdout) }
+	case *initCA != "":
+		command = func() error { return initLocalCA(*initCA, os.Stdout) }
+	case *issue != "":
+		command = func() error { return issueCSR(*issue, os.Stdout) }
+	}
+	if command != nil {
+		if err := command(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	r1.InitWindow(880, 600, "Secure Byters")
 	defer r1.CloseWindow()
 	for !r1.WindowShouldClose() {
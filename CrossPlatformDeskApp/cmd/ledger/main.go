@@ -0,0 +1,269 @@
+// Command ledger checks the app's certificate ledger and proves what it
+// holds. Like cmd/verify it needs no cgo:
+//
+//	go run ./cmd/ledger verify
+//	go run ./cmd/ledger verify -head head-from-last-audit.json
+//	go run ./cmd/ledger head -sign > head.json
+//	go run ./cmd/ledger prove -log-hash 3f5a...
+//	go run ./cmd/ledger consistency -from 120
+//
+// verify exits 1 when it finds removed or altered entries.
+package main
+
+import (
+	"data_wiper/internal/certs"
+	"data_wiper/internal/ledger"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit statuses.
+const (
+	exitOK       = 0
+	exitTampered = 1
+	exitError    = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func usage(stderr io.Writer) {
+	fmt.Fprintf(stderr, `usage: ledger verify [-dir dir] [-key file]... [-ca file]... [-tsa-ca file]... [-keyring dir] [-head file]...
+       ledger head [-dir dir] [-sign]
+       ledger prove [-dir dir] -index n | -log-hash hash [-size n]
+       ledger consistency [-dir dir] -from n [-to n]
+`)
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitError
+	}
+	flags := flag.NewFlagSet("ledger "+args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("dir", "", "ledger `directory` (default: this machine's ledger)")
+	var err error
+	switch args[0] {
+	case "verify":
+		err = verify(flags, dir, args[1:], stdout, stderr)
+	case "head":
+		err = head(flags, dir, args[1:], stdout)
+	case "prove":
+		err = prove(flags, dir, args[1:], stdout)
+	case "consistency":
+		err = consistency(flags, dir, args[1:], stdout)
+	default:
+		usage(stderr)
+		return exitError
+	}
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errTampered):
+		return exitTampered
+	case errors.Is(err, flag.ErrHelp):
+		return exitError
+	default:
+		fmt.Fprintf(stderr, "ledger: %v\n", err)
+		return exitError
+	}
+}
+
+var errTampered = errors.New("ledger has been tampered with")
+
+func open(dir string) (*ledger.Ledger, error) {
+	if dir != "" {
+		return ledger.Open(dir), nil
+	}
+	return ledger.Default()
+}
+
+func verify(flags *flag.FlagSet, dir *string, args []string, stdout, stderr io.Writer) error {
+	var keyFiles, caFiles, tsaFiles, headFiles []string
+	repeated := func(list *[]string) func(string) error {
+		return func(path string) error {
+			*list = append(*list, path)
+			return nil
+		}
+	}
+	flags.Func("key", "trusted public key `file` (PEM); may be repeated", repeated(&keyFiles))
+	flags.Func("ca", "trusted CA certificate `file` (PEM); may be repeated", repeated(&caFiles))
+	flags.Func("tsa-ca", "trusted TSA root certificate `file` (PEM) (default: the system roots); may be repeated", repeated(&tsaFiles))
+	flags.Func("head", "signed tree head `file` kept from an earlier audit, which the ledger must still extend; may be repeated", repeated(&headFiles))
+	keyringDir := flags.String("keyring", "", "trust every public key in this keyring `directory` (default: this machine's keyring, when no -key or -ca is given)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	store, err := certs.LoadTrustStore(keyFiles, caFiles, *keyringDir)
+	if err != nil {
+		return err
+	}
+	if store.TSARoots, err = certs.LoadCertPool(tsaFiles); err != nil {
+		return err
+	}
+	var published []certs.Payload
+	for _, path := range headFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		p, err := certs.ParseJSON(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		published = append(published, p)
+	}
+	l, err := open(*dir)
+	if err != nil {
+		return err
+	}
+
+	report, err := l.Verify(store, published)
+	if err != nil {
+		return err
+	}
+	if !report.OK() {
+		for _, problem := range report.Problems {
+			fmt.Fprintf(stderr, "TAMPERED: %s\n", problem)
+		}
+		return errTampered
+	}
+	fmt.Fprintf(stdout, "INTACT: %d certificates, %d signed tree heads checked\n", report.Entries, report.Heads)
+	return nil
+}
+
+func head(flags *flag.FlagSet, dir *string, args []string, stdout io.Writer) error {
+	sign := flags.Bool("sign", false, "sign a head for the ledger as it is now with this machine's active signing key")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	l, err := open(*dir)
+	if err != nil {
+		return err
+	}
+	var p certs.Payload
+	if *sign {
+		keyring, err := certs.DefaultKeyring()
+		if err != nil {
+			return err
+		}
+		key, err := keyring.Active()
+		if err != nil {
+			return err
+		}
+		if p, err = l.SignHead(key); err != nil {
+			return err
+		}
+	} else {
+		heads, err := l.SignedHeads()
+		if err != nil {
+			return err
+		}
+		if len(heads) == 0 {
+			return errors.New("no signed tree head yet; use -sign")
+		}
+		p = heads[len(heads)-1]
+	}
+	data, err := certs.PayloadJSON(p)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s\n", data)
+	return nil
+}
+
+func prove(flags *flag.FlagSet, dir *string, args []string, stdout io.Writer) error {
+	index := flags.Int("index", -1, "prove the entry at this `index`")
+	logHash := flags.String("log-hash", "", "prove the entry holding the certificate with this log `hash`")
+	size := flags.Int("size", 0, "prove against the tree of this many entries (default: the latest signed head)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	l, err := open(*dir)
+	if err != nil {
+		return err
+	}
+	if *logHash != "" {
+		if *index, err = l.FindRecord(*logHash); err != nil {
+			return err
+		}
+	}
+	if *index < 0 {
+		return errors.New("give -index or -log-hash")
+	}
+	if *size == 0 {
+		if *size, err = latestSize(l); err != nil {
+			return err
+		}
+	}
+	proof, err := l.ProveInclusion(*index, *size)
+	if err != nil {
+		return err
+	}
+	if err := proof.Verify(); err != nil {
+		return err
+	}
+	return printJSON(stdout, proof)
+}
+
+func consistency(flags *flag.FlagSet, dir *string, args []string, stdout io.Writer) error {
+	from := flags.Int("from", -1, "size of the earlier tree, as its signed head gives it")
+	to := flags.Int("to", 0, "size of the later tree (default: the latest signed head)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *from < 0 {
+		return errors.New("give -from")
+	}
+	l, err := open(*dir)
+	if err != nil {
+		return err
+	}
+	if *to == 0 {
+		if *to, err = latestSize(l); err != nil {
+			return err
+		}
+	}
+	proof, err := l.ProveConsistency(*from, *to)
+	if err != nil {
+		return err
+	}
+	if err := proof.Verify(); err != nil {
+		return err
+	}
+	return printJSON(stdout, proof)
+}
+
+// latestSize is the size of the latest signed head, or of the whole
+// ledger when no head has been signed.
+func latestSize(l *ledger.Ledger) (int, error) {
+	heads, err := l.SignedHeads()
+	if err != nil {
+		return 0, err
+	}
+	if len(heads) == 0 {
+		head, err := l.Head()
+		return head.Size, err
+	}
+	head, err := ledger.ParseHead(heads[len(heads)-1])
+	if err != nil {
+		return 0, fmt.Errorf("latest signed head: %v", err)
+	}
+	return head.Size, nil
+}
+
+func printJSON(w io.Writer, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s\n", data)
+	return nil
+}
@@ -0,0 +1,150 @@
+// Command verify checks the signature of a NullBytes wipe certificate, PDF
+// or JSON, from the desktop app or CertificateTOOL, or of the verification
+// URL its QR code links to. It needs no cgo, so auditors can build it
+// without raylib:
+//
+//	go run ./cmd/verify -key nullbytes-signing.pub.pem certificate.pdf
+//
+// The exit status tells why a certificate was rejected.
+package main
+
+import (
+	"crypto/x509"
+	"data_wiper/internal/certs"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Exit statuses.
+const (
+	exitValid      = 0
+	exitTampered   = 1
+	exitUnknownKey = 2
+	exitSchema     = 3
+	exitError      = 4
+	exitUntrusted  = 5
+	exitTimestamp  = 6
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var keyFiles []string
+	flags.Func("key", "trusted public key `file` (PEM, Ed25519 or RSA); may be repeated", func(path string) error {
+		keyFiles = append(keyFiles, path)
+		return nil
+	})
+	var caFiles []string
+	flags.Func("ca", "trusted CA certificate `file` (PEM); certificates whose chain leads to it are accepted; may be repeated", func(path string) error {
+		caFiles = append(caFiles, path)
+		return nil
+	})
+	var tsaFiles []string
+	flags.Func("tsa-ca", "trusted TSA root certificate `file` (PEM) for RFC 3161 timestamps (default: the system roots); may be repeated", func(path string) error {
+		tsaFiles = append(tsaFiles, path)
+		return nil
+	})
+	keyringDir := flags.String("keyring", "", "trust every public key in this keyring `directory` (default: this machine's keyring, when no -key is given)")
+	quiet := flags.Bool("q", false, "print nothing on success")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: verify [-key file]... [-ca file]... [-tsa-ca file]... [-keyring dir] certificate.pdf|certificate.json|verification-url\n\n")
+		flags.PrintDefaults()
+		fmt.Fprintf(stderr, "\nexit status: %d valid, %d tampered, %d unknown key, %d not a signed certificate, %d other errors, %d untrusted or expired certificate chain, %d invalid or untrusted timestamp\n",
+			exitValid, exitTampered, exitUnknownKey, exitSchema, exitError, exitUntrusted, exitTimestamp)
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+
+	trusted, err := certs.LoadTrustStore(keyFiles, caFiles, *keyringDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "verify: %v\n", err)
+		return exitError
+	}
+	if trusted.TSARoots, err = certs.LoadCertPool(tsaFiles); err != nil {
+		fmt.Fprintf(stderr, "verify: %v\n", err)
+		return exitError
+	}
+	var payload certs.Payload
+	if target := flags.Arg(0); strings.Contains(target, "://") {
+		// A verification URL carries the payload in its fragment
+		payload, err = certs.DecodeFragment(target)
+	} else {
+		data, readErr := os.ReadFile(target)
+		if readErr != nil {
+			fmt.Fprintf(stderr, "verify: %v\n", readErr)
+			return exitError
+		}
+		payload, err = certs.ReadPayload(data)
+	}
+	var stamp *certs.TimestampInfo
+	if err == nil {
+		stamp, err = trusted.VerifyTimestamp(payload)
+	}
+	var leaf *x509.Certificate
+	if err == nil {
+		if stamp != nil {
+			// The token covers the signature, so the key had signed by
+			// the stamped time: its certificate is checked as of then
+			trusted.At = stamp.Time
+		}
+		leaf, err = trusted.Verify(payload)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, certs.ErrBadTimestamp):
+		fmt.Fprintf(stderr, "INVALID: %v\n", err)
+		return exitTimestamp
+	case errors.Is(err, certs.ErrUntrustedChain):
+		fmt.Fprintf(stderr, "INVALID: %v\n", err)
+		return exitUntrusted
+	case errors.Is(err, certs.ErrBadSignature):
+		fmt.Fprintf(stderr, "INVALID: %v; the certificate was altered after signing\n", err)
+		return exitTampered
+	case errors.Is(err, certs.ErrUnknownKey):
+		fmt.Fprintf(stderr, "INVALID: %v; it was not signed by a trusted key\n", err)
+		return exitUnknownKey
+	case errors.Is(err, certs.ErrSchema):
+		fmt.Fprintf(stderr, "INVALID: %v\n", err)
+		return exitSchema
+	default:
+		fmt.Fprintf(stderr, "verify: %v\n", err)
+		return exitError
+	}
+
+	if !*quiet {
+		signer := "a trusted key"
+		if payload.KeyID != "" {
+			signer = "key " + payload.KeyID
+		}
+		fmt.Fprintf(stdout, "VALID: signed by %s\n", signer)
+		if leaf != nil {
+			fmt.Fprintf(stdout, "Certified as %q by %q until %s\n", leaf.Subject.String(), leaf.Issuer.String(), leaf.NotAfter.Format("2006-01-02"))
+		}
+		if stamp != nil {
+			fmt.Fprintf(stdout, "Timestamped %s by %q\n", stamp.Time.UTC().Format(time.RFC3339), stamp.Signer.Subject.String())
+		} else {
+			fmt.Fprintf(stdout, "UNSTAMPED: its times come from the signing workstation's clock\n")
+		}
+		var cert any
+		if json.Unmarshal(payload.Cert, &cert) == nil {
+			pretty, _ := json.MarshalIndent(cert, "", "  ")
+			fmt.Fprintf(stdout, "%s\n", pretty)
+		}
+	}
+	return exitValid
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"data_wiper/internal/certs"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA creates a local CA in dir for organisation and writes its root
+// certificate to a PEM file, whose path is returned with the CA.
+func testCA(t *testing.T, keyring *certs.Keyring, dir, organisation string) (*certs.LocalCA, string) {
+	t.Helper()
+	ca := keyring.LocalCA(filepath.Join(dir, organisation))
+	root, err := ca.Init(organisation, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, organisation+".pem")
+	if err := os.WriteFile(path, certs.MarshalCertificates([]*x509.Certificate{root}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return ca, path
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	keysDir := filepath.Join(dir, "keys")
+	keyring := certs.OpenKeyring(keysDir, []byte("passphrase"))
+	key, err := keyring.Active()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKeys := filepath.Join(dir, "other-keys")
+	if _, err := certs.OpenKeyring(otherKeys, []byte("passphrase")).Active(); err != nil {
+		t.Fatal(err)
+	}
+	ca, caFile := testCA(t, keyring, dir, "Org")
+	_, otherCAFile := testCA(t, keyring, dir, "Other Org")
+	if key.Chain, err = ca.Issue(key.Public(), certs.KeySubject(key)); err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := certs.Sign(key, map[string]any{"wipe": map[string]any{"status": "success", "method": "purge"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := signed
+	tampered.Cert = []byte(strings.Replace(string(signed.Cert), "purge", "clear", 1))
+	stamped := signed
+	stamped.Timestamp = base64.StdEncoding.EncodeToString([]byte("not a token"))
+	files := map[string]certs.Payload{"valid.json": signed, "tampered.json": tampered, "stamped.json": stamped}
+	for name, p := range files {
+		data, err := certs.PayloadJSON(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "unsigned.json"), []byte(`{"wipe":{"status":"success"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		file   string
+		want   int
+		output string
+	}{
+		{"valid", []string{"-keyring", keysDir}, "valid.json", exitValid, "VALID: signed by key " + key.ID},
+		{"valid by CA", []string{"-ca", caFile}, "valid.json", exitValid, `Certified as "CN=`},
+		{"tampered", []string{"-keyring", keysDir}, "tampered.json", exitTampered, "altered after signing"},
+		{"unknown key", []string{"-keyring", otherKeys}, "valid.json", exitUnknownKey, "not signed by a trusted key"},
+		{"schema", []string{"-keyring", keysDir}, "unsigned.json", exitSchema, "not a signed certificate"},
+		{"missing file", []string{"-keyring", keysDir}, "gone.json", exitError, "gone.json"},
+		{"untrusted chain", []string{"-ca", otherCAFile}, "valid.json", exitUntrusted, "certificate chain not trusted"},
+		{"bad timestamp", []string{"-keyring", keysDir}, "stamped.json", exitTimestamp, "timestamp"},
+		{"no certificate", []string{"-keyring", keysDir}, "", exitError, "usage: verify"},
+	}
+	for _, tt := range tests {
+		args := tt.args
+		if tt.file != "" {
+			args = append(args, filepath.Join(dir, tt.file))
+		}
+		var stdout, stderr bytes.Buffer
+		got := run(args, &stdout, &stderr)
+		if got != tt.want {
+			t.Errorf("%s: exit %d, want %d; stderr %q", tt.name, got, tt.want, stderr.String())
+		}
+		if !strings.Contains(stdout.String()+stderr.String(), tt.output) {
+			t.Errorf("%s: output %q, want it to say %q", tt.name, stdout.String()+stderr.String(), tt.output)
+		}
+	}
+
+	// -q prints nothing on success, and still reports failures
+	var stdout, stderr bytes.Buffer
+	if got := run([]string{"-q", "-keyring", keysDir, filepath.Join(dir, "valid.json")}, &stdout, &stderr); got != exitValid || stdout.Len() != 0 {
+		t.Errorf("-q: exit %d, stdout %q", got, stdout.String())
+	}
+	if got := run([]string{"-q", "-keyring", keysDir, filepath.Join(dir, "tampered.json")}, &stdout, &stderr); got != exitTampered {
+		t.Errorf("-q with a tampered certificate: exit %d", got)
+	}
+}
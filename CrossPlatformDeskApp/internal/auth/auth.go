@@ -1 +1,84 @@
-package auth
\ No newline at end of file
+// Package auth guards administrator actions, such as unlocking internal
+// disks under the removable-only policy, with a PIN. Only a salted PBKDF2
+// hash of the PIN is stored.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	hashScheme     = "pbkdf2-sha256"
+	hashIterations = 200000
+	minPINLength   = 6
+)
+
+// HashPIN returns the stored form of pin:
+// pbkdf2-sha256$<iterations>$<salt>$<key>, base64 without padding.
+func HashPIN(pin string) (string, error) {
+	if len(pin) < minPINLength {
+		return "", fmt.Errorf("PIN must be at least %d characters", minPINLength)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := DeriveKey([]byte(pin), salt, hashIterations, sha256.Size)
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("%s$%d$%s$%s", hashScheme, hashIterations, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+// CheckPIN reports whether pin matches a hash made by HashPIN.
+func CheckPIN(hash, pin string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != hashScheme {
+		return false, errors.New("unrecognised PIN hash")
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return false, errors.New("unrecognised PIN hash")
+	}
+	enc := base64.RawStdEncoding
+	salt, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return false, errors.New("unrecognised PIN hash")
+	}
+	want, err := enc.DecodeString(parts[3])
+	if err != nil {
+		return false, errors.New("unrecognised PIN hash")
+	}
+	got := DeriveKey([]byte(pin), salt, iterations, len(want))
+	return hmac.Equal(got, want), nil
+}
+
+// DeriveKey is PBKDF2 with HMAC-SHA256 (RFC 8018, section 5.2). Besides
+// PIN hashes it derives the keys signing keys are encrypted with.
+func DeriveKey(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
@@ -1 +1,272 @@
-package certs
\ No newline at end of file
+package certs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+// Signature algorithms a Payload may name.
+const (
+	AlgEd25519 = "Ed25519"
+	// AlgRS256 is RSA PKCS #1 v1.5 with SHA-256, which CertificateTOOL
+	// signs with. Its payloads do not name the algorithm.
+	AlgRS256 = "RS256"
+)
+
+var (
+	// ErrBadSignature is returned when a certificate was altered after it
+	// was signed, or signed by a different key than it names.
+	ErrBadSignature = errors.New("signature does not match the certificate")
+	// ErrSchema is returned for data that is not a signed certificate.
+	ErrSchema = errors.New("not a signed certificate payload")
+)
+
+// Payload is a signed certificate in CertificateTOOL's embedding format,
+// {"cert": ..., "sig": ...}, with the algorithm and key ID added. Sig is
+// the base64 signature of Canonical(Cert). Chain is the signing key's X.509
+// chain, if it has one; see ParseChain. Timestamp is the base64 RFC 3161
+// token over the certificate and its signature, if it was stamped.
+type Payload struct {
+	Cert      json.RawMessage `json:"cert"`
+	Sig       string          `json:"sig"`
+	Alg       string          `json:"alg,omitempty"`
+	KeyID     string          `json:"kid,omitempty"`
+	Chain     []string        `json:"x5c,omitempty"`
+	Timestamp string          `json:"tst,omitempty"`
+}
+
+// Sign signs the canonical JSON of cert with key.
+func Sign(key *Key, cert any) (Payload, error) {
+	canonical, err := Canonical(cert)
+	if err != nil {
+		return Payload{}, err
+	}
+	return Payload{
+		Cert:  canonical,
+		Sig:   base64.StdEncoding.EncodeToString(ed25519.Sign(key.Private, canonical)),
+		Alg:   AlgEd25519,
+		KeyID: key.ID,
+		Chain: encodeChain(key.Chain),
+	}, nil
+}
+
+// Hash is the hex SHA-256 of the signed bytes of p.
+func (p Payload) Hash() (string, error) {
+	canonical, err := Canonical(p.Cert)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Verify checks p against the trusted public keys. A payload naming its
+// key must be signed by that key; one without a key ID, as CertificateTOOL
+// writes them, may be signed by any trusted key of its algorithm.
+func Verify(p Payload, trusted []crypto.PublicKey) error {
+	if len(p.Cert) == 0 || p.Sig == "" || !bytes.HasPrefix(bytes.TrimSpace(p.Cert), []byte("{")) {
+		return ErrSchema
+	}
+	if p.Alg != "" && p.Alg != AlgEd25519 && p.Alg != AlgRS256 {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrSchema, p.Alg)
+	}
+	sig, err := base64.StdEncoding.DecodeString(p.Sig)
+	if err != nil {
+		return fmt.Errorf("%w: signature is not base64", ErrSchema)
+	}
+	canonical, err := Canonical(p.Cert)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSchema, err)
+	}
+
+	candidates := 0
+	for _, pub := range trusted {
+		if p.KeyID != "" && PublicKeyID(pub) != p.KeyID {
+			continue
+		}
+		switch pub := pub.(type) {
+		case ed25519.PublicKey:
+			if p.Alg == AlgRS256 {
+				continue
+			}
+			candidates++
+			if ed25519.Verify(pub, canonical, sig) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if p.Alg == AlgEd25519 {
+				continue
+			}
+			candidates++
+			sum := sha256.Sum256(canonical)
+			if rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig) == nil {
+				return nil
+			}
+		}
+	}
+	if candidates == 0 {
+		if p.KeyID != "" {
+			return fmt.Errorf("%w %s", ErrUnknownKey, p.KeyID)
+		}
+		return fmt.Errorf("%w: no trusted key for this algorithm", ErrUnknownKey)
+	}
+	return ErrBadSignature
+}
+
+// ParseJSON reads a payload saved as JSON: either {"cert", "sig"} as
+// CertificateTOOL embeds it, or a certificate carrying its own signature
+// block, as the app writes them.
+func ParseJSON(data []byte) (Payload, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Payload{}, fmt.Errorf("%w: %v", ErrSchema, err)
+	}
+	if _, ok := fields["cert"]; ok {
+		var p Payload
+		if err := json.Unmarshal(data, &p); err != nil {
+			return Payload{}, fmt.Errorf("%w: %v", ErrSchema, err)
+		}
+		return p, nil
+	}
+
+	raw, ok := fields["signature"]
+	if !ok {
+		return Payload{}, fmt.Errorf("%w: neither a cert/sig pair nor a signature block", ErrSchema)
+	}
+	var sig struct {
+		Algorithm string     `json:"algorithm"`
+		KeyID     string     `json:"key_id"`
+		Sig       string     `json:"sig"`
+		Chain     []string   `json:"x5c"`
+		Timestamp *Timestamp `json:"timestamp"`
+	}
+	if err := json.Unmarshal(raw, &sig); err != nil {
+		return Payload{}, fmt.Errorf("%w: signature block: %v", ErrSchema, err)
+	}
+	delete(fields, "signature")
+	cert, err := json.Marshal(fields)
+	if err != nil {
+		return Payload{}, err
+	}
+	p := Payload{Cert: cert, Sig: sig.Sig, Alg: sig.Algorithm, KeyID: sig.KeyID, Chain: sig.Chain}
+	if sig.Timestamp != nil {
+		p.Timestamp = sig.Timestamp.Token
+	}
+	return p, nil
+}
+
+// PublicKeyID is KeyID for Ed25519 keys, and the same digest of the
+// PKIX encoding for others.
+func PublicKeyID(pub crypto.PublicKey) string {
+	if ed, ok := pub.(ed25519.PublicKey); ok {
+		return KeyID(ed)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Canonical encodes v as CertificateTOOL's canonical_json does:
+// json.dumps(v, separators=(",", ":"), sort_keys=True). Keys are sorted,
+// there is no whitespace, and every non-ASCII character is escaped, so the
+// Python and Go tools sign and verify the same bytes.
+func Canonical(v any) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	writeCanonical(&b, tree)
+	return b.Bytes(), nil
+}
+
+func writeCanonical(b *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		// Byte order of UTF-8 is code point order, which Python sorts by.
+		sort.Strings(keys)
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeString(b, k)
+			b.WriteByte(':')
+			writeCanonical(b, v[k])
+		}
+		b.WriteByte('}')
+	case []any:
+		b.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeCanonical(b, e)
+		}
+		b.WriteByte(']')
+	case string:
+		writeString(b, v)
+	case json.Number:
+		b.WriteString(v.String())
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case nil:
+		b.WriteString("null")
+	}
+}
+
+// writeString quotes s like Python's json module with ensure_ascii.
+func writeString(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\b':
+			b.WriteString(`\b`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r < 0x20 || (r > 0x7e && r < 0x10000):
+			fmt.Fprintf(b, `\u%04x`, r)
+		case r >= 0x10000:
+			r1, r2 := utf16.EncodeRune(r)
+			fmt.Fprintf(b, `\u%04x\u%04x`, r1, r2)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+}
@@ -0,0 +1,136 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestCanonicalMatchesPython(t *testing.T) {
+	in := json.RawMessage(`{"b": [1, 2.5, true, null, "x"], "a": {"z": "é € 😀 \u007f \u0001 \b\f\n\t\r \"q\" \\ <>&", "m": -3}, "Ä": 1, "Z": 0}`)
+	// json.dumps(obj, separators=(",", ":"), sort_keys=True)
+	want := `{"Z":0,"a":{"m":-3,"z":"\u00e9 \u20ac \ud83d\ude00 \u007f \u0001 \b\f\n\t\r \"q\" \\ <>&"},"b":[1,2.5,true,null,"x"],"\u00c4":1}`
+
+	got, err := Canonical(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Canonical =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCanonicalStruct(t *testing.T) {
+	cert := struct {
+		Wipe   map[string]int `json:"wipe"`
+		Device string         `json:"device"`
+	}{map[string]int{"passes": 3, "duration_sec": 90}, "Ultra Fit <USB>"}
+	got, err := Canonical(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"device":"Ultra Fit <USB>","wipe":{"duration_sec":90,"passes":3}}`; string(got) != want {
+		t.Errorf("Canonical = %s, want %s", got, want)
+	}
+}
+
+func testKey(t *testing.T) *Key {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Key{ID: KeyID(pub), Private: priv}
+}
+
+func TestSignVerify(t *testing.T) {
+	key := testKey(t)
+	other := testKey(t)
+	cert := map[string]any{"device": map[string]any{"name": "Ultra Fit", "serial": "4C530001230712114275"}, "wipe": map[string]any{"status": "success"}}
+
+	p, err := Sign(key, cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Alg != AlgEd25519 || p.KeyID != key.ID {
+		t.Errorf("payload names %s key %s", p.Alg, p.KeyID)
+	}
+	trusted := []crypto.PublicKey{other.Public(), key.Public()}
+	if err := Verify(p, trusted); err != nil {
+		t.Fatalf("Verify = %v", err)
+	}
+
+	// The payload survives a trip through indented JSON, as when a JSON
+	// certificate is pretty-printed.
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Payload
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(decoded, trusted); err != nil {
+		t.Errorf("Verify after re-encoding = %v", err)
+	}
+
+	tampered := p
+	tampered.Cert = json.RawMessage(`{"device":{"name":"Ultra Fit","serial":"4C530001230712114275"},"wipe":{"status":"failed"}}`)
+	if err := Verify(tampered, trusted); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("tampered certificate: err = %v", err)
+	}
+	if err := Verify(p, []crypto.PublicKey{other.Public()}); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("untrusted key: err = %v", err)
+	}
+
+	// A payload claiming a trusted key ID it was not signed with.
+	forged := p
+	forged.KeyID = other.ID
+	if err := Verify(forged, trusted); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("forged key ID: err = %v", err)
+	}
+}
+
+func TestVerifyCertificateTOOLPayload(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := json.RawMessage(`{"media":{"model":"SSD 860 EVO","serial_number":"S3Z9NB0K123456X"},"sanitization":{"method_type":"Purge"}}`)
+	canonical, err := Canonical(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(canonical)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// CertificateTOOL names neither the algorithm nor the key.
+	p := Payload{Cert: cert, Sig: base64.StdEncoding.EncodeToString(sig)}
+	if err := Verify(p, []crypto.PublicKey{testKey(t).Public(), &rsaKey.PublicKey}); err != nil {
+		t.Errorf("Verify = %v", err)
+	}
+}
+
+func TestVerifySchema(t *testing.T) {
+	trusted := []crypto.PublicKey{testKey(t).Public()}
+	for _, p := range []Payload{
+		{},
+		{Cert: json.RawMessage(`{"a":1}`)},
+		{Cert: json.RawMessage(`[1,2]`), Sig: "AAAA"},
+		{Cert: json.RawMessage(`{"a":1}`), Sig: "not base64!"},
+		{Cert: json.RawMessage(`{"a":1}`), Sig: "AAAA", Alg: "HS256"},
+	} {
+		if err := Verify(p, trusted); !errors.Is(err, ErrSchema) {
+			t.Errorf("Verify(%+v) = %v, want a schema error", p, err)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"data_wiper/internal/config"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	chainSuffix  = ".chain.pem"
+	caKeyFile    = "ca.key.pem"
+	caCertFile   = "ca.crt.pem"
+	leafValidity = 365 * 24 * time.Hour
+	// clockSkew backdates new certificates so a verifier whose clock is a
+	// little behind still accepts them
+	clockSkew = 5 * time.Minute
+)
+
+// ErrUntrustedChain is returned when a certificate's X.509 chain does not
+// lead to a trusted CA, has expired, or does not certify the signing key.
+var ErrUntrustedChain = errors.New("certificate chain not trusted")
+
+// CSR returns a PEM certificate signing request for key, for an
+// organisation CA to issue it a certificate.
+func CSR(key *Key, subject pkix.Name) ([]byte, error) {
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: subject}, key.Private)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+// ParseCertificates decodes every PEM certificate in data, in order.
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no PEM certificate found")
+	}
+	return chain, nil
+}
+
+// MarshalCertificates encodes chain as PEM, leaf first.
+func MarshalCertificates(chain []*x509.Certificate) []byte {
+	var data []byte
+	for _, c := range chain {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	return data
+}
+
+// ParseChain decodes a payload's x5c list: base64 DER certificates, leaf
+// first, as in JWS (RFC 7515).
+func ParseChain(x5c []string) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for i, s := range x5c {
+		der, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: x5c[%d] is not base64", ErrSchema, i)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("%w: x5c[%d]: %v", ErrSchema, i, err)
+		}
+		chain = append(chain, cert)
+	}
+	return chain, nil
+}
+
+func encodeChain(chain []*x509.Certificate) []string {
+	var x5c []string
+	for _, c := range chain {
+		x5c = append(x5c, base64.StdEncoding.EncodeToString(c.Raw))
+	}
+	return x5c
+}
+
+// SetChain stores the certificate chain an organisation CA issued for one
+// of the keyring's keys, leaf first. The leaf must certify that key.
+func (r *Keyring) SetChain(chain []*x509.Certificate) error {
+	if len(chain) == 0 {
+		return errors.New("empty certificate chain")
+	}
+	pub, ok := chain[0].PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return fmt.Errorf("certificate is for a %T, not an Ed25519 signing key", chain[0].PublicKey)
+	}
+	id := KeyID(pub)
+	if _, err := r.PublicKey(id); err != nil {
+		return fmt.Errorf("certificate is for key %s: %w", id, err)
+	}
+	return writeFile(filepath.Join(r.Dir, id+chainSuffix), MarshalCertificates(chain))
+}
+
+// Chain returns the certificate chain of the key with the given ID, or
+// nil if it has none.
+func (r *Keyring) Chain(id string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(filepath.Join(r.Dir, id+chainSuffix))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseCertificates(data)
+}
+
+// LocalCA is a certificate authority kept on one workstation, for teams
+// without an organisation CA: an encrypted Ed25519 root key and its
+// self-signed certificate. Auditors trust its certificate, and it
+// certifies the signing keys of the team's workstations.
+type LocalCA struct {
+	Dir        string
+	passphrase []byte
+}
+
+// LocalCADir is where the app keeps its local CA.
+func LocalCADir() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ca"), nil
+}
+
+// LocalCA opens the local CA in dir, whose key is encrypted with the
+// keyring's passphrase.
+func (r *Keyring) LocalCA(dir string) *LocalCA {
+	return &LocalCA{Dir: dir, passphrase: r.passphrase}
+}
+
+// Exists reports whether the CA has been created.
+func (ca *LocalCA) Exists() bool {
+	_, err := os.Stat(filepath.Join(ca.Dir, caCertFile))
+	return err == nil
+}
+
+// Init creates the CA with a self-signed root certificate for organisation.
+func (ca *LocalCA) Init(organisation string, validity time.Duration) (*x509.Certificate, error) {
+	if ca.Exists() {
+		return nil, fmt.Errorf("a CA already exists in %s", ca.Dir)
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{Organization: []string{organisation}, CommonName: organisation + " Wipe Certificate CA"},
+		NotBefore:             now.Add(-clockSkew),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := EncryptPrivateKey(priv, ca.passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(ca.Dir, caKeyFile), encrypted); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(ca.Dir, caCertFile), MarshalCertificates([]*x509.Certificate{cert})); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// Certificate returns the CA's root certificate, which verifiers trust.
+func (ca *LocalCA) Certificate() (*x509.Certificate, error) {
+	data, err := os.ReadFile(filepath.Join(ca.Dir, caCertFile))
+	if err != nil {
+		return nil, err
+	}
+	chain, err := ParseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	return chain[0], nil
+}
+
+// Issue certifies pub for a year as the signing key named by subject, and
+// returns the chain to store with it.
+func (ca *LocalCA) Issue(pub crypto.PublicKey, subject pkix.Name) ([]*x509.Certificate, error) {
+	root, err := ca.Certificate()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(ca.Dir, caKeyFile))
+	if err != nil {
+		return nil, err
+	}
+	priv, err := DecryptPrivateKey(data, ca.passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("CA key: %v", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      subject,
+		NotBefore:    now.Add(-clockSkew),
+		NotAfter:     now.Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if template.NotAfter.After(root.NotAfter) {
+		template.NotAfter = root.NotAfter
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, root, pub, priv)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return []*x509.Certificate{leaf}, nil
+}
+
+// IssueCSR certifies the key of a PEM certificate signing request from
+// another workstation and returns the chain as PEM.
+func (ca *LocalCA) IssueCSR(data []byte) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("no PEM certificate request found")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("certificate request: %v", err)
+	}
+	chain, err := ca.Issue(csr.PublicKey, csr.Subject)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalCertificates(chain), nil
+}
+
+// KeySubject is the subject a workstation's signing key is certified
+// under: the key ID and the host it signs on.
+func KeySubject(key *Key) pkix.Name {
+	host, _ := os.Hostname()
+	return pkix.Name{CommonName: "NullBytes signing key " + key.ID, OrganizationalUnit: []string{host}}
+}
+
+func randomSerial() *big.Int {
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	return serial
+}
+
+// TrustStore is what a verifier trusts: bare public keys, CA certificates
+// whose chains vouch for signing keys, and the roots of time-stamping
+// authorities.
+type TrustStore struct {
+	Keys  []crypto.PublicKey
+	Roots *x509.CertPool
+	// TSARoots are the CAs of trusted TSAs; nil means the system roots.
+	TSARoots *x509.CertPool
+	// At is the time chains must be valid at; zero means now.
+	At time.Time
+}
+
+// Verify checks p against the store. A payload whose chain leads to a
+// trusted root is accepted on the strength of its leaf certificate, which
+// is returned; otherwise the signing key itself must be trusted.
+func (s TrustStore) Verify(p Payload) (*x509.Certificate, error) {
+	var chainErr error
+	if s.Roots != nil && len(p.Chain) > 0 {
+		leaf, err := s.verifyChain(p)
+		if err == nil {
+			return leaf, Verify(p, []crypto.PublicKey{leaf.PublicKey})
+		}
+		if !errors.Is(err, ErrUntrustedChain) {
+			return nil, err
+		}
+		chainErr = err
+	}
+	err := Verify(p, s.Keys)
+	if errors.Is(err, ErrUnknownKey) && chainErr != nil {
+		return nil, chainErr
+	}
+	return nil, err
+}
+
+// verifyChain validates p's chain against the roots and returns its leaf.
+func (s TrustStore) verifyChain(p Payload) (*x509.Certificate, error) {
+	chain, err := ParseChain(p.Chain)
+	if err != nil {
+		return nil, err
+	}
+	leaf := chain[0]
+	if p.KeyID != "" && PublicKeyID(leaf.PublicKey) != p.KeyID {
+		return nil, fmt.Errorf("%w: it certifies key %s, not signing key %s", ErrUntrustedChain, PublicKeyID(leaf.PublicKey), p.KeyID)
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         s.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   s.At,
+		// Signing keys carry no extended key usage of their own
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUntrustedChain, err)
+	}
+	return leaf, nil
+}
+
+// LoadTrustStore loads the trusted key and CA certificate files a verifier
+// was given, plus the keys of keyringDir. Given none of them, it trusts
+// this machine's keyring and local CA.
+func LoadTrustStore(keyFiles, caFiles []string, keyringDir string) (TrustStore, error) {
+	var store TrustStore
+	for _, path := range keyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return store, err
+		}
+		keys, err := ParseTrustedKeys(data)
+		if err != nil {
+			return store, fmt.Errorf("%s: %v", path, err)
+		}
+		store.Keys = append(store.Keys, keys...)
+	}
+	roots, err := LoadCertPool(caFiles)
+	if err != nil {
+		return store, err
+	}
+	store.Roots = roots
+
+	if keyringDir == "" && len(keyFiles) == 0 && len(caFiles) == 0 {
+		dir, err := KeyringDir()
+		if err != nil {
+			return store, err
+		}
+		keyringDir = dir
+		// This machine's local CA, if it runs one
+		if caDir, err := LocalCADir(); err == nil {
+			if root, err := OpenKeyring(dir, nil).LocalCA(caDir).Certificate(); err == nil {
+				store.Roots = x509.NewCertPool()
+				store.Roots.AddCert(root)
+			}
+		}
+	}
+	if keyringDir != "" {
+		// Only public keys are read, so no passphrase is needed
+		keys, err := OpenKeyring(keyringDir, nil).Trusted()
+		if err != nil {
+			return store, fmt.Errorf("keyring %s: %v", keyringDir, err)
+		}
+		store.Keys = append(store.Keys, keys...)
+	}
+	if len(store.Keys) == 0 && store.Roots == nil {
+		return store, errors.New("no trusted keys; pass -key, -ca or -keyring")
+	}
+	return store, nil
+}
+
+// LoadCertPool reads the PEM certificates in files into a pool, or returns
+// nil when there are no files.
+func LoadCertPool(files []string) (*x509.CertPool, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	pool := x509.NewCertPool()
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		roots, err := ParseCertificates(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		for _, root := range roots {
+			pool.AddCert(root)
+		}
+	}
+	return pool, nil
+}
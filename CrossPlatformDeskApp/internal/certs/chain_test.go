@@ -0,0 +1,172 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA makes a CA certificate signed by parent, or self-signed when
+// parent is nil.
+func testCA(t *testing.T, name string, parent *x509.Certificate, parentKey ed25519.PrivateKey) (*x509.Certificate, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if parent == nil {
+		parent, parentKey = template, priv
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, priv
+}
+
+func pool(certs ...*x509.Certificate) *x509.CertPool {
+	p := x509.NewCertPool()
+	for _, c := range certs {
+		p.AddCert(c)
+	}
+	return p
+}
+
+func TestLocalCA(t *testing.T) {
+	dir := t.TempDir()
+	r := OpenKeyring(filepath.Join(dir, "keys"), []byte("passphrase"))
+	key, err := r.Active()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca := r.LocalCA(filepath.Join(dir, "ca"))
+	if ca.Exists() {
+		t.Fatal("CA exists before Init")
+	}
+	root, err := ca.Init("Acme Recycling", 5*365*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ca.Init("Acme Recycling", time.Hour); err == nil {
+		t.Error("Init replaced an existing CA")
+	}
+
+	// Another workstation's key is certified through a CSR
+	csr, err := CSR(key, KeySubject(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued, err := ca.IssueCSR(csr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := ParseCertificates(issued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetChain(chain); err != nil {
+		t.Fatal(err)
+	}
+	if key, err = r.Active(); err != nil {
+		t.Fatal(err)
+	}
+	if len(key.Chain) != 1 || key.Chain[0].Subject.CommonName != "NullBytes signing key "+key.ID {
+		t.Fatalf("chain of the active key = %v", key.Chain)
+	}
+
+	p, err := Sign(key, map[string]any{"wipe": map[string]any{"status": "success"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the CA is trusted, not the key itself
+	leaf, err := TrustStore{Roots: pool(root)}.Verify(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaf == nil || !leaf.Equal(key.Chain[0]) {
+		t.Errorf("Verify returned leaf %v", leaf)
+	}
+
+	// Past the leaf's expiry the chain no longer vouches for the key
+	expired := TrustStore{Roots: pool(root), At: key.Chain[0].NotAfter.Add(time.Hour)}
+	if _, err := expired.Verify(p); !errors.Is(err, ErrUntrustedChain) {
+		t.Errorf("Verify after expiry = %v, want an untrusted chain", err)
+	}
+	// A directly trusted key still verifies
+	expired.Keys = []crypto.PublicKey{key.Public()}
+	if _, err := expired.Verify(p); err != nil {
+		t.Errorf("Verify by a trusted key = %v", err)
+	}
+}
+
+func TestOrganisationChain(t *testing.T) {
+	root, rootKey := testCA(t, "Acme Root CA", nil, nil)
+	intermediate, intermediateKey := testCA(t, "Acme Workstations CA", root, rootKey)
+	other, _ := testCA(t, "Someone Else", nil, nil)
+
+	key := testKey(t)
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      KeySubject(key),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, intermediate, key.Public(), intermediateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.Chain = []*x509.Certificate{leaf, intermediate}
+
+	p, err := Sign(key, map[string]any{"device": map[string]any{"serial": "4C530001"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (TrustStore{Roots: pool(root)}).Verify(p); err != nil {
+		t.Errorf("Verify through the intermediate = %v", err)
+	}
+	if _, err := (TrustStore{Roots: pool(other)}).Verify(p); !errors.Is(err, ErrUntrustedChain) {
+		t.Errorf("Verify against another root = %v, want an untrusted chain", err)
+	}
+
+	// A chain for a different key does not vouch for this signature
+	impostor := testKey(t)
+	forged, err := Sign(impostor, map[string]any{"device": map[string]any{"serial": "4C530001"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged.Chain = p.Chain
+	if _, err := (TrustStore{Roots: pool(root)}).Verify(forged); !errors.Is(err, ErrUntrustedChain) {
+		t.Errorf("Verify with a borrowed chain = %v, want an untrusted chain", err)
+	}
+
+	// A keyring only takes chains for its own keys
+	r := OpenKeyring(t.TempDir(), []byte("passphrase"))
+	if err := r.SetChain(key.Chain); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("SetChain for a foreign key = %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package certs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EncodeFragment packs p for a verification URL the way CertificateTOOL's
+// encode_fragment_payload does: its canonical JSON, zlib-compressed at the
+// best level, in unpadded base64url.
+func EncodeFragment(p Payload) (string, error) {
+	data, err := PayloadJSON(p)
+	if err != nil {
+		return "", err
+	}
+	var z bytes.Buffer
+	w, _ := zlib.NewWriterLevel(&z, zlib.BestCompression)
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(z.Bytes()), nil
+}
+
+// DecodeFragment unpacks a payload packed by EncodeFragment or
+// CertificateTOOL. A whole verification URL may be given; only the part
+// after '#' is read. Padding is accepted but not required.
+func DecodeFragment(fragment string) (Payload, error) {
+	if _, after, ok := strings.Cut(fragment, "#"); ok {
+		fragment = after
+	}
+	compressed, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(fragment, "="))
+	if err != nil {
+		return Payload{}, fmt.Errorf("%w: fragment is not base64url: %v", ErrSchema, err)
+	}
+	r, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return Payload{}, fmt.Errorf("%w: fragment is not zlib data: %v", ErrSchema, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Payload{}, fmt.Errorf("%w: fragment is truncated: %v", ErrSchema, err)
+	}
+	return ParseJSON(data)
+}
+
+// VerificationURL is the verifier site's address for p, base/#fragment,
+// which is how CertificateTOOL links its certificates.
+func VerificationURL(base string, p Payload) (string, error) {
+	fragment, err := EncodeFragment(p)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(base, "/") + "/#" + fragment, nil
+}
@@ -0,0 +1,53 @@
+package certs
+
+import (
+	"crypto"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFragmentRoundTrip(t *testing.T) {
+	key := testKey(t)
+	p, err := Sign(key, map[string]any{"device": map[string]any{"name": "Ultra Fit (64 GB) ü", "serial": "4C530001"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := VerificationURL("https://example.github.io/Verifier_site/", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fragment, ok := strings.CutPrefix(url, "https://example.github.io/Verifier_site/#")
+	if !ok || strings.ContainsAny(fragment, "=+/") {
+		t.Fatalf("URL %s is not base/#base64url", url)
+	}
+
+	for _, s := range []string{url, fragment, fragment + strings.Repeat("=", -len(fragment)&3)} {
+		got, err := DecodeFragment(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Verify(got, []crypto.PublicKey{key.Public()}); err != nil {
+			t.Errorf("Verify of %.20s... = %v", s, err)
+		}
+	}
+}
+
+func TestDecodeCertificateTOOLFragment(t *testing.T) {
+	// encode_fragment_payload(make_embedded_payload(cert, "c2lnbmF0dXJl"))
+	const fragment = "eNodi7EKAjEQRH8lbKVwxaqnhaXCCfaChU3crF4gOSW70eLIv5tYzfDmzQzESWE_g-OPJ25tsrEmXIImawavZrHrzemwNLeM-CDoQDh5G6rTH7cbRFxB6eDr3_97ZB1fro4yJnbNVqtZGshELAKl2uKfldA6TPc4oLueA5QfBAIr7w"
+	p, err := DecodeFragment(fragment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"device":{"name":"Ultra Fit (64 GB) \u00fc","serial":"4C530001"},"wipe":{"method":"shred","status":"success"}}`
+	if string(p.Cert) != want || p.Sig != "c2lnbmF0dXJl" {
+		t.Errorf("decoded cert %s, sig %q", p.Cert, p.Sig)
+	}
+
+	for _, bad := range []string{"not base64!", "bm90IHpsaWI", fragment[:len(fragment)-10]} {
+		if _, err := DecodeFragment(bad); !errors.Is(err, ErrSchema) {
+			t.Errorf("DecodeFragment(%q) = %v, want a schema error", bad, err)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Package certs signs wipe certificates and manages the keys they are
+// signed with.
+package certs
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"data_wiper/internal/config"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PassphraseEnv names the environment variable holding the passphrase
+// private keys are encrypted with. Without it a random passphrase is kept
+// in the system keystore.
+const PassphraseEnv = "NULLBYTES_KEY_PASSPHRASE"
+
+const (
+	activeFile     = "active"
+	passphraseFile = "passphrase"
+	privateSuffix  = ".key.pem"
+	publicSuffix   = ".pub.pem"
+)
+
+// ErrUnknownKey is returned for a key ID the keyring has no key for.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key is a signing key pair.
+type Key struct {
+	// ID names the key in signatures; see KeyID.
+	ID      string
+	Private ed25519.PrivateKey
+	// Chain is the X.509 chain certifying the key, leaf first, if a CA
+	// has issued it one.
+	Chain []*x509.Certificate
+}
+
+// Public returns the public half of k.
+func (k *Key) Public() ed25519.PublicKey {
+	return k.Private.Public().(ed25519.PublicKey)
+}
+
+// KeyID is the first 8 bytes of the SHA-256 of a public key, in hex.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Keyring is a directory of signing keys: for each key an encrypted
+// private key <id>.key.pem and its public key <id>.pub.pem, plus a file
+// naming the active key. Public keys of rotated keys are kept so older
+// certificates still verify.
+type Keyring struct {
+	Dir        string
+	passphrase []byte
+}
+
+// OpenKeyring returns the keyring in dir, whose private keys are
+// encrypted with passphrase.
+func OpenKeyring(dir string, passphrase []byte) *Keyring {
+	return &Keyring{Dir: dir, passphrase: passphrase}
+}
+
+// KeyringDir is where the app keeps its signing keys.
+func KeyringDir() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "keys"), nil
+}
+
+// DefaultKeyring opens the app's keyring with the passphrase from
+// PassphraseEnv, or the one in the system keystore, created on first use.
+func DefaultKeyring() (*Keyring, error) {
+	dir, err := KeyringDir()
+	if err != nil {
+		return nil, err
+	}
+	return openKeyring(dir, os.Getenv(PassphraseEnv), keystore)
+}
+
+// Active loads the key new certificates are signed with, generating the
+// first key when the keyring is empty.
+func (r *Keyring) Active() (*Key, error) {
+	id, err := os.ReadFile(filepath.Join(r.Dir, activeFile))
+	if os.IsNotExist(err) {
+		return r.Rotate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read active key: %v", err)
+	}
+	return r.load(strings.TrimSpace(string(id)))
+}
+
+// Rotate generates a new key and makes it the active one. The private key
+// it replaces is deleted; its public key stays for verification.
+func (r *Keyring) Rotate() (*Key, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	key := &Key{ID: KeyID(pub), Private: priv}
+
+	encrypted, err := EncryptPrivateKey(priv, r.passphrase)
+	if err != nil {
+		return nil, err
+	}
+	public, err := MarshalPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(r.Dir, key.ID+privateSuffix), encrypted); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(r.Dir, key.ID+publicSuffix), public); err != nil {
+		return nil, err
+	}
+
+	previous, _ := os.ReadFile(filepath.Join(r.Dir, activeFile))
+	if err := writeFile(filepath.Join(r.Dir, activeFile), []byte(key.ID+"\n")); err != nil {
+		return nil, err
+	}
+	if old := strings.TrimSpace(string(previous)); old != "" && old != key.ID {
+		if err := os.Remove(filepath.Join(r.Dir, old+privateSuffix)); err != nil && !os.IsNotExist(err) {
+			return key, fmt.Errorf("rotated to %s, but failed to delete the old private key: %v", key.ID, err)
+		}
+	}
+	return key, nil
+}
+
+// PublicKey returns the public key with the given ID.
+func (r *Keyring) PublicKey(id string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(filepath.Join(r.Dir, id+publicSuffix))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w %s", ErrUnknownKey, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicKey(data)
+}
+
+// KeyIDs lists every key in the keyring, rotated ones included.
+func (r *Keyring) KeyIDs() ([]string, error) {
+	entries, err := os.ReadDir(r.Dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), publicSuffix); ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Trusted returns every public key in the keyring, for Verify.
+func (r *Keyring) Trusted() ([]crypto.PublicKey, error) {
+	ids, err := r.KeyIDs()
+	if err != nil {
+		return nil, err
+	}
+	var keys []crypto.PublicKey
+	for _, id := range ids {
+		pub, err := r.PublicKey(id)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", id, err)
+		}
+		keys = append(keys, pub)
+	}
+	return keys, nil
+}
+
+func (r *Keyring) load(id string) (*Key, error) {
+	data, err := os.ReadFile(filepath.Join(r.Dir, id+privateSuffix))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w %s: private key missing", ErrUnknownKey, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	priv, err := DecryptPrivateKey(data, r.passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %v", id, err)
+	}
+	key := &Key{ID: id, Private: priv}
+	if KeyID(key.Public()) != id {
+		return nil, fmt.Errorf("key %s: file holds key %s", id, KeyID(key.Public()))
+	}
+	if key.Chain, err = r.Chain(id); err != nil {
+		return nil, fmt.Errorf("key %s: certificate chain: %v", id, err)
+	}
+	return key, nil
+}
+
+// MarshalPublicKey encodes pub as a PEM "PUBLIC KEY" block, the form
+// openssl and Python's cryptography read.
+func MarshalPublicKey(pub ed25519.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// ParsePublicKey decodes an Ed25519 public key written by MarshalPublicKey.
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("no PEM public key found")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := pub.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("not an Ed25519 key but %T", pub)
+	}
+	return key, nil
+}
+
+// ParseTrustedKeys decodes every PEM public key in data: Ed25519 keys
+// from a keyring, or the RSA keys CertificateTOOL signs with.
+func ParseTrustedKeys(data []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		var pub crypto.PublicKey
+		var err error
+		switch block.Type {
+		case "PUBLIC KEY":
+			pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			pub, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, pub)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no PEM public key found")
+	}
+	return keys, nil
+}
+
+// writeFile replaces path atomically, readable by the owner only.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create key directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
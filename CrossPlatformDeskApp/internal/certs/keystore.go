@@ -0,0 +1,85 @@
+package certs
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errNoPassphrase is returned by a passphraseStore holding no passphrase
+// yet.
+var errNoPassphrase = errors.New("no key passphrase stored")
+
+// passphraseStore keeps the keyring passphrase in the operating system's
+// credential store, where it is protected by the user's login: the Secret
+// Service on Linux, the login keychain on macOS and DPAPI on Windows.
+type passphraseStore interface {
+	// Load returns the stored passphrase, or errNoPassphrase.
+	Load() ([]byte, error)
+	Store(passphrase []byte) error
+}
+
+// keystore is the credential store DefaultKeyring uses.
+var keystore passphraseStore = systemKeystore
+
+// openKeyring opens the keyring in dir with passphrase when it is set,
+// or with the one in store, which is created when the keyring has no keys
+// yet. A passphrase older versions kept in plain text beside the keys is
+// moved into store.
+func openKeyring(dir, passphrase string, store passphraseStore) (*Keyring, error) {
+	if passphrase != "" {
+		return OpenKeyring(dir, []byte(passphrase)), nil
+	}
+
+	secret, err := store.Load()
+	if err == nil {
+		return OpenKeyring(dir, secret), nil
+	}
+	if !errors.Is(err, errNoPassphrase) {
+		return nil, fmt.Errorf("cannot read the key passphrase from the system keystore (%v); set %s instead", err, PassphraseEnv)
+	}
+
+	legacy := filepath.Join(dir, passphraseFile)
+	if data, err := os.ReadFile(legacy); err == nil {
+		secret = []byte(strings.TrimSpace(string(data)))
+		if err := store.Store(secret); err != nil {
+			return nil, fmt.Errorf("cannot move the key passphrase in %s to the system keystore (%v); set %s to it instead", legacy, err, PassphraseEnv)
+		}
+		if err := removeSecretFile(legacy, len(data)); err != nil {
+			return nil, fmt.Errorf("moved the key passphrase to the system keystore, but failed to delete %s: %v", legacy, err)
+		}
+		return OpenKeyring(dir, secret), nil
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, activeFile)); err == nil {
+		return nil, fmt.Errorf("the signing keys in %s are encrypted with a passphrase the system keystore does not hold; set %s", dir, PassphraseEnv)
+	}
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	secret = []byte(base64.RawURLEncoding.EncodeToString(random))
+	if err := store.Store(secret); err != nil {
+		return nil, fmt.Errorf("cannot keep a key passphrase in the system keystore (%v); set %s instead", err, PassphraseEnv)
+	}
+	return OpenKeyring(dir, secret), nil
+}
+
+// removeSecretFile overwrites a file of size bytes before deleting it.
+func removeSecretFile(path string, size int) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	_, werr := file.Write(make([]byte, size))
+	serr := file.Sync()
+	file.Close()
+	if err := errors.Join(werr, serr); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,60 @@
+package certs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychain keeps the passphrase in the user's login keychain.
+type keychain struct{}
+
+var systemKeystore passphraseStore = keychain{}
+
+// errItemNotFound is security's exit status for a missing item.
+const errItemNotFound = 44
+
+func (keychain) Load() ([]byte, error) {
+	cmd := exec.Command("security", "find-generic-password", "-s", "nullbyters", "-a", "signing-key", "-w")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == errItemNotFound {
+		return nil, errNoPassphrase
+	}
+	if err != nil {
+		return nil, fmt.Errorf("security: %s", strings.TrimSpace(stderr.String()+" "+err.Error()))
+	}
+	return bytes.TrimSpace(out), nil
+}
+
+// Store adds the item. security only takes the password on its command
+// line, where any process can read it while it runs, so the command is
+// given to an interactive security on its standard input instead.
+func (keychain) Store(passphrase []byte) error {
+	if bytes.ContainsAny(passphrase, "\r\n") {
+		return errors.New("the passphrase holds a line break")
+	}
+	command := `add-generic-password -U -s nullbyters -a signing-key -l "NullBytes signing key passphrase" -w ` + securityQuote(string(passphrase)) + "\n"
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(command)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	// In interactive mode security reports a failed command on stderr and
+	// carries on, so its exit status alone does not tell
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("security: %s", strings.TrimSpace(stderr.String()+" "+err.Error()))
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("security: %s", msg)
+	}
+	return nil
+}
+
+// securityQuote quotes s as one argument of a security -i command line.
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
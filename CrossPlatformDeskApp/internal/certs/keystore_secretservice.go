@@ -0,0 +1,157 @@
+//go:build !windows && !darwin
+
+package certs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// sessionKeystore keeps the passphrase in the Secret Service when there is
+// a desktop session to reach it through. Wipes usually run as root, from
+// sudo or a service, where there is none; root then keeps the passphrase
+// in RootPassphraseFile, which only root can read.
+type sessionKeystore struct{}
+
+var systemKeystore passphraseStore = sessionKeystore{}
+
+// RootPassphraseFile is where root keeps the key passphrase when there is
+// no Secret Service to keep it in.
+var RootPassphraseFile = "/etc/nullbyters/signing-key.passphrase"
+
+// Test hooks.
+var (
+	geteuid = os.Geteuid
+	getenv  = os.Getenv
+)
+
+func (sessionKeystore) store() (passphraseStore, error) {
+	if hasSessionBus() {
+		return secretService{}, nil
+	}
+	if geteuid() == 0 {
+		return rootFile{RootPassphraseFile}, nil
+	}
+	return nil, fmt.Errorf("no desktop session (D-Bus session bus) to reach the Secret Service through; as root the passphrase is kept in %s instead", RootPassphraseFile)
+}
+
+func (k sessionKeystore) Load() ([]byte, error) {
+	store, err := k.store()
+	if err != nil {
+		return nil, err
+	}
+	return store.Load()
+}
+
+func (k sessionKeystore) Store(passphrase []byte) error {
+	store, err := k.store()
+	if err != nil {
+		return err
+	}
+	return store.Store(passphrase)
+}
+
+// hasSessionBus reports whether secret-tool can find a D-Bus session bus:
+// named in the environment, or at its usual place in the runtime directory.
+func hasSessionBus() bool {
+	if getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+		return true
+	}
+	runtime := getenv("XDG_RUNTIME_DIR")
+	if runtime == "" {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(runtime, "bus"))
+	return err == nil && info.Mode().Type() == fs.ModeSocket
+}
+
+// rootFile keeps the passphrase in a file owned by root that nobody else
+// can read.
+type rootFile struct{ path string }
+
+func (f rootFile) Load() ([]byte, error) {
+	info, err := os.Lstat(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errNoPassphrase
+	}
+	if err != nil {
+		return nil, err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !info.Mode().IsRegular() || !ok || st.Uid != 0 || info.Mode().Perm()&0o077 != 0 {
+		return nil, fmt.Errorf("%s must be a regular file owned by root that only root can read", f.path)
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(data), nil
+}
+
+func (f rootFile) Store(passphrase []byte) error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	_, werr := file.Write(append(passphrase, '\n'))
+	serr := file.Sync()
+	if err := errors.Join(werr, serr, file.Close()); err != nil {
+		os.Remove(f.path)
+		return err
+	}
+	return nil
+}
+
+// secretService keeps the passphrase in the desktop's Secret Service
+// (GNOME Keyring, KWallet) through libsecret's secret-tool.
+type secretService struct{}
+
+var secretAttributes = []string{"service", "nullbyters", "account", "signing-key"}
+
+func (secretService) Load() ([]byte, error) {
+	cmd := exec.Command("secret-tool", append([]string{"lookup"}, secretAttributes...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exit *exec.ExitError
+	// secret-tool fails silently when nothing matches
+	if errors.As(err, &exit) && len(out) == 0 && stderr.Len() == 0 {
+		return nil, errNoPassphrase
+	}
+	if err != nil {
+		return nil, secretToolError(err, stderr.String())
+	}
+	return bytes.TrimSpace(out), nil
+}
+
+func (secretService) Store(passphrase []byte) error {
+	args := append([]string{"store", "--label=NullBytes signing key passphrase"}, secretAttributes...)
+	cmd := exec.Command("secret-tool", args...)
+	cmd.Stdin = bytes.NewReader(passphrase)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return secretToolError(err, stderr.String())
+	}
+	return nil
+}
+
+func secretToolError(err error, stderr string) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return errors.New("secret-tool is not installed")
+	}
+	if msg := strings.TrimSpace(stderr); msg != "" {
+		return fmt.Errorf("secret-tool: %s", msg)
+	}
+	return fmt.Errorf("secret-tool: %v", err)
+}
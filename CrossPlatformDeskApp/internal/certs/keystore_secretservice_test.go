@@ -0,0 +1,107 @@
+//go:build !windows && !darwin
+
+package certs
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sessionHost sets the environment and effective user sessionKeystore
+// sees, and points RootPassphraseFile into a temp directory.
+func sessionHost(t *testing.T, env map[string]string, euid int) {
+	t.Helper()
+	savedEnv, savedEuid, savedFile := getenv, geteuid, RootPassphraseFile
+	t.Cleanup(func() { getenv, geteuid, RootPassphraseFile = savedEnv, savedEuid, savedFile })
+	getenv = func(key string) string { return env[key] }
+	geteuid = func() int { return euid }
+	RootPassphraseFile = filepath.Join(t.TempDir(), "nullbyters", "signing-key.passphrase")
+}
+
+func TestSessionKeystore(t *testing.T) {
+	runtime := t.TempDir()
+	bus, err := net.Listen("unix", filepath.Join(runtime, "bus"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		euid int
+		want passphraseStore
+	}{
+		{"desktop user", map[string]string{"DBUS_SESSION_BUS_ADDRESS": "unix:path=/run/user/1000/bus"}, 1000, secretService{}},
+		{"bus in the runtime dir", map[string]string{"XDG_RUNTIME_DIR": runtime}, 1000, secretService{}},
+		{"root in a desktop session", map[string]string{"DBUS_SESSION_BUS_ADDRESS": "unix:path=/run/user/0/bus"}, 0, secretService{}},
+		{"root from sudo", map[string]string{"XDG_RUNTIME_DIR": t.TempDir()}, 0, rootFile{}},
+		{"root service", nil, 0, rootFile{}},
+		{"user without a session", nil, 1000, nil},
+	}
+	for _, tt := range tests {
+		sessionHost(t, tt.env, tt.euid)
+		if _, ok := tt.want.(rootFile); ok {
+			tt.want = rootFile{RootPassphraseFile}
+		}
+		got, err := sessionKeystore{}.store()
+		if got != tt.want {
+			t.Errorf("%s: store = %#v, want %#v", tt.name, got, tt.want)
+		}
+		if tt.want == nil && (err == nil || !strings.Contains(err.Error(), "D-Bus session bus")) {
+			t.Errorf("%s: err = %v, want one naming the missing session bus", tt.name, err)
+		}
+	}
+}
+
+func TestRootFile(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("the passphrase file must be owned by root")
+	}
+	sessionHost(t, nil, 0)
+	store := sessionKeystore{}
+	if _, err := store.Load(); !errors.Is(err, errNoPassphrase) {
+		t.Fatalf("Load before Store = %v", err)
+	}
+
+	// A keyring opened as root without a session keeps its passphrase there
+	dir := t.TempDir()
+	if _, err := openKeyring(dir, "", store); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(RootPassphraseFile)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("passphrase file %v, %v", info, err)
+	}
+	secret, err := store.Load()
+	if err != nil || len(secret) < 32 {
+		t.Fatalf("Load = %q, %v", secret, err)
+	}
+	r, err := openKeyring(dir, "", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Active(); err != nil {
+		t.Errorf("reopened keyring: %v", err)
+	}
+	// It is never overwritten
+	if err := store.Store([]byte("another")); err == nil {
+		t.Error("Store replaced the passphrase file")
+	}
+	if again, _ := store.Load(); !bytes.Equal(again, secret) {
+		t.Errorf("passphrase now %q", again)
+	}
+
+	// Nor trusted once others can read it
+	if err := os.Chmod(RootPassphraseFile, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(); err == nil || !strings.Contains(err.Error(), "only root can read") {
+		t.Errorf("Load of a world-readable file = %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+//go:build windows
+
+package certs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	crypt32            = syscall.NewLazyDLL("crypt32.dll")
+	cryptProtectData   = crypt32.NewProc("CryptProtectData")
+	cryptUnprotectData = crypt32.NewProc("CryptUnprotectData")
+	localFree          = syscall.NewLazyDLL("kernel32.dll").NewProc("LocalFree")
+)
+
+// dpapi keeps the passphrase beside the keys, encrypted with DPAPI under
+// the user's login credentials.
+type dpapi struct{}
+
+var systemKeystore passphraseStore = dpapi{}
+
+// dataBlob is DPAPI's DATA_BLOB.
+type dataBlob struct {
+	size uint32
+	data *byte
+}
+
+func newBlob(b []byte) *dataBlob {
+	if len(b) == 0 {
+		return &dataBlob{}
+	}
+	return &dataBlob{size: uint32(len(b)), data: &b[0]}
+}
+
+func (b *dataBlob) bytes() []byte {
+	out := make([]byte, b.size)
+	copy(out, unsafe.Slice(b.data, b.size))
+	return out
+}
+
+func protectedPassphrasePath() (string, error) {
+	dir, err := KeyringDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, passphraseFile+".dpapi"), nil
+}
+
+func (dpapi) Load() ([]byte, error) {
+	path, err := protectedPassphrasePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, errNoPassphrase
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out dataBlob
+	r, _, callErr := cryptUnprotectData.Call(uintptr(unsafe.Pointer(newBlob(data))), 0, 0, 0, 0, 0, uintptr(unsafe.Pointer(&out)))
+	if r == 0 {
+		return nil, fmt.Errorf("failed to decrypt %s: %v", path, callErr)
+	}
+	defer localFree.Call(uintptr(unsafe.Pointer(out.data)))
+	return out.bytes(), nil
+}
+
+func (dpapi) Store(passphrase []byte) error {
+	path, err := protectedPassphrasePath()
+	if err != nil {
+		return err
+	}
+	var out dataBlob
+	r, _, callErr := cryptProtectData.Call(uintptr(unsafe.Pointer(newBlob(passphrase))), 0, 0, 0, 0, 0, uintptr(unsafe.Pointer(&out)))
+	if r == 0 {
+		return fmt.Errorf("failed to encrypt the key passphrase: %v", callErr)
+	}
+	defer localFree.Call(uintptr(unsafe.Pointer(out.data)))
+	return writeFile(path, out.bytes())
+}
@@ -1 +1,630 @@
-package certs
\ No newline at end of file
+package certs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+const (
+	// InfoKey is the document information entry CertificateTOOL stores
+	// the payload in.
+	InfoKey = "CertPayload"
+	// PayloadFileName names the payload when it is attached to a PDF.
+	PayloadFileName = "certificate.json"
+)
+
+// ReadPayload extracts the signed payload from a certificate PDF or JSON
+// file.
+func ReadPayload(data []byte) (Payload, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("%PDF-")) {
+		return ReadPDF(data)
+	}
+	return ParseJSON(data)
+}
+
+// ReadPDF extracts the signed payload from a certificate PDF: the
+// /CertPayload entry of its document information, or failing that an
+// attached JSON file holding the payload.
+func ReadPDF(data []byte) (Payload, error) {
+	f := &pdfFile{data: data}
+	info, err := f.info()
+	if err != nil {
+		return Payload{}, err
+	}
+	if v, ok := info[InfoKey]; ok {
+		v, err := f.resolve(v)
+		if err != nil {
+			return Payload{}, err
+		}
+		s, ok := v.(pdfString)
+		if !ok {
+			return Payload{}, fmt.Errorf("%w: /%s is not a string", ErrSchema, InfoKey)
+		}
+		return ParseJSON(s.text())
+	}
+	files, err := f.embeddedFiles()
+	if err != nil {
+		return Payload{}, err
+	}
+	for _, file := range files {
+		if p, err := ParseJSON(file); err == nil {
+			return p, nil
+		}
+	}
+	return Payload{}, fmt.Errorf("%w: the PDF has no /%s entry or attached payload", ErrSchema, InfoKey)
+}
+
+// PayloadJSON is p as embedded in PDFs: canonical JSON, as CertificateTOOL
+// writes it.
+func PayloadJSON(p Payload) ([]byte, error) {
+	return Canonical(p)
+}
+
+// EmbedPayload stores p in the /CertPayload entry of a PDF's document
+// information. The new entry is appended as an incremental update, so the
+// original bytes stay untouched.
+func EmbedPayload(pdf []byte, p Payload) ([]byte, error) {
+	payload, err := PayloadJSON(p)
+	if err != nil {
+		return nil, err
+	}
+	f := &pdfFile{data: pdf}
+	trailers := trailerStart.FindAllIndex(pdf, -1)
+	xrefs := startXref.FindAllSubmatch(pdf, -1)
+	if trailers == nil || xrefs == nil {
+		return nil, errors.New("PDF has no trailer; cross-reference streams are not supported")
+	}
+	tp := &pdfParser{b: pdf, pos: trailers[len(trailers)-1][1] - 2}
+	trailer, err := tp.value()
+	if err != nil {
+		return nil, fmt.Errorf("malformed PDF trailer: %v", err)
+	}
+	size, ok := trailer.(pdfDict)["Size"].(int)
+	if !ok {
+		return nil, errors.New("PDF trailer has no /Size")
+	}
+
+	prev, _ := strconv.Atoi(string(xrefs[len(xrefs)-1][1]))
+
+	info, err := f.info()
+	if err != nil {
+		info = pdfDict{}
+	}
+	info[InfoKey] = pdfString(payload)
+
+	var b bytes.Buffer
+	b.Write(pdf)
+	if !bytes.HasSuffix(pdf, []byte("\n")) {
+		b.WriteByte('\n')
+	}
+	offset := b.Len()
+	fmt.Fprintf(&b, "%d 0 obj\n", size)
+	writePDFValue(&b, info)
+	b.WriteString("\nendobj\n")
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n%d 1\n%010d 00000 n \ntrailer\n", size, offset)
+	update := pdfDict{"Size": size + 1, "Info": pdfRef{size, 0}, "Prev": prev}
+	for _, key := range []pdfName{"Root", "ID", "Encrypt"} {
+		if v, ok := trailer.(pdfDict)[key]; ok {
+			update[key] = v
+		}
+	}
+	writePDFValue(&b, update)
+	fmt.Fprintf(&b, "\nstartxref\n%d\n%%%%EOF\n", xref)
+	return b.Bytes(), nil
+}
+
+// writePDFValue serializes a value read by pdfParser.
+func writePDFValue(b *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case pdfDict:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, string(k))
+		}
+		sort.Strings(keys)
+		b.WriteString("<<")
+		for _, k := range keys {
+			b.WriteByte(' ')
+			writePDFValue(b, pdfName(k))
+			b.WriteByte(' ')
+			writePDFValue(b, v[pdfName(k)])
+		}
+		b.WriteString(" >>")
+	case []any:
+		b.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			writePDFValue(b, e)
+		}
+		b.WriteByte(']')
+	case pdfName:
+		b.WriteByte('/')
+		for _, c := range []byte(v) {
+			if c <= ' ' || c > '~' || c == '#' || isPDFDelimiter(c) {
+				fmt.Fprintf(b, "#%02X", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	case pdfString:
+		b.WriteByte('(')
+		for _, c := range v {
+			switch {
+			case c == '(' || c == ')' || c == '\\':
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			case c < ' ' || c > '~':
+				fmt.Fprintf(b, "\\%03o", c)
+			default:
+				b.WriteByte(c)
+			}
+		}
+		b.WriteByte(')')
+	case pdfRef:
+		fmt.Fprintf(b, "%d %d R", v.num, v.gen)
+	case int:
+		b.WriteString(strconv.Itoa(v))
+	case float64:
+		b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case nil:
+		b.WriteString("null")
+	}
+}
+
+// maxResolveDepth bounds how many objects may be resolved inside one
+// another, as a stream's /Length is while its stream is read.
+const maxResolveDepth = 32
+
+// pdfFile reads objects from a PDF written without object streams, as
+// gofpdf, ReportLab and PyPDF2 write them. Objects redefined by incremental
+// updates resolve to their last definition.
+type pdfFile struct {
+	data []byte
+	// objects maps each object to the offset of its body, built on first
+	// use.
+	objects map[pdfRef]int
+	// resolving holds the objects being resolved, to catch references
+	// back into themselves.
+	resolving map[pdfRef]bool
+}
+
+type (
+	pdfName   string
+	pdfString []byte
+	pdfDict   map[pdfName]any
+	pdfRef    struct{ num, gen int }
+	pdfStream struct {
+		dict pdfDict
+		data []byte
+	}
+)
+
+var (
+	infoRef      = regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
+	objHeader    = regexp.MustCompile(`(?:^|[^\d])(\d+)\s+(\d+)\s+obj\b`)
+	trailerStart = regexp.MustCompile(`trailer\s*<<`)
+	startXref    = regexp.MustCompile(`startxref\s+(\d+)`)
+)
+
+// info returns the document information dictionary named by the last
+// trailer.
+func (f *pdfFile) info() (pdfDict, error) {
+	refs := infoRef.FindAllSubmatch(f.data, -1)
+	if refs == nil {
+		return nil, fmt.Errorf("%w: the PDF has no document information", ErrSchema)
+	}
+	last := refs[len(refs)-1]
+	num, _ := strconv.Atoi(string(last[1]))
+	gen, _ := strconv.Atoi(string(last[2]))
+	v, err := f.resolve(pdfRef{num, gen})
+	if err != nil {
+		return nil, err
+	}
+	info, ok := v.(pdfDict)
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed document information", ErrSchema)
+	}
+	return info, nil
+}
+
+// index returns the offset of every object's body, scanning the file the
+// first time it is needed.
+func (f *pdfFile) index() map[pdfRef]int {
+	if f.objects == nil {
+		f.objects = make(map[pdfRef]int)
+		for _, m := range objHeader.FindAllSubmatchIndex(f.data, -1) {
+			num, _ := strconv.Atoi(string(f.data[m[2]:m[3]]))
+			gen, _ := strconv.Atoi(string(f.data[m[4]:m[5]]))
+			f.objects[pdfRef{num, gen}] = m[1]
+		}
+	}
+	return f.objects
+}
+
+// resolve follows v if it is a reference; unknown or unparsable objects
+// are nil. A reference back into an object being resolved is an error.
+func (f *pdfFile) resolve(v any) (any, error) {
+	ref, ok := v.(pdfRef)
+	if !ok {
+		return v, nil
+	}
+	start, ok := f.index()[ref]
+	if !ok {
+		return nil, nil
+	}
+	if f.resolving[ref] {
+		return nil, fmt.Errorf("%w: object %d %d refers to itself", ErrSchema, ref.num, ref.gen)
+	}
+	if len(f.resolving) >= maxResolveDepth {
+		return nil, fmt.Errorf("%w: object references nested too deeply", ErrSchema)
+	}
+	if f.resolving == nil {
+		f.resolving = make(map[pdfRef]bool)
+	}
+	f.resolving[ref] = true
+	defer delete(f.resolving, ref)
+
+	obj, err := f.parseObject(start)
+	if errors.Is(err, ErrSchema) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, nil
+	}
+	return obj, nil
+}
+
+// embeddedFiles returns the decoded contents of every attached file, in
+// the order they appear.
+func (f *pdfFile) embeddedFiles() ([][]byte, error) {
+	offsets := make([]int, 0, len(f.index()))
+	for _, offset := range f.index() {
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+
+	var files [][]byte
+	for _, offset := range offsets {
+		obj, err := f.parseObject(offset)
+		if errors.Is(err, ErrSchema) {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+		s, ok := obj.(pdfStream)
+		if !ok || s.dict["Type"] != pdfName("EmbeddedFile") {
+			continue
+		}
+		data, err := f.decode(s)
+		if errors.Is(err, ErrSchema) {
+			return nil, err
+		}
+		if err == nil {
+			files = append(files, data)
+		}
+	}
+	return files, nil
+}
+
+// parseObject parses the object whose body starts at offset, along with
+// its stream if it has one.
+func (f *pdfFile) parseObject(offset int) (any, error) {
+	p := &pdfParser{b: f.data, pos: offset}
+	obj, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := obj.(pdfDict)
+	if !ok || !p.keyword("stream") {
+		return obj, nil
+	}
+
+	// The stream keyword ends with CRLF or LF
+	start := p.pos
+	if bytes.HasPrefix(f.data[start:], []byte("\r\n")) {
+		start += 2
+	} else if start < len(f.data) && f.data[start] == '\n' {
+		start++
+	}
+	v, err := f.resolve(dict["Length"])
+	if err != nil {
+		return nil, err
+	}
+	length, ok := v.(int)
+	if !ok || length < 0 || start+length > len(f.data) {
+		end := bytes.Index(f.data[start:], []byte("endstream"))
+		if end < 0 {
+			return nil, errors.New("unterminated stream")
+		}
+		length = end
+	}
+	return pdfStream{dict: dict, data: f.data[start : start+length]}, nil
+}
+
+// decode returns the contents of s, inflated if it is compressed.
+func (f *pdfFile) decode(s pdfStream) ([]byte, error) {
+	filter, err := f.resolve(s.dict["Filter"])
+	if err != nil {
+		return nil, err
+	}
+	switch filter := filter.(type) {
+	case nil:
+		return s.data, nil
+	case pdfName:
+		if filter != "FlateDecode" {
+			return nil, fmt.Errorf("unsupported stream filter %s", filter)
+		}
+		r, err := zlib.NewReader(bytes.NewReader(s.data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	default:
+		return nil, errors.New("unsupported stream filter")
+	}
+}
+
+// text decodes a PDF text string: UTF-16BE after a byte order mark,
+// otherwise PDFDocEncoding, which matches Latin-1 for the characters JSON
+// needs.
+func (s pdfString) text() []byte {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return []byte(string(utf16.Decode(units)))
+	}
+	runes := make([]rune, len(s))
+	for i, c := range s {
+		runes[i] = rune(c)
+	}
+	return []byte(string(runes))
+}
+
+// pdfParser reads PDF objects (ISO 32000-1, section 7.3).
+type pdfParser struct {
+	b   []byte
+	pos int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func (p *pdfParser) skipSpace() {
+	for p.pos < len(p.b) {
+		switch c := p.b[p.pos]; {
+		case isPDFSpace(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.b) && p.b[p.pos] != '\r' && p.b[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// keyword consumes kw if it is the next token.
+func (p *pdfParser) keyword(kw string) bool {
+	p.skipSpace()
+	end := p.pos + len(kw)
+	if end > len(p.b) || string(p.b[p.pos:end]) != kw {
+		return false
+	}
+	if end < len(p.b) && !isPDFSpace(p.b[end]) && !isPDFDelimiter(p.b[end]) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *pdfParser) value() (any, error) {
+	p.skipSpace()
+	if p.pos >= len(p.b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	switch c := p.b[p.pos]; {
+	case c == '<' && p.pos+1 < len(p.b) && p.b[p.pos+1] == '<':
+		return p.dict()
+	case c == '<':
+		return p.hexString()
+	case c == '(':
+		return p.literalString()
+	case c == '[':
+		return p.array()
+	case c == '/':
+		return p.name(), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return p.number()
+	case p.keyword("true"):
+		return true, nil
+	case p.keyword("false"):
+		return false, nil
+	case p.keyword("null"):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q at offset %d", c, p.pos)
+	}
+}
+
+func (p *pdfParser) dict() (pdfDict, error) {
+	p.pos += 2
+	d := pdfDict{}
+	for {
+		p.skipSpace()
+		if bytes.HasPrefix(p.b[p.pos:], []byte(">>")) {
+			p.pos += 2
+			return d, nil
+		}
+		if p.pos >= len(p.b) || p.b[p.pos] != '/' {
+			return nil, fmt.Errorf("expected a name at offset %d", p.pos)
+		}
+		key := p.name()
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		d[key] = v
+	}
+}
+
+func (p *pdfParser) array() ([]any, error) {
+	p.pos++
+	var a []any
+	for {
+		p.skipSpace()
+		if p.pos < len(p.b) && p.b[p.pos] == ']' {
+			p.pos++
+			return a, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+}
+
+func (p *pdfParser) name() pdfName {
+	p.pos++
+	var name []byte
+	for p.pos < len(p.b) && !isPDFSpace(p.b[p.pos]) && !isPDFDelimiter(p.b[p.pos]) {
+		c := p.b[p.pos]
+		if c == '#' && p.pos+2 < len(p.b) {
+			if n, err := strconv.ParseUint(string(p.b[p.pos+1:p.pos+3]), 16, 8); err == nil {
+				c = byte(n)
+				p.pos += 2
+			}
+		}
+		name = append(name, c)
+		p.pos++
+	}
+	return pdfName(name)
+}
+
+// number reads an integer, a real, or an indirect reference "num gen R".
+func (p *pdfParser) number() (any, error) {
+	start := p.pos
+	for p.pos < len(p.b) && bytes.IndexByte([]byte("+-.0123456789"), p.b[p.pos]) >= 0 {
+		p.pos++
+	}
+	tok := string(p.b[start:p.pos])
+	n, err := strconv.Atoi(tok)
+	if err != nil {
+		return strconv.ParseFloat(tok, 64)
+	}
+
+	save := p.pos
+	p.skipSpace()
+	genStart := p.pos
+	for p.pos < len(p.b) && p.b[p.pos] >= '0' && p.b[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos > genStart {
+		gen, _ := strconv.Atoi(string(p.b[genStart:p.pos]))
+		if p.keyword("R") {
+			return pdfRef{n, gen}, nil
+		}
+	}
+	p.pos = save
+	return n, nil
+}
+
+func (p *pdfParser) hexString() (pdfString, error) {
+	p.pos++
+	var digits []byte
+	for ; p.pos < len(p.b) && p.b[p.pos] != '>'; p.pos++ {
+		if !isPDFSpace(p.b[p.pos]) {
+			digits = append(digits, p.b[p.pos])
+		}
+	}
+	if p.pos >= len(p.b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	p.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	s := make(pdfString, len(digits)/2)
+	for i := range s {
+		n, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("malformed hex string: %v", err)
+		}
+		s[i] = byte(n)
+	}
+	return s, nil
+}
+
+func (p *pdfParser) literalString() (pdfString, error) {
+	p.pos++
+	var s pdfString
+	depth := 1
+	for p.pos < len(p.b) {
+		c := p.b[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return s, nil
+			}
+		case '\\':
+			if p.pos >= len(p.b) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			c = p.b[p.pos]
+			p.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// A backslash at the end of a line continues the string
+				if c == '\r' && p.pos < len(p.b) && p.b[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				n := int(c - '0')
+				for i := 0; i < 2 && p.pos < len(p.b) && p.b[p.pos] >= '0' && p.b[p.pos] <= '7'; i++ {
+					n = n*8 + int(p.b[p.pos]-'0')
+					p.pos++
+				}
+				c = byte(n)
+			}
+		}
+		s = append(s, c)
+	}
+	return nil, io.ErrUnexpectedEOF
+}
@@ -0,0 +1,279 @@
+package certs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// buildPDF lays out objects with an xref table and a trailer, the way
+// PyPDF2 writes them. Each update appends objects, a new xref section and a
+// trailer naming its last object as the document information, like an
+// incremental save.
+func buildPDF(updates ...[]string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.3\n%\xe2\xe3\xcf\xd3\n")
+	num := 1
+	for _, objects := range updates {
+		offsets := make([]int, len(objects))
+		first := num
+		for i, obj := range objects {
+			offsets[i] = b.Len()
+			fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", num, obj)
+			num++
+		}
+		xref := b.Len()
+		fmt.Fprintf(&b, "xref\n%d %d\n", first, len(objects))
+		for _, off := range offsets {
+			fmt.Fprintf(&b, "%010d 00000 n \n", off)
+		}
+		// The info dictionary is the last object of each update
+		fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", num, num-1, xref)
+	}
+	return b.Bytes()
+}
+
+// pypdfString escapes s as PyPDF2 writes text strings: every character
+// but letters, digits and spaces as an octal escape.
+func pypdfString(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, c := range []byte(s) {
+		if c == ' ' || c < 0x80 && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "\\%03o", c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func signedPayloadJSON(t *testing.T, key *Key) []byte {
+	t.Helper()
+	p, err := Sign(key, map[string]any{"device": map[string]any{"name": "Ultra Fit (64 GB)", "serial": "4C530001"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReadPDFInfo(t *testing.T) {
+	key := testKey(t)
+	payload := signedPayloadJSON(t, key)
+
+	pdf := buildPDF(
+		[]string{
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [] /Count 0 >>",
+			"<< /Producer (ReportLab PDF Library \\(www.reportlab.com\\)) >>",
+		},
+		[]string{
+			fmt.Sprintf("<<\n/Producer (PyPDF2)\n/CertPayload %s\n>>", pypdfString(string(payload))),
+		},
+	)
+	p, err := ReadPayload(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(p, []crypto.PublicKey{key.Public()}); err != nil {
+		t.Errorf("Verify = %v", err)
+	}
+
+	// The same payload as a UTF-16 hex string
+	utf16 := []byte{0xfe, 0xff}
+	for _, c := range payload {
+		utf16 = append(utf16, 0, c)
+	}
+	pdf = buildPDF([]string{fmt.Sprintf("<< /CertPayload <%X> >>", utf16)})
+	if p, err = ReadPDF(pdf); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(p, []crypto.PublicKey{key.Public()}); err != nil {
+		t.Errorf("Verify of UTF-16 payload = %v", err)
+	}
+}
+
+func TestReadPDFAttachment(t *testing.T) {
+	key := testKey(t)
+	var z bytes.Buffer
+	w := zlib.NewWriter(&z)
+	w.Write(signedPayloadJSON(t, key))
+	w.Close()
+
+	pdf := buildPDF([]string{
+		"<< /Type /Catalog /Names << /EmbeddedFiles << /Names [(certificate.json) 2 0 R] >> >> >>",
+		"<< /Type /Filespec /F (certificate.json) /EF << /F 3 0 R >> >>",
+		fmt.Sprintf("<< /Type /EmbeddedFile /Subtype /application#2Fjson /Filter /FlateDecode /Length 4 0 R >>\nstream\n%s\nendstream", z.Bytes()),
+		fmt.Sprint(z.Len()),
+		"<< /Title (Certificate of Sanitization) >>",
+	})
+	p, err := ReadPDF(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(p, []crypto.PublicKey{key.Public()}); err != nil {
+		t.Errorf("Verify = %v", err)
+	}
+}
+
+func TestReadPDFWithoutPayload(t *testing.T) {
+	pdf := buildPDF([]string{"<< /Title (Certificate) >>"})
+	if _, err := ReadPDF(pdf); !errors.Is(err, ErrSchema) {
+		t.Errorf("ReadPDF = %v, want a schema error", err)
+	}
+}
+
+func TestReadPDFReferenceLoops(t *testing.T) {
+	stream := "<< /Type /EmbeddedFile /Length %d 0 R >>\nstream\n{}\nendstream"
+	var chain []string
+	for i := 1; i <= 40; i++ {
+		chain = append(chain, fmt.Sprintf(stream, i+1))
+	}
+	tests := map[string][]string{
+		// The attachment's /Length is the attachment itself
+		"self":    {fmt.Sprintf(stream, 1), "<< /Title (Certificate) >>"},
+		"loop":    {fmt.Sprintf(stream, 2), fmt.Sprintf(stream, 1), "<< /Title (Certificate) >>"},
+		"payload": {fmt.Sprintf(stream, 1), "<< /CertPayload 1 0 R >>"},
+		"deep":    append(chain, "<< /Title (Certificate) >>"),
+	}
+	for name, objects := range tests {
+		_, err := ReadPDF(buildPDF(objects))
+		if !errors.Is(err, ErrSchema) || !strings.Contains(err.Error(), "refers to itself") && !strings.Contains(err.Error(), "nested too deeply") {
+			t.Errorf("%s: ReadPDF = %v, want a reference loop schema error", name, err)
+		}
+	}
+}
+
+func TestParseJSONSignatureBlock(t *testing.T) {
+	key := testKey(t)
+	cert := map[string]any{
+		"device": map[string]any{"name": "ST2000DM008", "size_gb": 2000},
+		"wipe":   map[string]any{"method": "purge", "status": "success"},
+	}
+	p, err := Sign(key, cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert["signature"] = map[string]any{
+		"algorithm":              p.Alg,
+		"key_id":                 p.KeyID,
+		"sig":                    p.Sig,
+		"public_key_fingerprint": p.KeyID,
+		"log_hash":               "",
+	}
+	data, err := json.MarshalIndent(cert, "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ReadPayload(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(parsed, []crypto.PublicKey{key.Public()}); err != nil {
+		t.Errorf("Verify = %v", err)
+	}
+
+	for _, bad := range []string{`[]`, `{"device":{}}`, `{"signature":"x"}`} {
+		if _, err := ParseJSON([]byte(bad)); !errors.Is(err, ErrSchema) {
+			t.Errorf("ParseJSON(%s) = %v, want a schema error", bad, err)
+		}
+	}
+}
+
+func gofpdfCertificate(t *testing.T, attachment []byte) []byte {
+	t.Helper()
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Wipe Certificate", false)
+	pdf.AddPage()
+	pdf.SetFont("Arial", "B", 16)
+	pdf.Cell(40, 10, "Data Wipe Certificate")
+	if attachment != nil {
+		pdf.SetAttachments([]gofpdf.Attachment{{Content: attachment, Filename: PayloadFileName}})
+	}
+	var b bytes.Buffer
+	if err := pdf.Output(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestEmbedPayload(t *testing.T) {
+	key := testKey(t)
+	p, err := Sign(key, map[string]any{"device": map[string]any{"name": "Ultra Fit (64 GB) \\ ü"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := gofpdfCertificate(t, nil)
+	pdf, err := EmbedPayload(original, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(pdf, original) {
+		t.Fatal("EmbedPayload rewrote the original PDF")
+	}
+
+	// The update's xref entry must point at the new information dictionary
+	update := pdf[len(original):]
+	m := regexp.MustCompile(`xref\n(\d+) 1\n(\d{10}) 00000 n \ntrailer\n(<<.*>>)\nstartxref\n(\d+)\n%%EOF\n$`).FindSubmatch(update)
+	if m == nil {
+		t.Fatalf("no xref section in update:\n%s", update)
+	}
+	offset, _ := strconv.Atoi(string(m[2]))
+	if !bytes.HasPrefix(pdf[offset:], append(m[1], " 0 obj\n"...)) {
+		t.Errorf("xref offset %d does not point at object %s", offset, m[1])
+	}
+	xref, _ := strconv.Atoi(string(m[4]))
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+		t.Errorf("startxref %d does not point at the xref section", xref)
+	}
+	prev := startXref.FindAllSubmatch(original, -1)
+	root := regexp.MustCompile(`/Root \d+ 0 R`).Find(original)
+	for _, entry := range []string{string(root), "/Info " + string(m[1]) + " 0 R", "/Prev " + string(prev[len(prev)-1][1])} {
+		if !bytes.Contains(m[3], []byte(entry)) {
+			t.Errorf("trailer %s lacks %s", m[3], entry)
+		}
+	}
+
+	got, err := ReadPDF(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(got, []crypto.PublicKey{key.Public()}); err != nil {
+		t.Errorf("Verify = %v", err)
+	}
+	info, err := (&pdfFile{data: pdf}).info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title, _ := info["Title"].(pdfString); string(title) != "Wipe Certificate" {
+		t.Errorf("Title = %q, want it kept", title)
+	}
+}
+
+func TestReadGofpdfAttachment(t *testing.T) {
+	key := testKey(t)
+	pdf := gofpdfCertificate(t, signedPayloadJSON(t, key))
+	p, err := ReadPDF(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(p, []crypto.PublicKey{key.Public()}); err != nil {
+		t.Errorf("Verify = %v", err)
+	}
+}
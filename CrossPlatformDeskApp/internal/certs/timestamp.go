@@ -0,0 +1,450 @@
+package certs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// Timestamp statuses, as the signature block records them.
+const (
+	Stamped   = "stamped"
+	Unstamped = "unstamped"
+)
+
+// ErrBadTimestamp is returned when a certificate's RFC 3161 timestamp is
+// malformed, covers different bytes, or was not issued by a trusted TSA.
+var ErrBadTimestamp = errors.New("timestamp not valid")
+
+// Timestamp records a certificate's RFC 3161 timestamp: a token from a
+// time-stamping authority over the hash of its signed bytes, which proves
+// the certificate existed at Time whatever the workstation clock said. An
+// unstamped certificate records why there is no token.
+type Timestamp struct {
+	Status string `json:"status"`
+	TSA    string `json:"tsa,omitempty"`
+	Time   string `json:"time,omitempty"`
+	// Token is the base64 DER TimeStampToken, a CMS SignedData.
+	Token string `json:"token,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// TimestampInfo is what a timestamp token says, once its own signature
+// has been checked.
+type TimestampInfo struct {
+	Time   time.Time
+	Serial *big.Int
+	Policy asn1.ObjectIdentifier
+	// Hash and Digest are the message imprint: what was stamped.
+	Hash   crypto.Hash
+	Digest []byte
+	// Signer is the TSA certificate that signed the token; Certificates
+	// are all those the token carries, for building its chain.
+	Signer       *x509.Certificate
+	Certificates []*x509.Certificate
+}
+
+var (
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidRSA         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidRSASHA256   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidRSASHA384   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidRSASHA512   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidECDSASHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSASHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSASHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidECPublicKey = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidEd25519     = asn1.ObjectIdentifier{1, 3, 101, 112}
+)
+
+// RFC 3161 and RFC 5652 structures, as far as a client reads them.
+type (
+	messageImprint struct {
+		HashAlgorithm pkix.AlgorithmIdentifier
+		HashedMessage []byte
+	}
+	timeStampReq struct {
+		Version        int
+		MessageImprint messageImprint
+		Nonce          *big.Int `asn1:"optional"`
+		CertReq        bool     `asn1:"optional,default:false"`
+	}
+	pkiStatusInfo struct {
+		Status       int
+		StatusString asn1.RawValue  `asn1:"optional"`
+		FailInfo     asn1.BitString `asn1:"optional"`
+	}
+	timeStampResp struct {
+		Status pkiStatusInfo
+		Token  asn1.RawValue `asn1:"optional"`
+	}
+	contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+	signedData struct {
+		Version          int
+		DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+		EncapContentInfo encapContentInfo
+		Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+		CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+		SignerInfos      []signerInfo  `asn1:"set"`
+	}
+	encapContentInfo struct {
+		EContentType asn1.ObjectIdentifier
+		EContent     []byte `asn1:"explicit,optional,tag:0"`
+	}
+	signerInfo struct {
+		Version            int
+		SID                asn1.RawValue
+		DigestAlgorithm    pkix.AlgorithmIdentifier
+		SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Signature          []byte
+		UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+	}
+	issuerAndSerial struct {
+		Issuer asn1.RawValue
+		Serial *big.Int
+	}
+	attribute struct {
+		Type   asn1.ObjectIdentifier
+		Values []asn1.RawValue `asn1:"set"`
+	}
+	accuracy struct {
+		Seconds int `asn1:"optional"`
+		Millis  int `asn1:"optional,tag:0"`
+		Micros  int `asn1:"optional,tag:1"`
+	}
+	tstInfo struct {
+		Version        int
+		Policy         asn1.ObjectIdentifier
+		MessageImprint messageImprint
+		SerialNumber   *big.Int
+		GenTime        time.Time     `asn1:"generalized"`
+		Accuracy       accuracy      `asn1:"optional"`
+		Ordering       bool          `asn1:"optional,default:false"`
+		Nonce          *big.Int      `asn1:"optional"`
+		TSA            asn1.RawValue `asn1:"optional,tag:0"`
+		Extensions     asn1.RawValue `asn1:"optional,tag:1"`
+	}
+)
+
+// TimestampRequest is a DER TimeStampReq for a SHA-256 digest, asking the
+// TSA to include its certificate.
+func TimestampRequest(digest []byte, nonce *big.Int) ([]byte, error) {
+	return asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+			HashedMessage: digest,
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+}
+
+// RequestTimestamp asks the TSA at url to stamp digest, a SHA-256 hash,
+// and returns the DER token once it is checked to cover digest.
+func RequestTimestamp(client *http.Client, url string, digest []byte) ([]byte, *TimestampInfo, error) {
+	nonce := randomSerial()
+	req, err := TimestampRequest(digest, nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.Post(url, "application/timestamp-query", bytes.NewReader(req))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("TSA replied %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reply timeStampResp
+	if rest, err := asn1.Unmarshal(body, &reply); err != nil || len(rest) > 0 {
+		return nil, nil, errors.New("TSA reply is not a TimeStampResp")
+	}
+	// 0 is granted, 1 granted with modifications
+	if reply.Status.Status > 1 || len(reply.Token.FullBytes) == 0 {
+		return nil, nil, fmt.Errorf("TSA refused the request: status %d, failure info %x", reply.Status.Status, reply.Status.FailInfo.Bytes)
+	}
+	token := reply.Token.FullBytes
+	info, tst, err := parseTimestamp(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Hash != crypto.SHA256 || !bytes.Equal(info.Digest, digest) {
+		return nil, nil, errors.New("TSA stamped a different hash")
+	}
+	if tst.Nonce == nil || tst.Nonce.Cmp(nonce) != 0 {
+		return nil, nil, errors.New("TSA reply is for a different request")
+	}
+	return token, info, nil
+}
+
+// StampPayload has the TSA at url stamp the hash of p's stamped bytes; see
+// stampedBytes. It does not fail: a TSA that cannot be reached leaves the
+// certificate unstamped, with the reason.
+func StampPayload(client *http.Client, url string, p Payload) Timestamp {
+	ts := Timestamp{Status: Unstamped, TSA: url}
+	canonical, err := stampedBytes(p)
+	if err != nil {
+		ts.Error = err.Error()
+		return ts
+	}
+	sum := sha256.Sum256(canonical)
+	token, info, err := RequestTimestamp(client, url, sum[:])
+	if err != nil {
+		ts.Error = err.Error()
+		return ts
+	}
+	ts.Status = Stamped
+	ts.Time = info.Time.UTC().Format(time.RFC3339Nano)
+	ts.Token = base64.StdEncoding.EncodeToString(token)
+	return ts
+}
+
+// stampedBytes is what a timestamp token covers: the canonical JSON of the
+// certificate together with its signature and key ID. Like a CAdES
+// signature timestamp, it proves the signature existed at the stamped time,
+// not only the certificate body.
+func stampedBytes(p Payload) ([]byte, error) {
+	return Canonical(map[string]any{"cert": p.Cert, "kid": p.KeyID, "sig": p.Sig})
+}
+
+// ParseTimestamp decodes a DER timestamp token and checks that its signer
+// signed it. Whether the signer is a trusted TSA is left to the caller;
+// see TrustStore.VerifyTimestamp.
+func ParseTimestamp(token []byte) (*TimestampInfo, error) {
+	info, _, err := parseTimestamp(token)
+	return info, err
+}
+
+func parseTimestamp(token []byte) (*TimestampInfo, *tstInfo, error) {
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(token, &ci); err != nil || len(rest) > 0 {
+		return nil, nil, fmt.Errorf("%w: token is not a CMS ContentInfo", ErrBadTimestamp)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, nil, fmt.Errorf("%w: token is not CMS SignedData", ErrBadTimestamp)
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, nil, fmt.Errorf("%w: SignedData: %v", ErrBadTimestamp, err)
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) || len(sd.EncapContentInfo.EContent) == 0 {
+		return nil, nil, fmt.Errorf("%w: token does not hold a TSTInfo", ErrBadTimestamp)
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, nil, fmt.Errorf("%w: token has %d signers, want 1", ErrBadTimestamp, len(sd.SignerInfos))
+	}
+	var tst tstInfo
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent, &tst); err != nil {
+		return nil, nil, fmt.Errorf("%w: TSTInfo: %v", ErrBadTimestamp, err)
+	}
+	hash, ok := hashByOID(tst.MessageImprint.HashAlgorithm.Algorithm)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: unsupported imprint hash %v", ErrBadTimestamp, tst.MessageImprint.HashAlgorithm.Algorithm)
+	}
+
+	info := &TimestampInfo{
+		Time:   tst.GenTime,
+		Serial: tst.SerialNumber,
+		Policy: tst.Policy,
+		Hash:   hash,
+		Digest: tst.MessageImprint.HashedMessage,
+	}
+	if len(sd.Certificates.Bytes) > 0 {
+		certificates, err := x509.ParseCertificates(sd.Certificates.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: certificates: %v", ErrBadTimestamp, err)
+		}
+		info.Certificates = certificates
+	}
+	signer := sd.SignerInfos[0]
+	if info.Signer = findSigner(signer.SID, info.Certificates); info.Signer == nil {
+		return nil, nil, fmt.Errorf("%w: the token does not carry its signer's certificate", ErrBadTimestamp)
+	}
+	if err := checkSignerInfo(signer, sd.EncapContentInfo.EContent, info.Signer); err != nil {
+		return nil, nil, err
+	}
+	return info, &tst, nil
+}
+
+// checkSignerInfo verifies the signature over the signed attributes and
+// that they bind the TSTInfo.
+func checkSignerInfo(si signerInfo, content []byte, signer *x509.Certificate) error {
+	hash, ok := hashByOID(si.DigestAlgorithm.Algorithm)
+	if !ok {
+		return fmt.Errorf("%w: unsupported digest %v", ErrBadTimestamp, si.DigestAlgorithm.Algorithm)
+	}
+	if len(si.SignedAttrs.FullBytes) == 0 {
+		return fmt.Errorf("%w: token has no signed attributes", ErrBadTimestamp)
+	}
+	var contentType, digest []byte
+	for rest := si.SignedAttrs.Bytes; len(rest) > 0; {
+		var attr attribute
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &attr); err != nil {
+			return fmt.Errorf("%w: signed attributes: %v", ErrBadTimestamp, err)
+		}
+		if len(attr.Values) != 1 {
+			continue
+		}
+		switch {
+		case attr.Type.Equal(oidContentType):
+			contentType = attr.Values[0].FullBytes
+		case attr.Type.Equal(oidMessageDigest):
+			asn1.Unmarshal(attr.Values[0].FullBytes, &digest)
+		}
+	}
+	wantType, _ := asn1.Marshal(oidTSTInfo)
+	if !bytes.Equal(contentType, wantType) {
+		return fmt.Errorf("%w: signed attributes name another content type", ErrBadTimestamp)
+	}
+	h := hash.New()
+	h.Write(content)
+	if !bytes.Equal(digest, h.Sum(nil)) {
+		return fmt.Errorf("%w: TSTInfo does not match its signed digest", ErrBadTimestamp)
+	}
+
+	algorithm, ok := signatureAlgorithm(si.SignatureAlgorithm.Algorithm, hash)
+	if !ok {
+		return fmt.Errorf("%w: unsupported signature algorithm %v", ErrBadTimestamp, si.SignatureAlgorithm.Algorithm)
+	}
+	// The attributes are signed as a SET OF, not with their [0] tag
+	signed := append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+	if err := signer.CheckSignature(algorithm, signed, si.Signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadTimestamp, err)
+	}
+	return nil
+}
+
+// findSigner picks the certificate a SignerInfo's sid names, by issuer and
+// serial number or by subject key identifier.
+func findSigner(sid asn1.RawValue, certificates []*x509.Certificate) *x509.Certificate {
+	if sid.Class == asn1.ClassContextSpecific && sid.Tag == 0 {
+		for _, c := range certificates {
+			if bytes.Equal(c.SubjectKeyId, sid.Bytes) {
+				return c
+			}
+		}
+		return nil
+	}
+	var ias issuerAndSerial
+	if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+		return nil
+	}
+	for _, c := range certificates {
+		if c.SerialNumber.Cmp(ias.Serial) == 0 && bytes.Equal(c.RawIssuer, ias.Issuer.FullBytes) {
+			return c
+		}
+	}
+	return nil
+}
+
+func hashByOID(oid asn1.ObjectIdentifier) (crypto.Hash, bool) {
+	switch {
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, true
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, true
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+// signatureAlgorithm maps a SignerInfo's signature algorithm to x509's.
+// TSAs often name only the key type and leave the hash to the digest
+// algorithm.
+func signatureAlgorithm(oid asn1.ObjectIdentifier, hash crypto.Hash) (x509.SignatureAlgorithm, bool) {
+	byHash := func(sha256, sha384, sha512 x509.SignatureAlgorithm) (x509.SignatureAlgorithm, bool) {
+		switch hash {
+		case crypto.SHA256:
+			return sha256, true
+		case crypto.SHA384:
+			return sha384, true
+		case crypto.SHA512:
+			return sha512, true
+		}
+		return x509.UnknownSignatureAlgorithm, false
+	}
+	switch {
+	case oid.Equal(oidRSA), oid.Equal(oidRSASHA256), oid.Equal(oidRSASHA384), oid.Equal(oidRSASHA512):
+		return byHash(x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA)
+	case oid.Equal(oidECPublicKey), oid.Equal(oidECDSASHA256), oid.Equal(oidECDSASHA384), oid.Equal(oidECDSASHA512):
+		return byHash(x509.ECDSAWithSHA256, x509.ECDSAWithSHA384, x509.ECDSAWithSHA512)
+	case oid.Equal(oidEd25519):
+		return x509.PureEd25519, true
+	}
+	return x509.UnknownSignatureAlgorithm, false
+}
+
+// VerifyTimestamp checks p's timestamp token: that it is well formed,
+// covers p's certificate and signature, and was signed by a TSA certificate chaining to
+// s.TSARoots, or to the system roots when there are none. A payload
+// without a token returns nil, nil.
+func (s TrustStore) VerifyTimestamp(p Payload) (*TimestampInfo, error) {
+	if p.Timestamp == "" {
+		return nil, nil
+	}
+	token, err := base64.StdEncoding.DecodeString(p.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: token is not base64", ErrBadTimestamp)
+	}
+	info, err := ParseTimestamp(token)
+	if err != nil {
+		return nil, err
+	}
+	canonical, err := stampedBytes(p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSchema, err)
+	}
+	h := info.Hash.New()
+	h.Write(canonical)
+	if !bytes.Equal(h.Sum(nil), info.Digest) {
+		return nil, fmt.Errorf("%w: it stamps a different certificate or signature", ErrBadTimestamp)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range info.Certificates {
+		if c != info.Signer {
+			intermediates.AddCert(c)
+		}
+	}
+	_, err = info.Signer.Verify(x509.VerifyOptions{
+		Roots:         s.TSARoots,
+		Intermediates: intermediates,
+		CurrentTime:   info.Time,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: TSA %s: %v", ErrBadTimestamp, info.Signer.Subject.String(), err)
+	}
+	return info, nil
+}
@@ -0,0 +1,216 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testTSA is a stand-in RFC 3161 time-stamping authority. It signs
+// TSTInfos with an ECDSA key certified by root, and replies with status
+// when that is set.
+type testTSA struct {
+	root   *x509.Certificate
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	now    time.Time
+	status int
+}
+
+func newTestTSA(t *testing.T) *testTSA {
+	t.Helper()
+	root, rootKey := testCA(t, "Test TSA Root", nil, nil)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{CommonName: "Test TSA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, root, key.Public(), rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testTSA{root: root, cert: cert, key: key, now: time.Now().UTC().Truncate(time.Second)}
+}
+
+func (tsa *testTSA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var req timeStampReq
+	if _, err := asn1.Unmarshal(body, &req); err != nil || r.Header.Get("Content-Type") != "application/timestamp-query" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	reply := timeStampResp{Status: pkiStatusInfo{Status: tsa.status}}
+	if tsa.status == 0 {
+		token, err := tsa.sign(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		reply.Token = asn1.RawValue{FullBytes: token}
+	}
+	der, _ := asn1.Marshal(reply)
+	w.Header().Set("Content-Type", "application/timestamp-reply")
+	w.Write(der)
+}
+
+// sign issues a TimeStampToken for req: a SignedData over a TSTInfo, with
+// the contentType and messageDigest signed attributes RFC 3161 requires.
+func (tsa *testTSA) sign(req timeStampReq) ([]byte, error) {
+	content, err := asn1.Marshal(tstInfo{
+		Version:        1,
+		Policy:         asn1.ObjectIdentifier{1, 2, 3, 4, 1},
+		MessageImprint: req.MessageImprint,
+		SerialNumber:   big.NewInt(42),
+		GenTime:        tsa.now,
+		Nonce:          req.Nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(content)
+	var attrs []byte
+	for _, attr := range []struct {
+		oid   asn1.ObjectIdentifier
+		value any
+	}{{oidContentType, oidTSTInfo}, {oidMessageDigest, digest[:]}} {
+		value, _ := asn1.Marshal(attr.value)
+		der, err := asn1.Marshal(attribute{Type: attr.oid, Values: []asn1.RawValue{{FullBytes: value}}})
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, der...)
+	}
+	signed, _ := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: attrs})
+	sum := sha256.Sum256(signed)
+	sig, err := ecdsa.SignASN1(rand.Reader, tsa.key, sum[:])
+	if err != nil {
+		return nil, err
+	}
+
+	sid, _ := asn1.Marshal(issuerAndSerial{Issuer: asn1.RawValue{FullBytes: tsa.cert.RawIssuer}, Serial: tsa.cert.SerialNumber})
+	sha256ID := pkix.AlgorithmIdentifier{Algorithm: oidSHA256}
+	sd, err := asn1.Marshal(signedData{
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{sha256ID},
+		EncapContentInfo: encapContentInfo{EContentType: oidTSTInfo, EContent: content},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: tsa.cert.Raw},
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                asn1.RawValue{FullBytes: sid},
+			DigestAlgorithm:    sha256ID,
+			SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attrs},
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidECDSASHA256},
+			Signature:          sig,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd},
+	})
+}
+
+func TestTimestamp(t *testing.T) {
+	tsa := newTestTSA(t)
+	server := httptest.NewServer(tsa)
+	defer server.Close()
+
+	key := testKey(t)
+	p, err := Sign(key, map[string]any{"wipe": map[string]any{"finished_at": "2020-01-01T00:00:00Z"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := StampPayload(server.Client(), server.URL, p)
+	if ts.Status != Stamped || ts.Token == "" || ts.Error != "" {
+		t.Fatalf("StampPayload = %+v", ts)
+	}
+	if ts.Time != tsa.now.Format(time.RFC3339Nano) {
+		t.Errorf("stamp time %s, want the TSA's %s", ts.Time, tsa.now.Format(time.RFC3339Nano))
+	}
+
+	// The token travels in the signature block of a saved certificate
+	saved, _ := json.Marshal(map[string]any{
+		"wipe":      map[string]any{"finished_at": "2020-01-01T00:00:00Z"},
+		"signature": map[string]any{"algorithm": p.Alg, "key_id": p.KeyID, "sig": p.Sig, "timestamp": ts},
+	})
+	p, err = ParseJSON(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := TrustStore{TSARoots: pool(tsa.root)}
+	info, err := store.VerifyTimestamp(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Time.Equal(tsa.now) || !info.Signer.Equal(tsa.cert) {
+		t.Errorf("VerifyTimestamp = %v by %v", info.Time, info.Signer.Subject)
+	}
+
+	altered := p
+	altered.Cert = json.RawMessage(`{"wipe":{"finished_at":"2020-06-01T00:00:00Z"}}`)
+	if _, err := store.VerifyTimestamp(altered); !errors.Is(err, ErrBadTimestamp) {
+		t.Errorf("VerifyTimestamp of an altered certificate = %v", err)
+	}
+	// The token covers the signature too: a stamp cannot be moved onto
+	// a signature made later, or by another key
+	resigned := p
+	resigned.Sig = base64.StdEncoding.EncodeToString(make([]byte, 64))
+	rekeyed := p
+	rekeyed.KeyID = "0123456789abcdef"
+	for name, q := range map[string]Payload{"signature": resigned, "key ID": rekeyed} {
+		if _, err := store.VerifyTimestamp(q); !errors.Is(err, ErrBadTimestamp) {
+			t.Errorf("VerifyTimestamp with another %s = %v", name, err)
+		}
+	}
+	other, _ := testCA(t, "Other TSA Root", nil, nil)
+	if _, err := (TrustStore{TSARoots: pool(other)}).VerifyTimestamp(p); !errors.Is(err, ErrBadTimestamp) {
+		t.Errorf("VerifyTimestamp against another TSA root = %v", err)
+	}
+	if info, err := store.VerifyTimestamp(Payload{Cert: p.Cert}); info != nil || err != nil {
+		t.Errorf("VerifyTimestamp of an unstamped payload = %v, %v", info, err)
+	}
+}
+
+func TestTimestampUnreachable(t *testing.T) {
+	tsa := newTestTSA(t)
+	tsa.status = 2
+	server := httptest.NewServer(tsa)
+	p, err := Sign(testKey(t), map[string]any{"wipe": map[string]any{"status": "success"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts := StampPayload(server.Client(), server.URL, p); ts.Status != Unstamped || ts.Token != "" || ts.Error == "" {
+		t.Errorf("StampPayload from a refusing TSA = %+v", ts)
+	}
+
+	server.Close()
+	if ts := StampPayload(server.Client(), server.URL, p); ts.Status != Unstamped || ts.Error == "" {
+		t.Errorf("StampPayload from a closed TSA = %+v", ts)
+	}
+}
@@ -1 +1,206 @@
-package config
\ No newline at end of file
+package config
+
+import (
+	"data_wiper/internal/drivers"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config is the persisted application configuration. It is stored as JSON in
+// the user's config directory and edited by hand or from the Settings tab.
+type Config struct {
+	// SweepProfiles are user-defined privacy sweep profiles, offered next to
+	// drivers.BuiltinSweepProfiles.
+	SweepProfiles []drivers.SweepProfile `json:"sweep_profiles,omitempty"`
+
+	// Jobs are wipes run unattended by the scheduler.
+	Jobs []Job `json:"jobs,omitempty"`
+
+	// RetentionRules are evaluated by the scheduler like jobs, each on its
+	// own schedule.
+	RetentionRules []drivers.RetentionRule `json:"retention_rules,omitempty"`
+
+	// Policy restricts which drives may be wiped. When the administrator
+	// manages it in SystemPolicyPath, it is that policy; see PolicySource.
+	Policy Policy `json:"policy"`
+
+	// PolicySource is the system policy file Policy was loaded from, or ""
+	// when it comes from the user's config file.
+	PolicySource string `json:"-"`
+	// userPolicy is the config file's own policy, saved back in place of a
+	// system policy.
+	userPolicy Policy
+
+	// VerifierURL is the verifier site certificate QR codes link to; the
+	// signed payload follows it as a #fragment. Empty means
+	// DefaultVerifierURL.
+	VerifierURL string `json:"verifier_url,omitempty"`
+
+	// TSAURL is the RFC 3161 time-stamping authority certificates are
+	// stamped by. Stamping sends each certificate's hash to the TSA, so it
+	// is off unless a URL is set; "off" is accepted too.
+	TSAURL string `json:"tsa_url,omitempty"`
+}
+
+// DefaultVerifierURL is CertificateTOOL's verifier site.
+const DefaultVerifierURL = "https://AdityaRajj23.github.io/Verifier_site"
+
+// Job actions understood by the scheduler.
+const (
+	JobPurge     = "purge"
+	JobClear     = "clear"
+	JobSweep     = "sweep"
+	JobFreeSpace = "freespace"
+	JobRetention = "retention"
+)
+
+// Job is a scheduled wipe. Schedule is a cron expression such as
+// "0 2 * * *"; Target is a path for purge and clear, a sweep profile name for
+// sweep and a directory on the filesystem to fill for freespace.
+type Job struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Action   string `json:"action"`
+	Target   string `json:"target"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// Dir returns the directory holding the config file and other app state.
+func Dir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate user config directory: %v", err)
+	}
+	return filepath.Join(base, "nullbyters"), nil
+}
+
+// Path returns the location of the config file.
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// Load reads the config file and applies the system policy. A missing file
+// is not an error and yields an empty configuration.
+func Load() (*Config, error) {
+	cfg := &Config{}
+	err := cfg.load()
+	if policyErr := cfg.applySystemPolicy(); policyErr != nil && err == nil {
+		err = policyErr
+	}
+	return cfg, err
+}
+
+func (c *Config) load() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %v", path, err)
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+	return nil
+}
+
+// Save writes the config file, creating the config directory if needed. A
+// system policy is left out; the file keeps its own.
+func (c *Config) Save() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+
+	out := *c
+	if c.PolicySource != "" {
+		out.Policy = c.userPolicy
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %v", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Verifier returns the verifier site certificates link to.
+func (c *Config) Verifier() string {
+	if c.VerifierURL == "" {
+		return DefaultVerifierURL
+	}
+	return c.VerifierURL
+}
+
+// TSA returns the time-stamping authority to stamp certificates by, or ""
+// when stamping is off.
+func (c *Config) TSA() string {
+	if c.TSAURL == "off" {
+		return ""
+	}
+	return c.TSAURL
+}
+
+// SweepProfile looks a profile up by name among all known profiles.
+func (c *Config) SweepProfile(name string) (drivers.SweepProfile, bool) {
+	for _, p := range c.AllSweepProfiles() {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return drivers.SweepProfile{}, false
+}
+
+// RetentionRule looks a retention rule up by name.
+func (c *Config) RetentionRule(name string) (drivers.RetentionRule, bool) {
+	for _, r := range c.RetentionRules {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return drivers.RetentionRule{}, false
+}
+
+// ScheduledJobs returns the configured jobs plus one retention job per
+// retention rule, which is how the scheduler sees the rules.
+func (c *Config) ScheduledJobs() []Job {
+	jobs := append([]Job{}, c.Jobs...)
+	for _, r := range c.RetentionRules {
+		schedule := r.Schedule
+		if schedule == "" {
+			schedule = "@hourly"
+		}
+		jobs = append(jobs, Job{
+			Name:     "retention: " + r.Name,
+			Schedule: schedule,
+			Action:   JobRetention,
+			Target:   r.Name,
+		})
+	}
+	return jobs
+}
+
+// AllSweepProfiles returns the built-in profiles followed by the user's own.
+func (c *Config) AllSweepProfiles() []drivers.SweepProfile {
+	profiles := append([]drivers.SweepProfile{}, drivers.BuiltinSweepProfiles...)
+	return append(profiles, c.SweepProfiles...)
+}
@@ -0,0 +1,17 @@
+package config
+
+import "testing"
+
+func TestTSA(t *testing.T) {
+	tests := map[string]string{
+		"":                              "",
+		"off":                           "",
+		"http://timestamp.digicert.com": "http://timestamp.digicert.com",
+	}
+	for url, want := range tests {
+		c := Config{TSAURL: url}
+		if got := c.TSA(); got != want {
+			t.Errorf("TSA() with tsa_url %q = %q, want %q", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"data_wiper/internal/drivers"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// SystemPolicyPath is the policy file an administrator manages for every
+// user of the machine. When it exists it replaces the policy in the user's
+// config file, which the user can edit.
+var SystemPolicyPath = systemPolicyPath()
+
+func systemPolicyPath() string {
+	switch runtime.GOOS {
+	case "windows":
+		dir := os.Getenv("ProgramData")
+		if dir == "" {
+			dir = `C:\ProgramData`
+		}
+		return filepath.Join(dir, "nullbyters", "policy.json")
+	case "darwin":
+		return "/Library/Application Support/nullbyters/policy.json"
+	}
+	return "/etc/nullbyters/policy.json"
+}
+
+// Policy restricts which drives may be wiped.
+type Policy struct {
+	// RemovableOnly allows only removable and USB drives as targets until
+	// an administrator unlocks internal disks for the session.
+	RemovableOnly bool `json:"removable_only,omitempty"`
+	// AdminPINHash is the administrator PIN in auth.HashPIN form, set with
+	// the app's -set-admin-pin flag.
+	AdminPINHash string `json:"admin_pin_hash,omitempty"`
+}
+
+// Allows reports why d may not be wiped under the policy, or nil if it
+// may. unlocked says whether an administrator has unlocked internal disks.
+func (p Policy) Allows(d drivers.Drive, unlocked bool) error {
+	if !p.RemovableOnly || unlocked || d.IsRemovable || d.Transport == "usb" || d.Type == "usb" {
+		return nil
+	}
+	return fmt.Errorf("%s is an internal disk; policy allows only removable media until an administrator unlocks internal disks", d.Name)
+}
+
+// applySystemPolicy replaces c.Policy with the one in SystemPolicyPath, if
+// there is one. A file that cannot be read or parsed fails closed: only
+// removable media may be wiped until it is fixed.
+func (c *Config) applySystemPolicy() error {
+	data, err := os.ReadFile(SystemPolicyPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	c.userPolicy = c.Policy
+	c.PolicySource = SystemPolicyPath
+	c.Policy = Policy{RemovableOnly: true}
+	if err != nil {
+		return fmt.Errorf("failed to read policy %s: %v", SystemPolicyPath, err)
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("failed to parse policy %s: %v", SystemPolicyPath, err)
+	}
+	c.Policy = p
+	return nil
+}
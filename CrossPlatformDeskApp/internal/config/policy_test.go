@@ -0,0 +1,96 @@
+package config
+
+import (
+	"data_wiper/internal/drivers"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPolicyAllows(t *testing.T) {
+	internal := drivers.Drive{Name: "ST2000DM008-2FR1", Device: "/dev/sda", Type: "internal", Transport: "sata"}
+	stick := drivers.Drive{Name: "Ultra Fit", Device: "/dev/sdc", Type: "usb", Transport: "usb", IsRemovable: true}
+	// Card readers are removable without being USB.
+	card := drivers.Drive{Name: "SD card", Device: "/dev/mmcblk0", Transport: "mmc", IsRemovable: true}
+	// Some USB enclosures do not set the removable flag.
+	enclosure := drivers.Drive{Name: "CT500MX500SSD1", Device: "/dev/sdd", Transport: "usb"}
+
+	off := Policy{}
+	on := Policy{RemovableOnly: true}
+	tests := []struct {
+		policy   Policy
+		drive    drivers.Drive
+		unlocked bool
+		allowed  bool
+	}{
+		{off, internal, false, true},
+		{on, internal, false, false},
+		{on, internal, true, true},
+		{on, stick, false, true},
+		{on, card, false, true},
+		{on, enclosure, false, true},
+	}
+	for _, tt := range tests {
+		err := tt.policy.Allows(tt.drive, tt.unlocked)
+		if (err == nil) != tt.allowed {
+			t.Errorf("%+v.Allows(%s, unlocked=%v) = %v", tt.policy, tt.drive.Name, tt.unlocked, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), tt.drive.Name) {
+			t.Errorf("refusal %q does not name the drive", err)
+		}
+	}
+}
+
+func TestSystemPolicy(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	saved := SystemPolicyPath
+	t.Cleanup(func() { SystemPolicyPath = saved })
+	SystemPolicyPath = filepath.Join(t.TempDir(), "policy.json")
+
+	user := &Config{Policy: Policy{AdminPINHash: "user"}}
+	if err := user.Save(); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PolicySource != "" || cfg.Policy != user.Policy {
+		t.Errorf("without a system policy got %+v from %q, want the user's", cfg.Policy, cfg.PolicySource)
+	}
+
+	system := Policy{RemovableOnly: true, AdminPINHash: "system"}
+	data, _ := json.Marshal(system)
+	if err := os.WriteFile(SystemPolicyPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PolicySource != SystemPolicyPath || cfg.Policy != system {
+		t.Errorf("got %+v from %q, want the system policy", cfg.Policy, cfg.PolicySource)
+	}
+	// Saving must not copy the system policy into the user's file.
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	SystemPolicyPath = filepath.Join(t.TempDir(), "missing.json")
+	if cfg, _ := Load(); cfg.Policy != user.Policy {
+		t.Errorf("user policy after save = %+v, want %+v", cfg.Policy, user.Policy)
+	}
+
+	SystemPolicyPath = filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(SystemPolicyPath, []byte("{removable_only: false"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = Load()
+	if err == nil {
+		t.Error("malformed system policy loaded without error")
+	}
+	if !cfg.Policy.RemovableOnly || cfg.PolicySource != SystemPolicyPath {
+		t.Errorf("malformed system policy gave %+v from %q, want removable only", cfg.Policy, cfg.PolicySource)
+	}
+}
@@ -1 +1,100 @@
-package db
\ No newline at end of file
+package db
+
+import (
+	"bufio"
+	"data_wiper/internal/config"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var mu sync.Mutex
+
+// HistoryPath is where wipe records are kept, one JSON object per line.
+func HistoryPath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "wipe_history.jsonl"), nil
+}
+
+// AppendWipe adds a record to the wipe history.
+func AppendWipe(r WipeRecord) error {
+	path, err := HistoryPath()
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open wipe history: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write wipe history: %v", err)
+	}
+	return nil
+}
+
+// WipesFor returns the recorded wipes of the drive with the given
+// fingerprint, newest first.
+func WipesFor(fingerprint string) ([]WipeRecord, error) {
+	if fingerprint == "" {
+		return nil, nil
+	}
+	all, err := Wipes()
+	if err != nil {
+		return nil, err
+	}
+	var found []WipeRecord
+	for _, r := range all {
+		if r.Fingerprint == fingerprint {
+			found = append(found, r)
+		}
+	}
+	return found, nil
+}
+
+// Wipes returns the whole wipe history, newest first. A missing history
+// file is an empty history; lines that do not parse are skipped.
+func Wipes() ([]WipeRecord, error) {
+	path, err := HistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wipe history: %v", err)
+	}
+	defer file.Close()
+
+	var records []WipeRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r WipeRecord
+		if json.Unmarshal(scanner.Bytes(), &r) == nil {
+			records = append(records, r)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].FinishedAt.After(records[j].FinishedAt) })
+	return records, scanner.Err()
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWipesFor(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	if records, err := Wipes(); err != nil || records != nil {
+		t.Fatalf("empty history = %v, %v", records, err)
+	}
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, r := range []WipeRecord{
+		{Fingerprint: "1a2b-3c4d-5e6f-7a8b", Device: "/dev/sdc", Status: "success", FinishedAt: base},
+		{Fingerprint: "9f8e-7d6c-5b4a-3928", Device: "/dev/sdb", Status: "success", FinishedAt: base.Add(time.Hour)},
+		{Fingerprint: "1a2b-3c4d-5e6f-7a8b", Device: "/dev/sdd", Status: "failure", FinishedAt: base.Add(2 * time.Hour)},
+		{Device: "/home/alex/notes.txt", Status: "success", FinishedAt: base.Add(3 * time.Hour)},
+	} {
+		if err := AppendWipe(r); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+	}
+
+	// A torn or foreign line does not hide the rest of the history
+	path, _ := HistoryPath()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{\"fingerprint\": \"1a2b-3c4d\n")
+	f.Close()
+
+	all, err := Wipes()
+	if err != nil || len(all) != 4 || all[0].Device != "/home/alex/notes.txt" {
+		t.Fatalf("Wipes = %+v, %v", all, err)
+	}
+
+	// The same stick seen under another kernel name is the same drive
+	found, err := WipesFor("1a2b-3c4d-5e6f-7a8b")
+	if err != nil || len(found) != 2 || found[0].Device != "/dev/sdd" || found[1].Device != "/dev/sdc" {
+		t.Errorf("WipesFor = %+v, %v", found, err)
+	}
+	// Drives without a fingerprint never match each other
+	if found, _ := WipesFor(""); found != nil {
+		t.Errorf("WipesFor(\"\") = %+v", found)
+	}
+	if found, _ := WipesFor("0000-0000-0000-0000"); found != nil {
+		t.Errorf("unknown drive = %+v", found)
+	}
+	if filepath.Base(path) != "wipe_history.jsonl" {
+		t.Errorf("history kept in %s", path)
+	}
+}
@@ -1 +1,17 @@
-package db
\ No newline at end of file
+package db
+
+import "time"
+
+// WipeRecord is the history entry kept for every certified wipe, so a drive
+// can be recognised by its fingerprint the next time it is connected.
+type WipeRecord struct {
+	Fingerprint string    `json:"fingerprint"`
+	Device      string    `json:"device"`
+	Serial      string    `json:"serial,omitempty"`
+	Type        string    `json:"type"`
+	Method      string    `json:"method"`
+	NistLevel   string    `json:"nist_level"`
+	Status      string    `json:"status"`
+	FinishedAt  time.Time `json:"finished_at"`
+	LogHash     string    `json:"log_hash,omitempty"`
+}
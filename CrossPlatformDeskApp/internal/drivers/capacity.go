@@ -0,0 +1,240 @@
+package drivers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+// PhaseProbe is reported through ProbeOptions.Progress.
+const PhaseProbe = "probe"
+
+const (
+	// probeBlockSize is the size of each tagged block; a multiple of every
+	// sector size.
+	probeBlockSize = 4096
+	// probePoints is how many blocks are spread across the reported
+	// capacity. More points find the real size more precisely.
+	probePoints = 1024
+)
+
+var probeMagic = []byte("NBPROBE1")
+
+// CapacityReport is the outcome of a fake-capacity probe.
+type CapacityReport struct {
+	Device        string `json:"device"`
+	ReportedBytes int64  `json:"reported_bytes"`
+	// UsableBytes is the offset of the first probed block that did not
+	// keep its data; the reported size when every block did.
+	UsableBytes  int64     `json:"usable_bytes"`
+	ProbedBlocks int       `json:"probed_blocks"`
+	BadBlocks    int       `json:"bad_blocks"`
+	CheckedAt    time.Time `json:"checked_at"`
+}
+
+// Fake reports whether the device holds less than it claims.
+func (r CapacityReport) Fake() bool {
+	return r.BadBlocks > 0
+}
+
+// ProbeOptions controls a capacity probe.
+type ProbeOptions struct {
+	// Progress, when set, is called after every block read or written with
+	// the operations done out of the total.
+	Progress func(phase string, done, total int64)
+	// Cancel stops the probe between blocks when closed. The blocks written
+	// so far are restored before it returns.
+	Cancel <-chan struct{}
+}
+
+// probeTarget is the part of *os.File the probe needs, so tests can stand
+// in a simulated counterfeit device.
+type probeTarget interface {
+	io.ReaderAt
+	io.WriterAt
+	Sync() error
+}
+
+// ProbeCapacity checks that device really stores data across its reported
+// capacity, the way f3probe does. It writes uniquely tagged blocks spread
+// over the whole device, reads them back and reports where data starts to
+// go missing. Counterfeit flash wraps writes past its real size around onto
+// earlier blocks, or drops them. The original contents of the probed
+// blocks are restored afterwards, but the device must not be in use.
+func ProbeCapacity(device string, opts ProbeOptions) (CapacityReport, error) {
+	report := CapacityReport{Device: device}
+	dev, err := os.OpenFile(device, os.O_RDWR|os.O_EXCL|directIO, 0)
+	if err != nil {
+		return report, fmt.Errorf("failed to open %s exclusively: %v", device, err)
+	}
+	defer dev.Close()
+
+	size, err := dev.Seek(0, io.SeekEnd)
+	if err != nil {
+		return report, fmt.Errorf("failed to size %s: %v", device, err)
+	}
+	report, err = probeCapacity(dev, size, opts)
+	report.Device = device
+	if err == nil && report.Fake() {
+		fmt.Printf("Capacity probe: %s claims %d bytes but only %d are usable\n", device, report.ReportedBytes, report.UsableBytes)
+	}
+	return report, err
+}
+
+func probeCapacity(dev probeTarget, size int64, opts ProbeOptions) (report CapacityReport, err error) {
+	report = CapacityReport{ReportedBytes: size, UsableBytes: size, CheckedAt: time.Now().UTC()}
+	offsets := probeOffsets(size)
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return report, fmt.Errorf("failed to generate probe tag: %v", err)
+	}
+
+	var done int64
+	total := int64(4 * len(offsets))
+	progress := func() {
+		done++
+		if opts.Progress != nil {
+			opts.Progress(PhaseProbe, done, total)
+		}
+	}
+	step := func() error {
+		progress()
+		if cancelled(opts.Cancel) {
+			return ErrWipeCancelled
+		}
+		return nil
+	}
+
+	buf := alignedBuffer(probeBlockSize)
+	saved := make([][]byte, len(offsets))
+	for i, off := range offsets {
+		if _, err := dev.ReadAt(buf, off); err != nil {
+			return report, fmt.Errorf("read failed at byte %d: %v", off, err)
+		}
+		saved[i] = bytes.Clone(buf)
+		if err := step(); err != nil {
+			return report, err
+		}
+	}
+
+	// Put the original blocks back in reverse order: where two offsets
+	// share a physical block, the lower one's contents were read first and
+	// are the ones that belong there.
+	written := 0
+	defer func() {
+		for i := written - 1; i >= 0; i-- {
+			copy(buf, saved[i])
+			if _, werr := dev.WriteAt(buf, offsets[i]); werr != nil && err == nil {
+				err = fmt.Errorf("failed to restore block at byte %d: %v", offsets[i], werr)
+			}
+			progress()
+		}
+		if serr := dev.Sync(); serr != nil && err == nil {
+			err = serr
+		}
+	}()
+
+	for _, off := range offsets {
+		fillProbeBlock(buf, nonce, off)
+		if _, err := dev.WriteAt(buf, off); err != nil {
+			return report, fmt.Errorf("write failed at byte %d: %v", off, err)
+		}
+		written++
+		if err := step(); err != nil {
+			return report, err
+		}
+	}
+	if err := dev.Sync(); err != nil {
+		return report, err
+	}
+
+	// A block that reads back another offset's tag shares its physical
+	// storage with that offset. Of each such group only the lowest offset
+	// is real; a block that reads back no tag at all lost its write.
+	alias := make(map[int64]int64)
+	var root func(off int64) int64
+	root = func(off int64) int64 {
+		if parent, ok := alias[off]; ok && parent != off {
+			return root(parent)
+		}
+		return off
+	}
+	bad := make(map[int64]bool)
+	want := make([]byte, probeBlockSize)
+	for _, off := range offsets {
+		if _, err := dev.ReadAt(buf, off); err != nil {
+			bad[off] = true
+		} else if tagged, ok := probeTagOf(buf, nonce, want); !ok {
+			bad[off] = true
+		} else if tagged != off {
+			a, b := root(off), root(tagged)
+			alias[max(a, b)] = min(a, b)
+		}
+		if err := step(); err != nil {
+			return report, err
+		}
+	}
+	for _, off := range offsets {
+		if bad[off] || root(off) != off {
+			report.BadBlocks++
+			report.UsableBytes = min(report.UsableBytes, off)
+		}
+	}
+	report.ProbedBlocks = len(offsets)
+	return report, nil
+}
+
+// probeOffsets spreads probePoints blocks evenly over size, always
+// including the first and the last block, and adds every power-of-two
+// offset below size. Counterfeit controllers ignore the address bits above
+// the real size, which is a power of two, so the block at that size lands
+// on block 0 whatever size the stick claims.
+func probeOffsets(size int64) []int64 {
+	blocks := size / probeBlockSize
+	if blocks == 0 {
+		return nil
+	}
+	n := min(blocks, probePoints)
+	offsets := make([]int64, 0, n+64)
+	for i := int64(0); i < n; i++ {
+		offsets = append(offsets, i*blocks/n*probeBlockSize)
+	}
+	offsets = append(offsets, (blocks-1)*probeBlockSize)
+	for b := int64(1); b < blocks; b *= 2 {
+		offsets = append(offsets, b*probeBlockSize)
+	}
+	slices.Sort(offsets)
+	return slices.Compact(offsets)
+}
+
+// fillProbeBlock writes the tag for offset into buf: a header naming the
+// offset and this run's nonce, followed by data derived from both, so a
+// block cannot pass for another or for a previous run's.
+func fillProbeBlock(buf, nonce []byte, offset int64) {
+	copy(buf, probeMagic)
+	binary.LittleEndian.PutUint64(buf[8:], uint64(offset))
+	copy(buf[16:32], nonce)
+	var seed [8]byte
+	for i, pos := uint64(0), 32; pos < len(buf); i++ {
+		binary.LittleEndian.PutUint64(seed[:], i)
+		sum := sha256.Sum256(append(append(append([]byte(nil), nonce...), buf[8:16]...), seed[:]...))
+		pos += copy(buf[pos:], sum[:])
+	}
+}
+
+// probeTagOf returns the offset block was tagged for in this run, using
+// scratch to regenerate the expected contents.
+func probeTagOf(block, nonce, scratch []byte) (int64, bool) {
+	if !bytes.Equal(block[:8], probeMagic) || !bytes.Equal(block[16:32], nonce) {
+		return 0, false
+	}
+	offset := int64(binary.LittleEndian.Uint64(block[8:16]))
+	fillProbeBlock(scratch, nonce, offset)
+	return offset, bytes.Equal(block, scratch)
+}
@@ -0,0 +1,159 @@
+package drivers
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// fakeFlash simulates a flash drive whose controller stores real bytes.
+// Writes past that either wrap around onto earlier blocks, as counterfeit
+// sticks do by ignoring the high address bits, or are dropped.
+type fakeFlash struct {
+	real   int64
+	wrap   bool
+	blocks map[int64][]byte
+	writes int
+	failAt int64
+}
+
+func newFakeFlash(real int64, wrap bool) *fakeFlash {
+	return &fakeFlash{real: real, wrap: wrap, blocks: make(map[int64][]byte), failAt: -1}
+}
+
+func (f *fakeFlash) physical(off int64) (int64, bool) {
+	if off < f.real {
+		return off, true
+	}
+	return off & (f.real - 1), f.wrap
+}
+
+func (f *fakeFlash) ReadAt(p []byte, off int64) (int, error) {
+	phys, ok := f.physical(off)
+	block := f.blocks[phys]
+	if !ok || block == nil {
+		// Never written: the factory contents
+		copy(p, bytes.Repeat([]byte{0xff}, len(p)))
+		return len(p), nil
+	}
+	return copy(p, block), nil
+}
+
+func (f *fakeFlash) WriteAt(p []byte, off int64) (int, error) {
+	if off == f.failAt {
+		return 0, errors.New("I/O error")
+	}
+	f.writes++
+	if phys, ok := f.physical(off); ok {
+		f.blocks[phys] = bytes.Clone(p)
+	}
+	return len(p), nil
+}
+
+func (f *fakeFlash) Sync() error { return nil }
+
+func TestProbeOffsets(t *testing.T) {
+	if got := probeOffsets(probeBlockSize - 1); got != nil {
+		t.Errorf("offsets in less than a block: %v", got)
+	}
+	if got := probeOffsets(3 * probeBlockSize); len(got) != 3 || got[2] != 2*probeBlockSize {
+		t.Errorf("three blocks: %v", got)
+	}
+
+	for _, size := range []int64{16 << 30, 128000000000} {
+		offsets := probeOffsets(size)
+		if len(offsets) < probePoints+1 || offsets[0] != 0 || offsets[len(offsets)-1] != size/probeBlockSize*probeBlockSize-probeBlockSize {
+			t.Fatalf("%d: %d offsets from %d to %d", size, len(offsets), offsets[0], offsets[len(offsets)-1])
+		}
+		for i, off := range offsets {
+			if off%probeBlockSize != 0 || (i > 0 && off <= offsets[i-1]) {
+				t.Fatalf("%d: offset %d = %d", size, i, off)
+			}
+		}
+		// Every power of two a counterfeit could really hold is probed
+		for p := int64(probeBlockSize); p < size; p *= 2 {
+			if _, found := slices.BinarySearch(offsets, p); !found {
+				t.Errorf("%d: no probe at %d", size, p)
+			}
+		}
+	}
+}
+
+func TestProbeCapacity(t *testing.T) {
+	tests := []struct {
+		name    string
+		claimed int64
+		real    int64
+		wrap    bool
+		usable  int64
+	}{
+		{"genuine", 64 << 30, 64 << 30, true, 64 << 30},
+		{"genuine odd size", 128000000000, 128000000000, false, 128000000000},
+		// 8 GiB sold as 64 GiB; the probe at 8 GiB is where the data
+		// starts to go missing.
+		{"wraps around", 64 << 30, 8 << 30, true, 8 << 30},
+		// Sold sizes are rarely powers of two; the wrap must be caught
+		// whether or not the evenly spread points happen to alias.
+		{"wraps under 128 GB", 128000000000, 8 << 30, true, 8 << 30},
+		{"wraps under 30 GiB", 30 << 30, 8 << 30, true, 8 << 30},
+		{"wraps under 60 GiB", 60 << 30, 8 << 30, true, 8 << 30},
+		{"wraps under 60 GiB from 16 GiB", 60 << 30, 16 << 30, true, 16 << 30},
+		{"drops writes", 64 << 30, 8 << 30, false, 8 << 30},
+		{"drops past an odd size", 64 << 30, 3<<30 + 5*probeBlockSize, false, 3<<30 + 1<<26},
+	}
+	for _, tt := range tests {
+		dev := newFakeFlash(tt.real, tt.wrap)
+		original := bytes.Repeat([]byte("factory!"), probeBlockSize/8)
+		dev.blocks[0] = bytes.Clone(original)
+
+		report, err := probeCapacity(dev, tt.claimed, ProbeOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if report.UsableBytes != tt.usable || report.Fake() != (tt.real < tt.claimed) {
+			t.Errorf("%s: usable %d (fake %v), want %d", tt.name, report.UsableBytes, report.Fake(), tt.usable)
+		}
+		if report.ProbedBlocks != len(probeOffsets(tt.claimed)) || report.ReportedBytes != tt.claimed {
+			t.Errorf("%s: report %+v", tt.name, report)
+		}
+		if !bytes.Equal(dev.blocks[0], original) {
+			t.Errorf("%s: first block not restored", tt.name)
+		}
+	}
+}
+
+func TestProbeCapacityCancel(t *testing.T) {
+	dev := newFakeFlash(1<<30, true)
+	cancel := make(chan struct{})
+	var last int64
+	opts := ProbeOptions{Cancel: cancel, Progress: func(phase string, done, total int64) {
+		last = done
+		// Halfway through writing the tags
+		if done == total*3/8 {
+			close(cancel)
+		}
+	}}
+	_, err := probeCapacity(dev, 1<<30, opts)
+	if !errors.Is(err, ErrWipeCancelled) {
+		t.Fatalf("err = %v", err)
+	}
+	// Every tag written is overwritten again with the saved block
+	for off, block := range dev.blocks {
+		if !bytes.Equal(block, bytes.Repeat([]byte{0xff}, probeBlockSize)) {
+			t.Fatalf("block at %d not restored", off)
+		}
+	}
+	if want := int64(len(probeOffsets(1<<30))) * 3 / 2; last < want {
+		t.Errorf("progress stopped at %d", last)
+	}
+
+	dev = newFakeFlash(1<<30, true)
+	dev.failAt = probeOffsets(1 << 30)[10]
+	if _, err := probeCapacity(dev, 1<<30, ProbeOptions{}); err == nil {
+		t.Error("write failure not reported")
+	}
+	if dev.writes != 20 {
+		t.Errorf("%d writes, want 10 tags and 10 restores", dev.writes)
+	}
+}
@@ -14,6 +14,28 @@ import (
 // ClearItem performs basic file/directory deletion
 // This is a standard delete operation that removes files/directories from the filesystem
 func ClearItem(path string) error {
+	return ClearItemTraced(path, nil)
+}
+
+// ClearItemTraced is ClearItem, recording what it removed and any error in t
+func ClearItemTraced(path string, t *Trace) error {
+	err := checkTargets(path)
+	if err == nil {
+		err = clearItem(path, t)
+	}
+	t.fail(path, err)
+	return err
+}
+
+// clearChecked is ClearItemTraced for a path whose drive the guard has
+// already allowed.
+func clearChecked(path string, t *Trace) error {
+	err := clearItem(path, t)
+	t.fail(path, err)
+	return err
+}
+
+func clearItem(path string, t *Trace) error {
 	if path == "" {
 		return errors.New("path cannot be empty")
 	}
@@ -32,7 +54,20 @@ func ClearItem(path string) error {
 		return fmt.Errorf("cannot delete critical system path: %s", path)
 	}
 
+	t.useEngine(EngineUnlink)
 	if info.IsDir() {
+		// Count what is about to go; RemoveAll does not say
+		filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+			switch {
+			case err != nil:
+			case info.IsDir():
+				t.countDir()
+			default:
+				t.countFile()
+			}
+			return nil
+		})
+
 		// Remove directory and all its contents
 		err = os.RemoveAll(path)
 		if err != nil {
@@ -45,6 +80,7 @@ func ClearItem(path string) error {
 		if err != nil {
 			return fmt.Errorf("failed to remove file %s: %v", path, err)
 		}
+		t.countFile()
 		fmt.Printf("File cleared: %s\n", path)
 	}
 
@@ -54,6 +90,28 @@ func ClearItem(path string) error {
 // PurgeItem performs secure deletion with multiple overwrite passes
 // This function attempts to securely overwrite data before deletion
 func PurgeItem(path string) error {
+	return PurgeItemTraced(path, nil)
+}
+
+// PurgeItemTraced is PurgeItem, recording the engines, passes and errors in t
+func PurgeItemTraced(path string, t *Trace) error {
+	err := checkTargets(path)
+	if err == nil {
+		err = purgeItem(path, t)
+	}
+	t.fail(path, err)
+	return err
+}
+
+// purgeChecked is PurgeItemTraced for a path whose drive the guard has
+// already allowed.
+func purgeChecked(path string, t *Trace) error {
+	err := purgeItem(path, t)
+	t.fail(path, err)
+	return err
+}
+
+func purgeItem(path string, t *Trace) error {
 	if path == "" {
 		return errors.New("path cannot be empty")
 	}
@@ -74,14 +132,14 @@ func PurgeItem(path string) error {
 
 	if info.IsDir() {
 		// Recursively purge directory contents
-		err = purgeDirectory(path)
+		err = purgeDirectory(path, t)
 		if err != nil {
 			return fmt.Errorf("failed to purge directory %s: %v", path, err)
 		}
 		fmt.Printf("Directory purged: %s\n", path)
 	} else {
 		// Purge single file
-		err = purgeFile(path)
+		err = purgeFile(path, t)
 		if err != nil {
 			return fmt.Errorf("failed to purge file %s: %v", path, err)
 		}
@@ -92,62 +150,120 @@ func PurgeItem(path string) error {
 }
 
 // purgeFile securely overwrites and deletes a single file
-func purgeFile(filePath string) error {
+func purgeFile(filePath string, t *Trace) error {
+	var size int64
+	if info, err := os.Stat(filePath); err == nil {
+		size = info.Size()
+	}
+
 	// Try platform-specific secure deletion tools first
-	if err := trySecureDeleteTool(filePath); err == nil {
+	tool, err := trySecureDeleteTool(filePath)
+	if err == nil {
+		t.countFile()
+		tool.record(t, size)
 		return nil
 	}
+	if tool.name != "" {
+		// The tool ran and failed; say so, as the fallback may not be
+		// what the operator expected
+		t.fail(filePath, fmt.Errorf("%s failed, falling back to overwrite: %v", tool.name, err))
+	}
 
 	// Fallback to manual overwrite if tools aren't available
-	return manualSecureDelete(filePath)
+	if err := manualSecureDelete(filePath, t); err != nil {
+		return err
+	}
+	t.countFile()
+	return nil
 }
 
-// trySecureDeleteTool attempts to use OS-specific secure deletion tools
-func trySecureDeleteTool(filePath string) error {
-	switch runtime.GOOS {
-	case "linux":
-		// Try shred command (most common on Linux)
-		if _, err := exec.LookPath("shred"); err == nil {
-			cmd := exec.Command("shred", "-vfz", "-n", "3", "-u", filePath)
-			return cmd.Run()
-		}
+// secureDeleteTool is an external command that overwrites and removes a
+// file, with the patterns it writes in order.
+type secureDeleteTool struct {
+	name   string
+	args   []string
+	passes []string
+	// inPlace is false for tools that delete the file and then wipe the
+	// free space around it, so the file's size says nothing about them
+	inPlace bool
+}
 
-		// Try wipe command as alternative
-		if _, err := exec.LookPath("wipe"); err == nil {
-			cmd := exec.Command("wipe", "-rf", filePath)
-			return cmd.Run()
-		}
+var (
+	shredTool   = secureDeleteTool{"shred", []string{"-vfz", "-n", "3", "-u"}, []string{"random", "random", "random", "zeros"}, true}
+	wipeTool    = secureDeleteTool{"wipe", []string{"-rf"}, []string{"tool default"}, true}
+	rmTool      = secureDeleteTool{"rm", []string{"-P"}, []string{"tool default"}, true}
+	sdeleteTool = secureDeleteTool{"sdelete", []string{"-p", "3", "-s", "-z"}, []string{"tool default", "tool default", "tool default"}, true}
+	cipherTool  = secureDeleteTool{name: "cipher"}
+)
 
-	case "darwin":
-		// Try rm with secure deletion on macOS
-		cmd := exec.Command("rm", "-P", filePath)
-		if err := cmd.Run(); err == nil {
-			return nil
+// record adds the tool's passes over a file of size bytes to t.
+func (s secureDeleteTool) record(t *Trace, size int64) {
+	t.useEngine(s.name)
+	if !s.inPlace {
+		return
+	}
+	for i, pattern := range s.passes {
+		t.addPass(s.name, i+1, pattern, size, true)
+	}
+}
+
+// run runs the tool on filePath, folding its output into the error.
+func (s secureDeleteTool) run(filePath string) error {
+	out, err := Commands.CombinedOutput(s.name, append(s.args, filePath)...)
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%v: %s", err, msg)
 		}
+	}
+	return err
+}
 
+// trySecureDeleteTool attempts to use OS-specific secure deletion tools.
+// It returns the tool it ran, or none if no tool is installed.
+func trySecureDeleteTool(filePath string) (secureDeleteTool, error) {
+	var candidates []secureDeleteTool
+	switch runtime.GOOS {
+	case "linux":
+		// shred is the most common on Linux, wipe an alternative
+		candidates = []secureDeleteTool{shredTool, wipeTool}
+	case "darwin":
+		// rm with secure deletion on macOS
+		candidates = []secureDeleteTool{rmTool}
 	case "windows":
-		// Try sdelete if available (Sysinternals tool)
-		if _, err := exec.LookPath("sdelete"); err == nil {
-			cmd := exec.Command("sdelete", "-p", "3", "-s", "-z", filePath)
-			return cmd.Run()
-		}
+		// sdelete if available (Sysinternals tool), else cipher, which
+		// is built into Windows
+		candidates = []secureDeleteTool{sdeleteTool, cipherTool}
+	}
 
-		// Try cipher command (built into Windows)
-		if _, err := exec.LookPath("cipher"); err == nil {
-			// First delete the file normally
-			os.Remove(filePath)
-			// Then overwrite free space in the directory
-			dir := filepath.Dir(filePath)
-			cmd := exec.Command("cipher", "/w:"+dir)
-			return cmd.Run()
+	for _, tool := range candidates {
+		var err error
+		if tool.name == "cipher" {
+			// First delete the file normally, then overwrite free
+			// space in the directory. Without cipher the removal would
+			// leave the data behind, so the next tool gets the file.
+			if _, err := exec.LookPath("cipher"); err != nil {
+				continue
+			}
+			if err = os.Remove(filePath); err == nil {
+				_, err = Commands.CombinedOutput("cipher", "/w:"+filepath.Dir(filePath))
+			}
+		} else {
+			err = tool.run(filePath)
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			continue
 		}
+		return tool, err
 	}
 
-	return errors.New("no secure deletion tool available")
+	return secureDeleteTool{}, errors.New("no secure deletion tool available")
 }
 
+// overwritePasses are the patterns manualSecureDelete writes, in order
+var overwritePasses = []string{"zeros", "ones", "random"}
+
 // manualSecureDelete performs manual secure deletion with multiple overwrite passes
-func manualSecureDelete(filePath string) error {
+func manualSecureDelete(filePath string, t *Trace) error {
 	file, err := os.OpenFile(filePath, os.O_WRONLY, 0)
 	if err != nil {
 		return fmt.Errorf("failed to open file for overwriting: %v", err)
@@ -161,27 +277,17 @@ func manualSecureDelete(filePath string) error {
 	}
 	fileSize := info.Size()
 
-	// Perform multiple overwrite passes
-	passes := [][]byte{
-		// Pass 1: All zeros
-		make([]byte, fileSize),
-		// Pass 2: All ones (0xFF)
-		func() []byte {
-			data := make([]byte, fileSize)
-			for i := range data {
-				data[i] = 0xFF
+	for passNum, pattern := range overwritePasses {
+		passData := make([]byte, fileSize)
+		switch pattern {
+		case "ones":
+			for i := range passData {
+				passData[i] = 0xFF
 			}
-			return data
-		}(),
-		// Pass 3: Random data
-		func() []byte {
-			data := make([]byte, fileSize)
-			rand.Read(data)
-			return data
-		}(),
-	}
+		case "random":
+			rand.Read(passData)
+		}
 
-	for passNum, passData := range passes {
 		// Seek to beginning of file
 		_, err := file.Seek(0, 0)
 		if err != nil {
@@ -189,7 +295,8 @@ func manualSecureDelete(filePath string) error {
 		}
 
 		// Write the pattern
-		_, err = file.Write(passData)
+		n, err := file.Write(passData)
+		t.addPass(EngineOverwrite, passNum+1, pattern, int64(n), false)
 		if err != nil {
 			return fmt.Errorf("failed to write overwrite data on pass %d: %v", passNum+1, err)
 		}
@@ -200,7 +307,7 @@ func manualSecureDelete(filePath string) error {
 			return fmt.Errorf("failed to sync on pass %d: %v", passNum+1, err)
 		}
 
-		fmt.Printf("Completed overwrite pass %d/%d for %s\n", passNum+1, len(passes), filePath)
+		fmt.Printf("Completed overwrite pass %d/%d for %s\n", passNum+1, len(overwritePasses), filePath)
 	}
 
 	file.Close()
@@ -214,25 +321,25 @@ func manualSecureDelete(filePath string) error {
 	return nil
 }
 
-// purgeDirectory recursively purges all files in a directory
-func purgeDirectory(dirPath string) error {
+// purgeDirectory recursively purges all files in a directory. Symlinks,
+// devices and other special files are skipped rather than followed.
+func purgeDirectory(dirPath string, t *Trace) error {
 	return filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		// Skip the root directory itself on first pass
-		if path == dirPath && info.IsDir() {
+		if info.IsDir() {
+			// Directories are left in place; only their files are purged
+			t.countDir()
 			return nil
 		}
-
-		if info.IsDir() {
-			// For subdirectories, we'll handle them after their contents are processed
+		if !info.Mode().IsRegular() {
+			t.countSkipped()
 			return nil
-		} else {
-			// Purge individual files
-			return purgeFile(path)
 		}
+		// Purge individual files
+		return purgeFile(path, t)
 	})
 }
 
@@ -0,0 +1,28 @@
+package drivers
+
+import "os/exec"
+
+// CommandRunner runs the external tools drivers depend on, such as
+// smartctl, lsblk, udevadm and the teardown commands of a disk wipe. Tests
+// substitute one that answers with recorded output.
+type CommandRunner interface {
+	// Output runs name and returns its standard output.
+	Output(name string, args ...string) ([]byte, error)
+	// CombinedOutput runs name and returns its standard output and error
+	// together, for commands whose diagnostics end up in error messages.
+	CombinedOutput(name string, args ...string) ([]byte, error)
+}
+
+// Commands is the runner every driver goes through.
+var Commands CommandRunner = execRunner{}
+
+// execRunner runs commands on the host.
+type execRunner struct{}
+
+func (execRunner) Output(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).Output()
+}
+
+func (execRunner) CombinedOutput(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
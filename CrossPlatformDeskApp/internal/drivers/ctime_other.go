@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package drivers
+
+import (
+	"os"
+	"time"
+)
+
+// changeTime falls back to the modification time where the platform has no
+// inode change time.
+func changeTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
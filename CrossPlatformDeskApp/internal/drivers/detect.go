@@ -1,10 +1,8 @@
 package drivers
 
 import (
-	"bufio"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -18,288 +16,70 @@ type Drive struct {
 	FileSystem  string
 	IsRemovable bool
 	Device      string 
+
+	// Hardware details, filled in on Linux from sysfs.
+	Size               int64 // bytes
+	Model              string
+	Serial             string
+	WWN                string
+	Vendor             string
+	Rotational         bool
+	ReadOnly           bool
+	Transport          string // usb, sata, nvme, mmc, virtio, ...
+	LogicalSectorSize  int
+	PhysicalSectorSize int
+	// Fingerprint identifies the physical drive across inserts; see
+	// Fingerprint.
+	Fingerprint        string
+	Partitions         []Partition
+	// Holders is what is stacked on the whole disk, such as an LVM physical
+	// volume or RAID member without a partition table.
+	Holders []Holder
+	// MountPoints lists every mount of the disk, its partitions and the
+	// devices stacked on them; Path is the one offered for browsing.
+	MountPoints []string
+	// MountOptions are the options Path is mounted with, where known.
+	MountOptions []string
+	// Usage is the capacity of the filesystem at Path; nil when the drive
+	// is not mounted or statfs failed.
+	Usage *Usage
+	// USB identifies the USB device or bridge of a USB-attached drive.
+	USB *USBInfo
 }
 
 
 func GetDrives() ([]Drive, error) {
+	var drives []Drive
+	var err error
 	switch runtime.GOOS {
 	case "linux":
-		return getLinuxDrives()
+		drives, err = getLinuxDrives()
 	case "windows":
-		return getWindowsDrives()
+		drives, err = getWindowsDrives()
 	case "darwin":
-		return getMacDrives()
+		drives, err = getMacDrives()
 	default:
 		return nil, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
 	}
-}
-
-
-func getLinuxDrives() ([]Drive, error) {
-	var drives []Drive
-	
-
-	mountedDrives := getMountedDrives()
-	drives = append(drives, mountedDrives...)
-	
-	
-	blockDevices := getBlockDevices()
-	drives = append(drives, blockDevices...)
-	
-	
-	commonMounts := getCommonMountPoints()
-	drives = append(drives, commonMounts...)
-	
-	
-	drives = removeDuplicateDrives(drives)
-	
-	return drives, nil
-}
-
-
-func getMountedDrives() []Drive {
-	var drives []Drive
-	
-	file, err := os.Open("/proc/mounts")
-	if err != nil {
-		return drives
-	}
-	defer file.Close()
-	
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		fields := strings.Fields(line)
-		if len(fields) < 4 {
-			continue
-		}
-		
-		device := fields[0]
-		mountPoint := fields[1]
-		fsType := fields[2]
-		
-		
-		if strings.HasPrefix(mountPoint, "/sys") ||
-			strings.HasPrefix(mountPoint, "/proc") ||
-			strings.HasPrefix(mountPoint, "/dev") ||
-			strings.HasPrefix(mountPoint, "/run") && !strings.Contains(mountPoint, "/media") ||
-			mountPoint == "/" ||
-			mountPoint == "/boot" ||
-			strings.HasPrefix(device, "tmpfs") ||
-			strings.HasPrefix(device, "devtmpfs") {
-			continue
-		}
-		
-		
-		isUSB := false
-		driveType := "internal"
-		
-		if strings.HasPrefix(device, "/dev/sd") || strings.HasPrefix(device, "/dev/nvme") {
-			
-			isUSB = isUSBDevice(device)
-			if isUSB {
-				driveType = "usb"
-			}
-		}
-		
-		
-		name := filepath.Base(mountPoint)
-		if name == "/" || name == "" {
-			name = device
-		}
-		
-	
-		if strings.Contains(mountPoint, "/media/") || strings.Contains(mountPoint, "/mnt/") {
-			parts := strings.Split(mountPoint, "/")
-			if len(parts) > 0 {
-				name = parts[len(parts)-1]
-			}
-		}
-		
-		drives = append(drives, Drive{
-			Name:        name,
-			Path:        mountPoint,
-			Type:        driveType,
-			FileSystem:  fsType,
-			IsRemovable: isUSB,
-			Device:      device,
-		})
-	}
-	
-	return drives
-}
-
-
-func getBlockDevices() []Drive {
-	var drives []Drive
-	
-	
-	cmd := exec.Command("lsblk", "-rno", "NAME,TYPE,SIZE,MOUNTPOINT,FSTYPE,MODEL,TRAN")
-	output, err := cmd.Output()
-	if err != nil {
-		return drives
-	}
-	
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		fields := strings.Fields(line)
-		if len(fields) < 4 {
-			continue
-		}
-		
-		name := fields[0]
-		devType := fields[1]
-		mountPoint := fields[3]
-		fsType := ""
-		model := ""
-		transport := ""
-		
-		if len(fields) > 4 {
-			fsType = fields[4]
-		}
-		if len(fields) > 5 {
-			model = fields[5]
-		}
-		if len(fields) > 6 {
-			transport = fields[6]
-		}
-		
-		
-		if (devType != "part" && devType != "disk") || mountPoint == "" {
-			continue
-		}
-		
-		
-		if mountPoint == "/" || mountPoint == "/boot" || strings.HasPrefix(mountPoint, "/boot/") {
-			continue
-		}
-		
-		
-		isUSB := transport == "usb"
-		driveType := "internal"
-		if isUSB {
-			driveType = "usb"
-		}
-		
-		devicePath := "/dev/" + name
-		
-		
-		driveName := name
-		if model != "" && model != "N/A" {
-			driveName = model
-		} else if mountPoint != "" {
-			driveName = filepath.Base(mountPoint)
-		}
-		
-		drives = append(drives, Drive{
-			Name:        driveName,
-			Path:        mountPoint,
-			Type:        driveType,
-			FileSystem:  fsType,
-			IsRemovable: isUSB,
-			Device:      devicePath,
-		})
-	}
-	
-	return drives
-}
-
 
-func getCommonMountPoints() []Drive {
-	var drives []Drive
-	
-	
-	mountPoints := []string{
-		"/media",
-		"/mnt",
-		fmt.Sprintf("/run/media/%s", os.Getenv("USER")),
-		fmt.Sprintf("/media/%s", os.Getenv("USER")),
-	}
-	
-	for _, base := range mountPoints {
-		dirs, err := os.ReadDir(base)
-		if err != nil {
+	for i := range drives {
+		if drives[i].Path == "" {
 			continue
 		}
-		
-		for _, d := range dirs {
-			info, err := d.Info()
-			if err != nil || !info.IsDir() {
-				continue
-			}
-			
-			fullPath := filepath.Join(base, d.Name())
-			
-		
-			isUSB := false
-			driveType := "external"
-			
-			
-			if device := findDeviceForMountPoint(fullPath); device != "" {
-				isUSB = isUSBDevice(device)
-				if isUSB {
-					driveType = "usb"
-				}
-			}
-			
-			drives = append(drives, Drive{
-				Name:        d.Name(),
-				Path:        fullPath,
-				Type:        driveType,
-				IsRemovable: isUSB,
-			})
-		}
-	}
-	
-	return drives
-}
-
-
-func isUSBDevice(device string) bool {
-	
-	deviceName := strings.TrimPrefix(device, "/dev/")
-	deviceName = strings.TrimRight(deviceName, "0123456789")
-	
-	
-	sysPaths := []string{
-		fmt.Sprintf("/sys/block/%s/removable", deviceName),
-		fmt.Sprintf("/sys/class/block/%s/removable", deviceName),
-	}
-	
-	for _, sysPath := range sysPaths {
-		data, err := os.ReadFile(sysPath)
-		if err == nil && strings.TrimSpace(string(data)) == "1" {
-			return true
-		}
-	}
-	
-	
-	usbPath := fmt.Sprintf("/sys/block/%s", deviceName)
-	if linkDest, err := os.Readlink(usbPath); err == nil {
-		if strings.Contains(linkDest, "/usb") {
-			return true
+		if usage, err := FilesystemUsage(drives[i].Path); err == nil {
+			drives[i].Usage = &usage
 		}
 	}
-	
-	return false
+	return drives, err
 }
 
 
-func findDeviceForMountPoint(mountPoint string) string {
-	file, err := os.Open("/proc/mounts")
+func getLinuxDrives() ([]Drive, error) {
+	drives, err := ScanBlockDevices(HostRoots)
 	if err != nil {
-		return ""
+		return nil, err
 	}
-	defer file.Close()
-	
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		fields := strings.Fields(scanner.Text())
-		if len(fields) >= 2 && fields[1] == mountPoint {
-			return fields[0]
-		}
-	}
-	return ""
+	return removeDuplicateDrives(drives), nil
 }
 
 
@@ -307,8 +87,7 @@ func getWindowsDrives() ([]Drive, error) {
 	var drives []Drive
 	
 	
-	cmd := exec.Command("wmic", "logicaldisk", "get", "size,freespace,name,volumename,drivetype,filesystem")
-	output, err := cmd.Output()
+	output, err := Commands.Output("wmic", "logicaldisk", "get", "size,freespace,name,volumename,drivetype,filesystem")
 	if err != nil {
 		
 		return getWindowsDrivesSimple()
@@ -429,8 +208,7 @@ func getMacDrives() ([]Drive, error) {
 		driveType := "external"
 		isRemovable := false
 		
-		cmd := exec.Command("diskutil", "info", fullPath)
-		if output, err := cmd.Output(); err == nil {
+		if output, err := Commands.Output("diskutil", "info", fullPath); err == nil {
 			outputStr := string(output)
 			if strings.Contains(outputStr, "Protocol:") && strings.Contains(outputStr, "USB") {
 				driveType = "usb"
@@ -455,11 +233,13 @@ func removeDuplicateDrives(drives []Drive) []Drive {
 	result := []Drive{}
 	
 	for _, drive := range drives {
-		if drive.Path != "" && !seen[drive.Path] {
-			seen[drive.Path] = true
+		// Unmounted disks have no Path, so the device node is the key.
+		key := DriveKey(drive)
+		if key != "" && !seen[key] {
+			seen[key] = true
 			result = append(result, drive)
 		}
 	}
 	
 	return result
-}
\ No newline at end of file
+}
@@ -0,0 +1,36 @@
+package drivers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoveDuplicateDrives(t *testing.T) {
+	drives := []Drive{
+		{Name: "Ultra Fit", Device: "/dev/sdc", Path: "/media/alex/My Stick"},
+		// The same stick seen again through a second mount.
+		{Name: "Ultra Fit", Device: "/dev/sdc", Path: "/mnt/stick"},
+		// Mounted volumes without a device node are told apart by path.
+		{Name: "Backup", Path: "/Volumes/Backup"},
+		{Name: "Backup", Path: "/Volumes/Backup"},
+		{Name: "Photos", Path: "/Volumes/Photos"},
+		// Unmounted disks have only their device node.
+		{Name: "ST2000DM008", Device: "/dev/sda"},
+		// Nothing to identify the drive by.
+		{Name: "ghost"},
+	}
+
+	var got []string
+	for _, d := range removeDuplicateDrives(drives) {
+		got = append(got, d.Name+" "+d.Path)
+	}
+	want := []string{
+		"Ultra Fit /media/alex/My Stick",
+		"Backup /Volumes/Backup",
+		"Photos /Volumes/Photos",
+		"ST2000DM008 ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("removeDuplicateDrives = %q, want %q", got, want)
+	}
+}
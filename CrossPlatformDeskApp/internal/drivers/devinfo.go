@@ -0,0 +1,180 @@
+package drivers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DeviceDetails is what lsblk and udev report about one block device,
+// looked up by its path rather than found by a scan.
+type DeviceDetails struct {
+	Device string
+	// Disk is the whole disk a partition belongs to; empty for anything
+	// else.
+	Disk       string
+	Type       string // lsblk TYPE: disk, part, crypt, lvm, raid1, loop, rom
+	Size       int64  // bytes
+	Rotational bool
+	// Removable is the kernel's removable flag, set for card readers and
+	// most flash sticks.
+	Removable bool
+	Transport string
+	Model     string
+	Serial    string
+}
+
+// LookupDevice describes device, such as /dev/sdb or /dev/sdb1, through
+// lsblk and udevadm. Partitions report the model and serial of their disk.
+func LookupDevice(device string) (DeviceDetails, error) {
+	return lookupDevice(Commands, HostRoots, device)
+}
+
+func lookupDevice(run CommandRunner, roots Roots, device string) (DeviceDetails, error) {
+	out, err := run.Output("lsblk", "-b", "-d", "-n", "-P", "-o", "NAME,PKNAME,TYPE,SIZE,ROTA,RM,TRAN,MODEL,SERIAL", device)
+	if err != nil {
+		return DeviceDetails{}, fmt.Errorf("lsblk failed for %s: %v", device, err)
+	}
+	rows := parseLsblkPairs(out)
+	if len(rows) == 0 {
+		return DeviceDetails{}, fmt.Errorf("lsblk reported nothing for %s", device)
+	}
+	row := rows[0]
+
+	d := DeviceDetails{
+		Device:     device,
+		Type:       row["TYPE"],
+		Rotational: row["ROTA"] == "1",
+		Removable:  row["RM"] == "1",
+		Transport:  row["TRAN"],
+		Model:      row["MODEL"],
+		Serial:     row["SERIAL"],
+	}
+	d.Size, _ = strconv.ParseInt(row["SIZE"], 10, 64)
+	disk := row["NAME"]
+	if d.Type == "part" && row["PKNAME"] != "" {
+		disk = row["PKNAME"]
+		d.Disk = "/dev/" + disk
+	}
+
+	// udev copies the disk's identity onto its partitions, so this works
+	// for both; sysfs only has the serial on the disk.
+	var udev map[string]string
+	if out, err := run.Output("udevadm", "info", "--query=property", "--name="+device); err == nil {
+		udev = parseUdevProperties(out)
+	}
+	d.Model = firstNonEmpty(d.Model, strings.ReplaceAll(udev["ID_MODEL"], "_", " "))
+	d.Serial = firstNonEmpty(d.Serial,
+		readSysString(filepath.Join(roots.Sys, "class", "block", disk), "device/serial"),
+		udev["ID_SERIAL_SHORT"], udev["ID_SERIAL"])
+	d.Transport = firstNonEmpty(d.Transport, udev["ID_BUS"])
+	return d, nil
+}
+
+// MediaType names what kind of device d is for a certificate: "partition",
+// "optical", the disk's kind as MediaType tells it, or lsblk's own type for
+// virtual devices such as crypt, lvm and loop.
+func (d DeviceDetails) MediaType() string {
+	switch d.Type {
+	case "part":
+		return "partition"
+	case "disk":
+		return MediaType(d.Device, d.Transport, d.Rotational, d.Removable)
+	case "rom":
+		return "optical"
+	}
+	return d.Type
+}
+
+// MediaType names what kind of disk d is for a certificate; see MediaType.
+func (d Drive) MediaType() string {
+	return MediaType(d.Device, d.Transport, d.Rotational, d.IsRemovable)
+}
+
+// MediaType names a kind of disk from what the kernel knows for certain:
+// "usb" for anything attached over USB, "sd/emmc" for MMC devices, "ssd"
+// for NVMe, "virtual" for virtio disks and "removable" for other removable
+// media. Only SATA and SAS disks are told apart by rotation into "hdd" and
+// "ssd": flash sticks and card readers often claim to spin, and virtual
+// disks report whatever the host chooses. Anything else is "unknown".
+func MediaType(device, transport string, rotational, removable bool) string {
+	name := filepath.Base(device)
+	switch {
+	case transport == "usb":
+		return "usb"
+	case transport == "mmc" || strings.HasPrefix(name, "mmcblk"):
+		return "sd/emmc"
+	case transport == "nvme" || strings.HasPrefix(name, "nvme"):
+		return "ssd"
+	case transport == "virtio" || strings.HasPrefix(name, "vd"):
+		return "virtual"
+	case removable:
+		return "removable"
+	case transport == "sata" || transport == "sas" || transport == "ata":
+		if rotational {
+			return "hdd"
+		}
+		return "ssd"
+	}
+	return "unknown"
+}
+
+// parseLsblkPairs parses lsblk -P output, one map of column to value per
+// line. lsblk quotes every value and escapes unsafe bytes as \xNN.
+func parseLsblkPairs(out []byte) []map[string]string {
+	var rows []map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		row := make(map[string]string)
+		line := scanner.Text()
+		for {
+			line = strings.TrimLeft(line, " ")
+			key, rest, ok := strings.Cut(line, `="`)
+			if !ok {
+				break
+			}
+			end := strings.IndexByte(rest, '"')
+			if end < 0 {
+				break
+			}
+			row[key] = strings.TrimSpace(unescapeLsblk(rest[:end]))
+			line = rest[end+1:]
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func unescapeLsblk(s string) string {
+	if !strings.Contains(s, `\x`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if n, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// parseUdevProperties parses udevadm info --query=property output.
+func parseUdevProperties(out []byte) map[string]string {
+	props := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[key] = value
+		}
+	}
+	return props
+}
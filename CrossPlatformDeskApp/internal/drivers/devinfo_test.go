@@ -0,0 +1,151 @@
+package drivers
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var lsblkColumns = []string{"lsblk", "-b", "-d", "-n", "-P", "-o", "NAME,PKNAME,TYPE,SIZE,ROTA,RM,TRAN,MODEL,SERIAL"}
+
+func lsblkCommand(device string) []string {
+	return append(append([]string(nil), lsblkColumns...), device)
+}
+
+func TestLookupDeviceDisk(t *testing.T) {
+	run := newFakeRunner(t)
+	run.record(filepath.Join("lsblk", "sata_disk.txt"), nil, lsblkCommand("/dev/sda")...)
+	run.record(filepath.Join("udevadm", "sda.txt"), nil, "udevadm", "info", "--query=property", "--name=/dev/sda")
+
+	d, err := lookupDevice(run, Roots{Sys: t.TempDir()}, "/dev/sda")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DeviceDetails{
+		Device:    "/dev/sda",
+		Type:      "disk",
+		Size:      500107862016,
+		Transport: "sata",
+		Model:     "Samsung SSD 870 EVO 500GB",
+		Serial:    "S6PXNS0T412345A",
+	}
+	if d != want {
+		t.Errorf("lookupDevice = %+v, want %+v", d, want)
+	}
+}
+
+func TestLookupDevicePartition(t *testing.T) {
+	run := newFakeRunner(t)
+	run.record(filepath.Join("lsblk", "usb_partition.txt"), nil, lsblkCommand("/dev/sdc1")...)
+	run.record(filepath.Join("udevadm", "sdc1.txt"), nil, "udevadm", "info", "--query=property", "--name=/dev/sdc1")
+
+	// lsblk leaves model, serial and transport blank for partitions; udev
+	// has them from the disk.
+	d, err := lookupDevice(run, Roots{Sys: t.TempDir()}, "/dev/sdc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DeviceDetails{
+		Device:     "/dev/sdc1",
+		Disk:       "/dev/sdc",
+		Type:       "part",
+		Size:       30752636928,
+		Rotational: true,
+		Removable:  true,
+		Transport:  "usb",
+		Model:      "Ultra Fit",
+		Serial:     "4C530001230712114275",
+	}
+	if d != want {
+		t.Errorf("lookupDevice = %+v, want %+v", d, want)
+	}
+}
+
+func TestLookupDeviceSysfsSerial(t *testing.T) {
+	h := desktopHost(t)
+	h.write(filepath.Join(h.roots.Sys, "block", "sdc", "device", "serial"), "4C530001230712114275\n")
+	run := newFakeRunner(t)
+	run.record(filepath.Join("lsblk", "usb_partition.txt"), nil, lsblkCommand("/dev/sdc1")...)
+
+	// Without udev the serial comes from the disk's sysfs entry.
+	d, err := lookupDevice(run, h.roots, "/dev/sdc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Serial != "4C530001230712114275" || d.Model != "" {
+		t.Errorf("serial %q, model %q", d.Serial, d.Model)
+	}
+}
+
+func TestLookupDeviceWithoutLsblk(t *testing.T) {
+	_, err := lookupDevice(newFakeRunner(t), Roots{Sys: t.TempDir()}, "/dev/sda")
+	if err == nil || !strings.Contains(err.Error(), "lsblk failed for /dev/sda") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	tests := []struct {
+		details DeviceDetails
+		want    string
+	}{
+		{DeviceDetails{Device: "/dev/sda", Type: "disk", Transport: "sata"}, "ssd"},
+		{DeviceDetails{Device: "/dev/sda", Type: "disk", Transport: "sata", Rotational: true}, "hdd"},
+		{DeviceDetails{Device: "/dev/sdb", Type: "disk", Transport: "sas", Rotational: true}, "hdd"},
+		{DeviceDetails{Device: "/dev/nvme0n1", Type: "disk"}, "ssd"},
+		// Flash sticks are not SSDs, and often claim to spin
+		{DeviceDetails{Device: "/dev/sdc", Type: "disk", Transport: "usb", Removable: true, Rotational: true}, "usb"},
+		{DeviceDetails{Device: "/dev/sdd", Type: "disk", Transport: "usb"}, "usb"},
+		{DeviceDetails{Device: "/dev/mmcblk0", Type: "disk"}, "sd/emmc"},
+		{DeviceDetails{Device: "/dev/mmcblk1", Type: "disk", Transport: "mmc", Removable: true}, "sd/emmc"},
+		{DeviceDetails{Device: "/dev/vda", Type: "disk", Rotational: true}, "virtual"},
+		{DeviceDetails{Device: "/dev/sde", Type: "disk", Removable: true}, "removable"},
+		// Nothing says what it is
+		{DeviceDetails{Device: "/dev/sdf", Type: "disk"}, "unknown"},
+		{DeviceDetails{Device: "/dev/sdf", Type: "disk", Transport: "iscsi"}, "unknown"},
+		// A partition is named as such whatever its disk is
+		{DeviceDetails{Type: "part", Rotational: true}, "partition"},
+		{DeviceDetails{Type: "rom", Rotational: true}, "optical"},
+		{DeviceDetails{Type: "crypt"}, "crypt"},
+		{DeviceDetails{Type: "raid1", Rotational: true}, "raid1"},
+		{DeviceDetails{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.details.MediaType(); got != tt.want {
+			t.Errorf("%+v.MediaType() = %q, want %q", tt.details, got, tt.want)
+		}
+	}
+
+	// The desktop fixture's drives, as the disk wipe labels them
+	drives, err := ScanBlockDevices(desktopHost(t).roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for device, want := range map[string]string{"/dev/nvme0n1": "ssd", "/dev/sda": "hdd", "/dev/sdc": "usb"} {
+		d, ok := driveForPath(drives, device)
+		if !ok || d.MediaType() != want {
+			t.Errorf("%s: MediaType() = %q, want %q", device, d.MediaType(), want)
+		}
+	}
+}
+
+func TestParseLsblkPairs(t *testing.T) {
+	out := []byte(`NAME="sda" MODEL="WDC\x20WD40EFPX \x22Red\x22" SERIAL=""
+NAME="sdb" MODEL="  Flash Disk  " SERIAL="AA00000000000489"
+`)
+	want := []map[string]string{
+		{"NAME": "sda", "MODEL": `WDC WD40EFPX "Red"`, "SERIAL": ""},
+		{"NAME": "sdb", "MODEL": "Flash Disk", "SERIAL": "AA00000000000489"},
+	}
+	if got := parseLsblkPairs(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLsblkPairs = %q, want %q", got, want)
+	}
+}
+
+func TestParseUdevProperties(t *testing.T) {
+	props := parseUdevProperties([]byte("ID_BUS=usb\nID_FS_LABEL_ENC=My\\x20Stick\nDEVLINKS=/dev/disk/by-uuid/9C33-6BBD /dev/disk/by-label/My\\x20Stick\n\n"))
+	if props["ID_BUS"] != "usb" || props["ID_FS_LABEL_ENC"] != `My\x20Stick` || len(props) != 3 {
+		t.Errorf("parseUdevProperties = %q", props)
+	}
+}
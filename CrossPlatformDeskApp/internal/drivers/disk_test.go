@@ -0,0 +1,112 @@
+package drivers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestCheckDiskWipeMounts(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("whole-disk wipes are Linux only")
+	}
+	tests := []struct {
+		mounts []string
+		want   string
+	}{
+		{nil, ""},
+		{[]string{"/media/alex/My Stick", "/run/media/sam/T7", "/mnt/backup"}, ""},
+		{[]string{"/media/alex/My Stick", "/home"}, "/home is mounted"},
+		{[]string{"/var"}, "in use"},
+		{[]string{"/srv/data"}, "in use"},
+		{[]string{"/media"}, "in use"},
+		{[]string{"/mnt"}, "in use"},
+		{[]string{"/mediastore/x"}, "in use"},
+		{[]string{"/"}, "running system"},
+	}
+	for _, tt := range tests {
+		err := CheckDiskWipe(Drive{Device: "/dev/sdb", MountPoints: tt.mounts})
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%q: unexpected error %v", tt.mounts, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%q: err = %v, want %q", tt.mounts, err, tt.want)
+		}
+	}
+}
+
+func TestAlignedBuffer(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		buf := alignedBuffer(diskChunk)
+		if len(buf) != diskChunk || uintptr(unsafe.Pointer(&buf[0]))%4096 != 0 {
+			t.Fatalf("buffer of %d bytes at %p", len(buf), &buf[0])
+		}
+	}
+}
+
+// patternFile writes size bytes of the AES-CTR pattern a wipe writes.
+func patternFile(t *testing.T, size int) (string, func() cipher.Stream) {
+	t.Helper()
+	block, err := aes.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := func() cipher.Stream { return cipher.NewCTR(block, make([]byte, aes.BlockSize)) }
+	data := make([]byte, size)
+	stream().XORKeyStream(data, data)
+	path := filepath.Join(t.TempDir(), "disk.img")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Direct I/O is not available on every filesystem, tmpfs among them
+	if f, err := os.OpenFile(path, os.O_RDONLY|directIO, 0); err != nil {
+		t.Skipf("no direct I/O here: %v", err)
+	} else {
+		f.Close()
+	}
+	return path, stream
+}
+
+func TestVerifyDisk(t *testing.T) {
+	const size = diskChunk + 8192
+	path, stream := patternFile(t, size)
+
+	var progress []int64
+	opts := DiskWipeOptions{Progress: func(phase string, done, total int64) {
+		if phase != PhaseVerify || total != size {
+			t.Errorf("progress %s %d/%d", phase, done, total)
+		}
+		progress = append(progress, done)
+	}}
+	if err := verifyDisk(path, size, stream(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) != 2 || progress[1] != size {
+		t.Errorf("progress %v", progress)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteAt([]byte{0}, diskChunk+100)
+	f.Close()
+	if err := verifyDisk(path, size, stream(), DiskWipeOptions{}); err == nil || !strings.Contains(err.Error(), "near byte 4194304") {
+		t.Errorf("altered image: %v", err)
+	}
+
+	cancel := make(chan struct{})
+	close(cancel)
+	if err := verifyDisk(path, size, stream(), DiskWipeOptions{Cancel: cancel}); !errors.Is(err, ErrWipeCancelled) {
+		t.Errorf("cancelled verification: %v", err)
+	}
+	if err := verifyDisk(path, size+4096, stream(), DiskWipeOptions{}); err == nil || !strings.Contains(err.Error(), "read failed") {
+		t.Errorf("image shorter than the disk: %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+package drivers
+
+import "syscall"
+
+// directIO bypasses the page cache so verification reads come from the
+// medium rather than from memory.
+const directIO = syscall.O_DIRECT
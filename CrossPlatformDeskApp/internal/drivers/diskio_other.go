@@ -0,0 +1,6 @@
+//go:build !linux
+
+package drivers
+
+// directIO is unavailable; reads and writes go through the page cache.
+const directIO = 0
@@ -0,0 +1,234 @@
+package drivers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeRunner answers commands with recorded output, keyed by the whole
+// command line. Commands without a recording fail as if not installed.
+type fakeRunner struct {
+	t       *testing.T
+	outputs map[string]fakeOutput
+	ran     []string
+}
+
+type fakeOutput struct {
+	out []byte
+	err error
+}
+
+func newFakeRunner(t *testing.T) *fakeRunner {
+	return &fakeRunner{t: t, outputs: make(map[string]fakeOutput)}
+}
+
+// stubCommands installs an empty fakeRunner as Commands for the test.
+func stubCommands(t *testing.T) *fakeRunner {
+	t.Helper()
+	f := newFakeRunner(t)
+	saved := Commands
+	t.Cleanup(func() { Commands = saved })
+	Commands = f
+	return f
+}
+
+// record makes the command line answer with testdata/fixture and err.
+func (f *fakeRunner) record(fixture string, err error, command ...string) {
+	f.t.Helper()
+	data, readErr := os.ReadFile(filepath.Join("testdata", fixture))
+	if readErr != nil {
+		f.t.Fatal(readErr)
+	}
+	f.outputs[strings.Join(command, " ")] = fakeOutput{data, err}
+}
+
+func (f *fakeRunner) Output(name string, args ...string) ([]byte, error) {
+	line := strings.Join(append([]string{name}, args...), " ")
+	f.ran = append(f.ran, line)
+	o, ok := f.outputs[line]
+	if !ok {
+		return nil, &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+	return o.out, o.err
+}
+
+func (f *fakeRunner) CombinedOutput(name string, args ...string) ([]byte, error) {
+	return f.Output(name, args...)
+}
+
+// fakeHost builds a sysfs, procfs and udev database under a temporary
+// directory, laid out the way the kernel and udev lay out theirs.
+type fakeHost struct {
+	t     *testing.T
+	roots Roots
+}
+
+// newFakeHost starts a host whose mount table is testdata/mountinfo/name.
+func newFakeHost(t *testing.T, mountinfo string) *fakeHost {
+	t.Helper()
+	dir := t.TempDir()
+	h := &fakeHost{t: t, roots: Roots{
+		Sys:  filepath.Join(dir, "sys"),
+		Proc: filepath.Join(dir, "proc"),
+		Udev: filepath.Join(dir, "run", "udev", "data"),
+	}}
+	data, err := os.ReadFile(filepath.Join("testdata", "mountinfo", mountinfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.write(filepath.Join(h.roots.Proc, "self", "mountinfo"), string(data))
+	h.write(filepath.Join(h.roots.Proc, "swaps"), "Filename\t\t\t\tType\t\tSize\t\tUsed\t\tPriority\n")
+	for _, d := range []string{"block", "class/block", "devices", "bus/usb/drivers"} {
+		h.mkdir(filepath.Join(h.roots.Sys, d))
+	}
+	return h
+}
+
+// disk adds the block device name with major:minor dev below the device
+// directory parent, relative to /sys/devices, and returns its directory.
+// attrs are written relative to the block device, so "device/model" lands
+// in parent.
+func (h *fakeHost) disk(parent, name, dev string, sectors int64, attrs map[string]string) string {
+	h.t.Helper()
+	rel := filepath.Join("devices", parent, "block", name)
+	dir := h.blockDir(rel, dev, sectors)
+	h.symlink(filepath.Join("..", ".."), filepath.Join(dir, "device"))
+	h.symlink(filepath.Join("..", rel), filepath.Join(h.roots.Sys, "block", name))
+	h.symlink(filepath.Join("..", "..", rel), filepath.Join(h.roots.Sys, "class", "block", name))
+	for attr, value := range attrs {
+		h.write(filepath.Join(dir, attr), value+"\n")
+	}
+	return dir
+}
+
+// partition adds partition number of disk.
+func (h *fakeHost) partition(disk, name, dev string, number int, start, sectors int64) {
+	h.t.Helper()
+	link, err := os.Readlink(filepath.Join(h.roots.Sys, "block", disk))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	rel := filepath.Join(strings.TrimPrefix(link, "../"), name)
+	dir := h.blockDir(rel, dev, sectors)
+	h.write(filepath.Join(dir, "partition"), itoa(int64(number))+"\n")
+	h.write(filepath.Join(dir, "start"), itoa(start)+"\n")
+	h.symlink(filepath.Join("..", "..", rel), filepath.Join(h.roots.Sys, "class", "block", name))
+}
+
+// virtual adds a device-mapper, md or loop device, which the kernel lists
+// under /sys/devices/virtual.
+func (h *fakeHost) virtual(name, dev string, sectors int64, attrs map[string]string) {
+	h.t.Helper()
+	rel := filepath.Join("devices", "virtual", "block", name)
+	dir := h.blockDir(rel, dev, sectors)
+	for attr, value := range attrs {
+		h.write(filepath.Join(dir, attr), value+"\n")
+	}
+	h.symlink(filepath.Join("..", rel), filepath.Join(h.roots.Sys, "block", name))
+	h.symlink(filepath.Join("..", "..", rel), filepath.Join(h.roots.Sys, "class", "block", name))
+}
+
+// stack records that upper is built on lower, through the holders and
+// slaves links.
+func (h *fakeHost) stack(lower, upper string) {
+	h.t.Helper()
+	lowerDir := h.resolve(filepath.Join(h.roots.Sys, "class", "block", lower))
+	upperDir := h.resolve(filepath.Join(h.roots.Sys, "class", "block", upper))
+	h.symlink(h.rel(filepath.Join(lowerDir, "holders"), upperDir), filepath.Join(lowerDir, "holders", upper))
+	h.symlink(h.rel(filepath.Join(upperDir, "slaves"), lowerDir), filepath.Join(upperDir, "slaves", lower))
+}
+
+// udev records properties for the device with major:minor dev in the udev
+// database.
+func (h *fakeHost) udev(dev string, props ...string) {
+	h.t.Helper()
+	var b strings.Builder
+	for _, p := range props {
+		b.WriteString("E:" + p + "\n")
+	}
+	h.write(filepath.Join(h.roots.Udev, "b"+dev), b.String())
+}
+
+// swap lists device as active swap in /proc/swaps.
+func (h *fakeHost) swap(device string) {
+	h.t.Helper()
+	f, err := os.OpenFile(filepath.Join(h.roots.Proc, "swaps"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer f.Close()
+	f.WriteString(device + "                               partition\t8388604\t\t0\t\t-2\n")
+}
+
+// usbDevice adds the USB device and storage interface a disk below
+// devices/usbPath/<port>:1.0/... hangs off, bound to driver.
+func (h *fakeHost) usbDevice(usbPath string, attrs map[string]string, driver string) {
+	h.t.Helper()
+	dir := filepath.Join(h.roots.Sys, "devices", usbPath)
+	for attr, value := range attrs {
+		h.write(filepath.Join(dir, attr), value+"\n")
+	}
+	iface := filepath.Join(dir, filepath.Base(usbPath)+":1.0")
+	h.write(filepath.Join(iface, "bInterfaceClass"), "08\n")
+	driverDir := filepath.Join(h.roots.Sys, "bus", "usb", "drivers", driver)
+	h.mkdir(driverDir)
+	h.symlink(h.rel(iface, driverDir), filepath.Join(iface, "driver"))
+}
+
+func (h *fakeHost) blockDir(rel, dev string, sectors int64) string {
+	dir := filepath.Join(h.roots.Sys, rel)
+	h.write(filepath.Join(dir, "dev"), dev+"\n")
+	h.write(filepath.Join(dir, "size"), itoa(sectors)+"\n")
+	return dir
+}
+
+// resolve follows the symlinks in path.
+func (h *fakeHost) resolve(path string) string {
+	h.t.Helper()
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return resolved
+}
+
+// rel is target relative to dir, for a symlink placed in dir.
+func (h *fakeHost) rel(dir, target string) string {
+	h.t.Helper()
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return rel
+}
+
+func (h *fakeHost) write(path, data string) {
+	h.t.Helper()
+	h.mkdir(filepath.Dir(path))
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+func (h *fakeHost) mkdir(dir string) {
+	h.t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+func (h *fakeHost) symlink(target, link string) {
+	h.t.Helper()
+	h.mkdir(filepath.Dir(link))
+	if err := os.Symlink(target, link); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
@@ -0,0 +1,70 @@
+package drivers
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// WipeFreeSpace overwrites the unallocated space of the filesystem holding
+// dir. It fills a temporary file with random data until the filesystem
+// reports it is full, syncs it and removes it again, so blocks released by
+// earlier plain deletes no longer hold their old contents. It returns the
+// number of bytes written.
+func WipeFreeSpace(dir string) (int64, error) {
+	if dir == "" {
+		return 0, errors.New("path cannot be empty")
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat path %s: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return 0, fmt.Errorf("not a directory: %s", dir)
+	}
+	if err := checkTargets(dir); err != nil {
+		return 0, err
+	}
+
+	file, err := os.CreateTemp(dir, ".nullbyters-freespace-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create fill file in %s: %v", dir, err)
+	}
+	fillPath := file.Name()
+	defer os.Remove(fillPath)
+	defer file.Close()
+
+	buf := make([]byte, 1024*1024)
+	var written int64
+	for {
+		if _, err := rand.Read(buf); err != nil {
+			return written, fmt.Errorf("failed to generate random data: %v", err)
+		}
+		n, err := file.Write(buf)
+		written += int64(n)
+		if err != nil {
+			if isDiskFull(err) {
+				break
+			}
+			return written, fmt.Errorf("failed to write fill file: %v", err)
+		}
+	}
+
+	if err := file.Sync(); err != nil && !isDiskFull(err) {
+		return written, fmt.Errorf("failed to sync fill file: %v", err)
+	}
+
+	fmt.Printf("Free space wiped in %s: %d bytes overwritten\n", dir, written)
+	return written, nil
+}
+
+// WipeFreeSpaceTraced is WipeFreeSpace, recording the fill pass and any
+// error in t.
+func WipeFreeSpaceTraced(dir string, t *Trace) (int64, error) {
+	written, err := WipeFreeSpace(dir)
+	t.addPass(EngineFreeSpace, 1, "random", written, false)
+	t.fail(dir, err)
+	return written, err
+}
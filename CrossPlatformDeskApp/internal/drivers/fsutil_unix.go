@@ -0,0 +1,45 @@
+//go:build !windows
+
+package drivers
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// geteuid is os.Geteuid, replaced in tests.
+var geteuid = os.Geteuid
+
+// fileOwner is the user an OwnedOnly sweep profile matches entries of.
+type fileOwner uint32
+
+// sweepOwner returns the user whose entries an OwnedOnly profile may match.
+// Run as root, that is the user sudo was invoked by: root's own entries in
+// a shared location like /tmp belong to running services. Without sudo
+// there is no such user, so the profile is refused.
+func sweepOwner() (fileOwner, error) {
+	if uid := geteuid(); uid != 0 {
+		return fileOwner(uid), nil
+	}
+	uid, err := strconv.ParseUint(os.Getenv("SUDO_UID"), 10, 32)
+	if err != nil || uid == 0 {
+		return 0, errors.New("cannot tell whose files are whose when running as root; run the sweep as the user whose files it is for")
+	}
+	return fileOwner(uid), nil
+}
+
+// owns reports whether info belongs to o. Entries owned by root never do.
+func (o fileOwner) owns(path string, info os.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return st.Uid != 0 && st.Uid == uint32(o)
+}
+
+// isDiskFull reports whether a write failed because the filesystem is full.
+func isDiskFull(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
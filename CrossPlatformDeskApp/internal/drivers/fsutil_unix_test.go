@@ -0,0 +1,94 @@
+//go:build !windows
+
+package drivers
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// ownedInfo is a FileInfo for an entry owned by uid.
+type ownedInfo struct{ uid uint32 }
+
+func (i ownedInfo) Name() string       { return "entry" }
+func (i ownedInfo) Size() int64        { return 0 }
+func (i ownedInfo) Mode() os.FileMode  { return 0o600 }
+func (i ownedInfo) ModTime() time.Time { return time.Time{} }
+func (i ownedInfo) IsDir() bool        { return false }
+func (i ownedInfo) Sys() any           { return &syscall.Stat_t{Uid: i.uid} }
+
+func stubEUID(t *testing.T, uid int) {
+	t.Helper()
+	saved := geteuid
+	t.Cleanup(func() { geteuid = saved })
+	geteuid = func() int { return uid }
+}
+
+func TestSweepOwner(t *testing.T) {
+	tests := []struct {
+		euid    int
+		sudoUID string
+		owner   fileOwner
+		ok      bool
+	}{
+		{1000, "", 1000, true},
+		{1000, "1001", 1000, true},
+		// Root sweeps the files of the user who ran sudo
+		{0, "1000", 1000, true},
+		{0, "", 0, false},
+		{0, "0", 0, false},
+		{0, "alex", 0, false},
+	}
+	for _, tt := range tests {
+		stubEUID(t, tt.euid)
+		t.Setenv("SUDO_UID", tt.sudoUID)
+		owner, err := sweepOwner()
+		if owner != tt.owner || (err == nil) != tt.ok {
+			t.Errorf("euid %d, SUDO_UID %q: owner %d, err %v", tt.euid, tt.sudoUID, owner, err)
+		}
+	}
+}
+
+func TestFileOwnerOwns(t *testing.T) {
+	tests := []struct {
+		owner fileOwner
+		uid   uint32
+		owns  bool
+	}{
+		{1000, 1000, true},
+		{1000, 1001, false},
+		{1000, 0, false},
+		// Root's entries in /tmp belong to services, never to the sweep
+		{0, 0, false},
+	}
+	for _, tt := range tests {
+		if got := tt.owner.owns("/tmp/entry", ownedInfo{tt.uid}); got != tt.owns {
+			t.Errorf("owner %d owns uid %d entry = %v", tt.owner, tt.uid, got)
+		}
+	}
+}
+
+func TestExpandSweepProfileAsRoot(t *testing.T) {
+	stubEUID(t, 0)
+	t.Setenv("SUDO_UID", "")
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/systemd-private-x", nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := SweepProfile{Name: "Temporary files", Paths: []string{dir + "/*"}, OwnedOnly: true}
+	if plan, err := ExpandSweepProfile(p); err == nil || !strings.Contains(err.Error(), "root") {
+		t.Errorf("plan %q, err %v, want a refusal as root", plan, err)
+	}
+
+	// Under sudo only the invoking user's entries are planned, and the
+	// file above is not uid 4242's.
+	t.Setenv("SUDO_UID", "4242")
+	plan, err := ExpandSweepProfile(p)
+	if err != nil || len(plan) != 0 {
+		t.Errorf("plan %q, err %v, want nothing", plan, err)
+	}
+}
@@ -0,0 +1,66 @@
+//go:build windows
+
+package drivers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32                 = syscall.NewLazyDLL("advapi32.dll")
+	procGetNamedSecurityInfo = advapi32.NewProc("GetNamedSecurityInfoW")
+)
+
+// fileOwner is the user an OwnedOnly sweep profile matches entries of, as
+// a SID string.
+type fileOwner string
+
+// sweepOwner returns the user the app runs as.
+func sweepOwner() (fileOwner, error) {
+	token, err := syscall.OpenCurrentProcessToken()
+	if err != nil {
+		return "", fmt.Errorf("cannot identify the current user: %v", err)
+	}
+	defer token.Close()
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return "", fmt.Errorf("cannot identify the current user: %v", err)
+	}
+	sid, err := user.User.Sid.String()
+	if err != nil {
+		return "", fmt.Errorf("cannot identify the current user: %v", err)
+	}
+	return fileOwner(sid), nil
+}
+
+// owns reports whether the owner in path's security descriptor is o.
+// Entries created by an elevated process are usually owned by the
+// Administrators group and so do not match.
+func (o fileOwner) owns(path string, info os.FileInfo) bool {
+	const seFileObject, ownerSecurityInformation = 1, 1
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return false
+	}
+	var owner *syscall.SID
+	var descriptor uintptr
+	ret, _, _ := procGetNamedSecurityInfo.Call(
+		uintptr(unsafe.Pointer(name)), seFileObject, ownerSecurityInformation,
+		uintptr(unsafe.Pointer(&owner)), 0, 0, 0, uintptr(unsafe.Pointer(&descriptor)))
+	if ret != 0 {
+		return false
+	}
+	defer syscall.LocalFree(syscall.Handle(descriptor))
+	sid, err := owner.String()
+	return err == nil && sid == string(o)
+}
+
+// isDiskFull reports whether a write failed because the volume is full.
+func isDiskFull(err error) bool {
+	const errorHandleDiskFull, errorDiskFull = syscall.Errno(39), syscall.Errno(112)
+	return errors.Is(err, errorDiskFull) || errors.Is(err, errorHandleDiskFull)
+}
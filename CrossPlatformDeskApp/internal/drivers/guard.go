@@ -0,0 +1,49 @@
+package drivers
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	guardMu sync.RWMutex
+	guard   func(Drive) error
+)
+
+// SetTargetGuard installs the check every wipe in this package runs
+// before destroying anything: guard is given the drive holding the target
+// and returns why it may not be wiped. The app installs its device policy
+// here, so the policy holds whichever dialog or job starts the wipe. nil
+// allows every drive.
+func SetTargetGuard(g func(Drive) error) {
+	guardMu.Lock()
+	defer guardMu.Unlock()
+	guard = g
+}
+
+// checkTargets asks the guard about the drive holding each path, scanning
+// the drives once. A path on no drive that can be found is asked about as
+// an unidentified drive, which is neither removable nor USB.
+func checkTargets(paths ...string) error {
+	guardMu.RLock()
+	g := guard
+	guardMu.RUnlock()
+	if g == nil || len(paths) == 0 {
+		return nil
+	}
+
+	drives, err := GetDrives()
+	if err != nil {
+		return fmt.Errorf("cannot check the device policy: %v", err)
+	}
+	for _, path := range paths {
+		d, ok := driveForPath(drives, path)
+		if !ok {
+			d = Drive{Name: "the drive holding " + path}
+		}
+		if err := g(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package drivers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// guardHost scans the desktop fixture, where everything outside the media
+// mounts lives on the system NVMe drive, and installs a guard that refuses
+// drives that are not removable. It returns the devices of the drives the
+// guard was asked about.
+func guardHost(t *testing.T) *[]string {
+	t.Helper()
+	h := desktopHost(t)
+	saved := HostRoots
+	t.Cleanup(func() { HostRoots = saved })
+	HostRoots = h.roots
+
+	var asked []string
+	SetTargetGuard(func(d Drive) error {
+		asked = append(asked, d.Device)
+		if !d.IsRemovable {
+			return errors.New("only removable drives may be wiped: " + d.Name)
+		}
+		return nil
+	})
+	t.Cleanup(func() { SetTargetGuard(nil) })
+	return &asked
+}
+
+func TestTargetGuardRefusesFiles(t *testing.T) {
+	asked := guardHost(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "secret.txt")
+	if err := os.WriteFile(file, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().AddDate(0, 0, -30)
+	if err := os.Chtimes(file, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	wipes := map[string]func() error{
+		"ClearItem": func() error { return ClearItem(file) },
+		"PurgeItem": func() error { return PurgeItem(file) },
+		"WipeFreeSpace": func() error {
+			_, err := WipeFreeSpace(dir)
+			return err
+		},
+		"ApplyRetention": func() error {
+			_, err := ApplyRetention(RetentionRule{Name: "old", Path: dir, MaxAgeDays: 7}, time.Now())
+			return err
+		},
+		"PurgeSweepProfile": func() error {
+			_, err := PurgeSweepProfile(SweepProfile{Name: "test", Paths: []string{file}})
+			return err
+		},
+	}
+	for name, wipe := range wipes {
+		*asked = nil
+		err := wipe()
+		if err == nil || !strings.Contains(err.Error(), "only removable drives") {
+			t.Errorf("%s: err = %v, want the guard's refusal", name, err)
+		}
+		if len(*asked) == 0 || (*asked)[0] != "/dev/nvme0n1" {
+			t.Errorf("%s: guard asked about %q, want /dev/nvme0n1", name, *asked)
+		}
+		if _, err := os.Stat(file); err != nil {
+			t.Fatalf("%s: file is gone after a refused wipe: %v", name, err)
+		}
+	}
+}
+
+func TestTargetGuardRefusesDisk(t *testing.T) {
+	asked := guardHost(t)
+	runner := stubCommands(t)
+
+	sda := Drive{Name: "sda", Device: "/dev/sda"}
+	if err := RunTeardown(DiskWipePlan{Device: sda.Device, Teardown: []TeardownStep{{"Unmount /mnt", []string{"umount", "/mnt"}}}}); err == nil {
+		t.Error("RunTeardown of the fixed disk succeeded")
+	}
+	if len(runner.ran) != 0 {
+		t.Errorf("refused teardown ran %q", runner.ran)
+	}
+	if _, err := WipeDisk(sda, DiskWipeOptions{}); err == nil || !strings.Contains(err.Error(), "only removable drives") {
+		t.Errorf("WipeDisk(sda) err = %v, want the guard's refusal", err)
+	}
+	if want := []string{"/dev/sda", "/dev/sda"}; strings.Join(*asked, ",") != strings.Join(want, ",") {
+		t.Errorf("guard asked about %q, want %q", *asked, want)
+	}
+}
+
+func TestTargetGuardUnknownPath(t *testing.T) {
+	var got Drive
+	SetTargetGuard(func(d Drive) error {
+		got = d
+		return nil
+	})
+	t.Cleanup(func() { SetTargetGuard(nil) })
+	saved := HostRoots
+	t.Cleanup(func() { HostRoots = saved })
+	HostRoots = newFakeHost(t, "desktop.txt").roots
+
+	if err := checkTargets("/dev/sdz"); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "the drive holding /dev/sdz" || got.IsRemovable {
+		t.Errorf("guard got %+v, want an unidentified fixed drive", got)
+	}
+}
+
+func TestTargetGuardUnset(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(file, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := PurgeItem(file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("file still present after purge: %v", err)
+	}
+}
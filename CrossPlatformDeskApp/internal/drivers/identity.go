@@ -0,0 +1,74 @@
+package drivers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Fingerprint derives a stable identifier for a physical drive from its
+// WWN, serial number, model and size, so certificates can be tied back to
+// the same disk whenever and wherever it is connected again. Kernel names,
+// mount points and volume labels change between inserts and are not used.
+// Drives reporting neither WWN nor serial cannot be told apart from others
+// of the same model and get no fingerprint.
+func Fingerprint(d Drive) string {
+	wwn := normalizeWWN(d.WWN)
+	serial := strings.ToUpper(strings.TrimSpace(d.Serial))
+	if wwn == "" && serial == "" {
+		return ""
+	}
+	model := strings.ToUpper(strings.Join(strings.Fields(d.Model), " "))
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%d", wwn, serial, model, d.Size)))
+	h := hex.EncodeToString(sum[:8])
+	return h[0:4] + "-" + h[4:8] + "-" + h[8:12] + "-" + h[12:16]
+}
+
+// normalizeWWN reduces the spellings sysfs and udev use for the same name
+// ("naa.5000c500...", "0x5000c500...", "eui.0025...") to bare lowercase hex.
+func normalizeWWN(wwn string) string {
+	wwn = strings.ToLower(strings.TrimSpace(wwn))
+	for _, prefix := range []string{"naa.", "eui.", "nguid.", "0x"} {
+		wwn = strings.TrimPrefix(wwn, prefix)
+	}
+	return wwn
+}
+
+// DriveForPath finds the drive that holds path: the drive or partition
+// whose device node it is, or the drive with the longest mount point
+// containing it.
+func DriveForPath(path string) (Drive, bool) {
+	drives, err := GetDrives()
+	if err != nil {
+		return Drive{}, false
+	}
+	return driveForPath(drives, path)
+}
+
+func driveForPath(drives []Drive, path string) (Drive, bool) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	var best Drive
+	bestLen := -1
+	for _, d := range drives {
+		if d.Device == path {
+			return d, true
+		}
+		for _, p := range d.Partitions {
+			if p.Device == path {
+				return d, true
+			}
+		}
+		for _, m := range d.MountPoints {
+			inside := path == m || m == "/" || isWithin(path, m)
+			if inside && len(m) > bestLen {
+				best, bestLen = d, len(m)
+			}
+		}
+	}
+	return best, bestLen >= 0
+}
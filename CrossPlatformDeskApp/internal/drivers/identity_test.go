@@ -0,0 +1,88 @@
+package drivers
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	disk := Drive{Device: "/dev/sdb", Model: "WDC WD40EFPX-68C", Serial: "WD-WX12D3456789", WWN: "0x50014ee2b5a1c3d4", Size: 4000787030016}
+	fp := Fingerprint(disk)
+	if len(fp) != 19 || fp[4] != '-' || fp[9] != '-' || fp[14] != '-' {
+		t.Fatalf("Fingerprint = %q", fp)
+	}
+
+	// What changes between inserts does not change the fingerprint
+	moved := disk
+	moved.Device, moved.Path, moved.Name = "/dev/sdf", "/media/alex/NAS", "NAS"
+	moved.MountPoints = []string{"/media/alex/NAS"}
+	moved.WWN = "naa.50014EE2B5A1C3D4"
+	moved.Serial = " wd-wx12d3456789 "
+	moved.Model = "WDC  WD40EFPX-68C "
+	if got := Fingerprint(moved); got != fp {
+		t.Errorf("same disk reconnected: %q, want %q", got, fp)
+	}
+
+	other := disk
+	other.Serial = "WD-WX12D3456790"
+	if Fingerprint(other) == fp {
+		t.Error("another serial gives the same fingerprint")
+	}
+	resized := disk
+	resized.Size--
+	if Fingerprint(resized) == fp {
+		t.Error("another size gives the same fingerprint")
+	}
+
+	if fp := Fingerprint(Drive{Model: "Generic Flash Disk", Size: 15728640000}); fp != "" {
+		t.Errorf("drive without serial or WWN got %q", fp)
+	}
+	if Fingerprint(Drive{WWN: "eui.0025385b71b0a123"}) == "" {
+		t.Error("WWN alone gave no fingerprint")
+	}
+}
+
+func TestNormalizeWWN(t *testing.T) {
+	tests := map[string]string{
+		"0x5000c500a1b2c3d4":       "5000c500a1b2c3d4",
+		"naa.5000C500A1B2C3D4":     "5000c500a1b2c3d4",
+		" eui.0025385B71B0A123\n":  "0025385b71b0a123",
+		"nguid.0025385b71b0a12300": "0025385b71b0a12300",
+		"":                         "",
+	}
+	for in, want := range tests {
+		if got := normalizeWWN(in); got != want {
+			t.Errorf("normalizeWWN(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDriveForPath(t *testing.T) {
+	drives := []Drive{
+		{Device: "/dev/nvme0n1", MountPoints: []string{"/", "/boot/efi"}, Partitions: []Partition{{Device: "/dev/nvme0n1p1"}, {Device: "/dev/nvme0n1p2"}}},
+		{Device: "/dev/sda", MountPoints: []string{"/home"}},
+		{Device: "/dev/sdc", MountPoints: []string{"/media/alex/My Stick"}, Partitions: []Partition{{Device: "/dev/sdc1"}}},
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/dev/sdc", "/dev/sdc"},
+		{"/dev/sdc1", "/dev/sdc"},
+		{"/dev/nvme0n1p2", "/dev/nvme0n1"},
+		{"/media/alex/My Stick/photos/a.jpg", "/dev/sdc"},
+		{"/media/alex/My Stick", "/dev/sdc"},
+		{"/media/alex/My Sticker", "/dev/nvme0n1"},
+		{"/home/alex/.cache", "/dev/sda"},
+		{"/homework", "/dev/nvme0n1"},
+		{"/boot/efi/EFI", "/dev/nvme0n1"},
+		{"/media/alex/../alex/My Stick/x", "/dev/sdc"},
+	}
+	for _, tt := range tests {
+		d, ok := driveForPath(drives, tt.path)
+		if !ok || d.Device != tt.want {
+			t.Errorf("driveForPath(%q) = %q, %v, want %q", tt.path, d.Device, ok, tt.want)
+		}
+	}
+
+	if d, ok := driveForPath(drives[1:], "/etc/fstab"); ok {
+		t.Errorf("path on no listed drive matched %q", d.Device)
+	}
+}
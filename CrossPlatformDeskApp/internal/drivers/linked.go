@@ -0,0 +1,266 @@
+package drivers
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PurgeOptions tunes PurgeItemWithOptions.
+type PurgeOptions struct {
+	// LinkedArtifacts also purges what the desktop derived from every
+	// destroyed file: freedesktop thumbnails, its recently-used.xbel entry
+	// and editor backup/swap companions next to it.
+	LinkedArtifacts bool
+}
+
+// PurgeItemWithOptions purges path like PurgeItem and, when asked, the
+// artifacts linked to each file it destroyed. Every extra item is listed in
+// the report's Linked field.
+func PurgeItemWithOptions(path string, opts PurgeOptions) (PurgeReport, error) {
+	var report PurgeReport
+
+	// The file list has to be taken before the purge destroys it.
+	var files []string
+	if opts.LinkedArtifacts {
+		files = collectFiles(path)
+	}
+
+	if err := PurgeItemTraced(path, &report.Trace); err != nil {
+		report.Failures = append(report.Failures, PurgeFailure{Path: path, Err: err})
+		return report, err
+	}
+	report.Purged = append(report.Purged, path)
+
+	if !opts.LinkedArtifacts {
+		return report, nil
+	}
+
+	var uris []string
+	for _, f := range files {
+		purgeCompanions(f, &report)
+		uri := fileURI(f)
+		purgeThumbnails(uri, &report)
+		uris = append(uris, uri)
+	}
+	removeRecentEntries(uris, &report)
+
+	for _, l := range report.Linked {
+		fmt.Printf("Linked artifact purged: %s\n", l)
+	}
+	if len(report.Failures) > 0 {
+		return report, fmt.Errorf("%d linked artifacts of %s could not be purged", len(report.Failures), path)
+	}
+	return report, nil
+}
+
+// collectFiles lists the absolute paths of the regular files at or below path.
+func collectFiles(path string) []string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	filepath.Walk(abs, func(p string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files
+}
+
+// purgeCompanions purges editor leftovers sitting next to a file: emacs and
+// vim backups (file~), vim swap files (.file.swp, .file.swo) and emacs
+// auto-save files (#file#).
+func purgeCompanions(file string, report *PurgeReport) {
+	dir, base := filepath.Split(file)
+	candidates := []string{
+		base + "~",
+		"." + base + ".swp",
+		"." + base + ".swo",
+		"#" + base + "#",
+	}
+
+	for _, c := range candidates {
+		companion := filepath.Join(dir, c)
+		info, err := os.Lstat(companion)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		purgeLinked(companion, report)
+	}
+}
+
+// purgeThumbnails purges every cached thumbnail of uri. Per the freedesktop
+// thumbnail spec they are named after the MD5 of the file's URI, in one
+// directory per size plus per-application failure directories.
+func purgeThumbnails(uri string, report *PurgeReport) {
+	sum := md5.Sum([]byte(uri))
+	name := hex.EncodeToString(sum[:]) + ".png"
+
+	var patterns []string
+	for _, root := range thumbnailRoots() {
+		patterns = append(patterns,
+			filepath.Join(root, "*", name),
+			filepath.Join(root, "fail", "*", name),
+		)
+	}
+
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, m := range matches {
+			purgeLinked(m, report)
+		}
+	}
+}
+
+// thumbnailRoots returns the current and legacy thumbnail cache locations.
+func thumbnailRoots() []string {
+	var roots []string
+	if cache, err := expandSweepPath("$XDG_CACHE_HOME/thumbnails"); err == nil {
+		roots = append(roots, cache)
+	}
+	if legacy, err := expandSweepPath("~/.thumbnails"); err == nil {
+		roots = append(roots, legacy)
+	}
+	return roots
+}
+
+// removeRecentEntries drops the bookmarks for uris from recently-used.xbel.
+// The rewritten list is written beside the original and renamed over it;
+// only then is the original purged, through a hard link taken beforehand,
+// so the old paths do not linger on disk and a failure never loses the list.
+func removeRecentEntries(uris []string, report *PurgeReport) {
+	if len(uris) == 0 {
+		return
+	}
+
+	xbel, err := expandSweepPath("$XDG_DATA_HOME/recently-used.xbel")
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(xbel)
+	if err != nil {
+		return
+	}
+
+	content := string(data)
+	var removed []string
+	for _, uri := range uris {
+		var ok bool
+		content, ok = removeBookmark(content, uri)
+		if ok {
+			removed = append(removed, uri)
+		}
+	}
+	if len(removed) == 0 {
+		return
+	}
+
+	info, err := os.Stat(xbel)
+	if err != nil {
+		return
+	}
+	tmp := xbel + ".nullbyters"
+	if err := os.WriteFile(tmp, []byte(content), info.Mode().Perm()); err != nil {
+		report.fail(xbel, err)
+		return
+	}
+	// Keep a second name for the old list so it can still be purged once
+	// the new one has replaced it; until the rename the original is intact.
+	old := xbel + ".nullbyters-old"
+	if err := os.Link(xbel, old); err != nil {
+		os.Remove(tmp)
+		report.fail(xbel, err)
+		return
+	}
+	if err := os.Rename(tmp, xbel); err != nil {
+		os.Remove(tmp)
+		os.Remove(old)
+		report.fail(xbel, err)
+		return
+	}
+	if err := purgeFile(old, &report.Trace); err != nil {
+		report.fail(xbel, err)
+		return
+	}
+
+	for _, uri := range removed {
+		report.Linked = append(report.Linked, fmt.Sprintf("%s (entry %s)", xbel, uri))
+	}
+}
+
+// removeBookmark cuts the <bookmark> element for uri, including its line,
+// out of an xbel document.
+func removeBookmark(content, uri string) (string, bool) {
+	start := strings.Index(content, `<bookmark href="`+xmlEscape(uri)+`"`)
+	if start < 0 {
+		return content, false
+	}
+
+	tagEnd := strings.Index(content[start:], ">")
+	if tagEnd < 0 {
+		return content, false
+	}
+	end := start + tagEnd + 1
+	if content[end-2] != '/' {
+		closeTag := strings.Index(content[end:], "</bookmark>")
+		if closeTag < 0 {
+			return content, false
+		}
+		end += closeTag + len("</bookmark>")
+	}
+
+	// Take the indentation before the element and the newline after it.
+	lineStart := strings.LastIndex(content[:start], "\n") + 1
+	if strings.TrimSpace(content[lineStart:start]) == "" {
+		start = lineStart
+	}
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+
+	return content[:start] + content[end:], true
+}
+
+// purgeLinked purges a single linked artifact and records the outcome.
+func purgeLinked(path string, report *PurgeReport) {
+	if err := purgeFile(path, &report.Trace); err != nil {
+		report.fail(path, err)
+		return
+	}
+	report.Linked = append(report.Linked, path)
+}
+
+// fileURI builds the file:// URI of an absolute path the way GLib's
+// g_filename_to_uri does, since thumbnail names and xbel entries are keyed on
+// exactly that spelling.
+func fileURI(path string) string {
+	const allowed = "-_.!~*'()/:@&=+$,"
+	const hexDigits = "0123456789ABCDEF"
+
+	var b strings.Builder
+	b.WriteString("file://")
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || strings.IndexByte(allowed, c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&0x0f])
+	}
+	return b.String()
+}
+
+// xmlEscape escapes s like g_markup_escape_text, which writes the xbel hrefs.
+func xmlEscape(s string) string {
+	r := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&#39;")
+	return r.Replace(s)
+}
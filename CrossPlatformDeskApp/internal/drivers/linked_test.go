@@ -0,0 +1,141 @@
+package drivers
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFileURI(t *testing.T) {
+	for path, want := range map[string]string{
+		"/home/alex/notes.txt":         "file:///home/alex/notes.txt",
+		"/home/alex/My Files/a b.txt":  "file:///home/alex/My%20Files/a%20b.txt",
+		"/tmp/50%/#1?;x":               "file:///tmp/50%25/%231%3F%3Bx",
+		"/tmp/a&b=c+d,$e@f:g!~*'()-_.": "file:///tmp/a&b=c+d,$e@f:g!~*'()-_.",
+		"/tmp/<x>\"y\"[z]{w}|\\^`":     "file:///tmp/%3Cx%3E%22y%22%5Bz%5D%7Bw%7D%7C%5C%5E%60",
+		"/tmp/résumé.pdf":              "file:///tmp/r%C3%A9sum%C3%A9.pdf",
+	} {
+		if got := fileURI(path); got != want {
+			t.Errorf("fileURI(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+const testXBEL = `<?xml version="1.0" encoding="UTF-8"?>
+<xbel version="1.0">
+  <bookmark href="file:///tmp/a.txt" added="2026-01-01T10:00:00Z" modified="2026-01-01T10:00:00Z" visited="2026-01-01T10:00:00Z">
+    <info>
+      <metadata owner="http://freedesktop.org">
+        <mime:mime-type type="text/plain"/>
+        <bookmark:applications>
+          <bookmark:application name="gedit" exec="&apos;gedit %u&apos;" modified="2026-01-01T10:00:00Z" count="1"/>
+        </bookmark:applications>
+      </metadata>
+    </info>
+  </bookmark>
+  <bookmark href="file:///tmp/Tom%20&amp;%20Jerry%27s.txt" added="2026-01-02T10:00:00Z"/>
+  <bookmark href="file:///tmp/a.txt.bak" added="2026-01-03T10:00:00Z"></bookmark>
+</xbel>
+`
+
+func TestRemoveBookmark(t *testing.T) {
+	cases := []struct {
+		name, uri string
+		gone      string
+		ok        bool
+	}{
+		{"multi-line", "file:///tmp/a.txt", `"file:///tmp/a.txt"`, true},
+		{"self-closing with reserved characters", "file:///tmp/Tom%20&%20Jerry%27s.txt", "Tom%20", true},
+		{"prefix of another entry", "file:///tmp/a.txt.ba", "", false},
+		{"empty element", "file:///tmp/a.txt.bak", "a.txt.bak", true},
+		{"absent", "file:///tmp/missing", "", false},
+	}
+	for _, c := range cases {
+		got, ok := removeBookmark(testXBEL, c.uri)
+		if ok != c.ok {
+			t.Errorf("%s: removed = %v, want %v", c.name, ok, c.ok)
+			continue
+		}
+		if !ok {
+			if got != testXBEL {
+				t.Errorf("%s: document changed though nothing was removed", c.name)
+			}
+			continue
+		}
+		if strings.Contains(got, c.gone) {
+			t.Errorf("%s: %s still in\n%s", c.name, c.gone, got)
+		}
+		// Exactly one bookmark and its lines are gone
+		if n := strings.Count(got, "<bookmark href="); n != 2 {
+			t.Errorf("%s: %d bookmarks left, want 2:\n%s", c.name, n, got)
+		}
+		if strings.Contains(got, "\n\n") || !strings.HasSuffix(got, "</xbel>\n") || strings.Count(got, "</bookmark>") != strings.Count(got, "</bookmark>\n") {
+			t.Errorf("%s: left a broken layout:\n%s", c.name, got)
+		}
+	}
+
+	// Entries are removed one after another from the same document
+	doc := testXBEL
+	for _, uri := range []string{"file:///tmp/a.txt", "file:///tmp/Tom%20&%20Jerry%27s.txt", "file:///tmp/a.txt.bak"} {
+		var ok bool
+		if doc, ok = removeBookmark(doc, uri); !ok {
+			t.Fatalf("%s not removed", uri)
+		}
+	}
+	if want := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<xbel version=\"1.0\">\n</xbel>\n"; doc != want {
+		t.Errorf("after removing every entry:\n%s", doc)
+	}
+}
+
+func TestRemoveRecentEntries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("recently-used.xbel is a freedesktop file")
+	}
+	stubCommands(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	dir := filepath.Join(home, ".local", "share")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	xbel := filepath.Join(dir, "recently-used.xbel")
+	if err := os.WriteFile(xbel, []byte(testXBEL), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var report PurgeReport
+	removeRecentEntries([]string{"file:///tmp/a.txt", "file:///tmp/missing"}, &report)
+	if len(report.Failures) != 0 || len(report.Linked) != 1 {
+		t.Fatalf("report %+v", report)
+	}
+	data, err := os.ReadFile(xbel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"file:///tmp/a.txt"`) || !strings.Contains(string(data), "a.txt.bak") {
+		t.Errorf("rewritten list:\n%s", data)
+	}
+	if info, _ := os.Stat(xbel); info.Mode().Perm() != 0o600 {
+		t.Errorf("mode %v, want the original's", info.Mode().Perm())
+	}
+	left, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(left) != 1 {
+		t.Errorf("left behind %q", left)
+	}
+
+	// When the rewrite cannot be put in place the original stays
+	if err := os.Mkdir(xbel+".nullbyters", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	report = PurgeReport{}
+	removeRecentEntries([]string{"file:///tmp/a.txt.bak"}, &report)
+	if len(report.Failures) != 1 {
+		t.Errorf("failures %+v", report.Failures)
+	}
+	if after, _ := os.ReadFile(xbel); string(after) != string(data) {
+		t.Errorf("list changed by a failed rewrite:\n%s", after)
+	}
+}
@@ -0,0 +1,172 @@
+package drivers
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TeardownStep is one command that releases part of what is stacked on a
+// disk.
+type TeardownStep struct {
+	Description string
+	Command     []string
+}
+
+// DiskWipePlan is what wiping a disk involves once everything stacked on it
+// is taken into account.
+type DiskWipePlan struct {
+	Device string
+	// Teardown unmounts, closes and stops everything built on the disk,
+	// topmost first, so the disk can be opened exclusively.
+	Teardown []TeardownStep
+	// Warnings describe the effects beyond the disk itself, such as arrays
+	// and volume groups that span other disks.
+	Warnings []string
+	// Blocked is why the disk must not be wiped, or nil.
+	Blocked error
+}
+
+// PlanDiskWipe works out how to take d out of use for a whole-disk wipe,
+// refusing when anything stacked on it is mounted outside the removable
+// media directories or is active swap.
+func PlanDiskWipe(d Drive) DiskWipePlan {
+	if err := CheckDiskWipe(d); err != nil {
+		return DiskWipePlan{Device: d.Device, Blocked: err}
+	}
+	topology, err := ScanTopology(HostRoots)
+	if err != nil {
+		return DiskWipePlan{Device: d.Device, Blocked: err}
+	}
+	return planDiskWipe(d, topology)
+}
+
+func planDiskWipe(d Drive, topology *Topology) DiskWipePlan {
+	plan := DiskWipePlan{Device: d.Device}
+	name := filepath.Base(d.Device)
+	disk := topology.Nodes[name]
+	if disk == nil {
+		plan.Blocked = fmt.Errorf("%s is no longer present", d.Device)
+		return plan
+	}
+
+	stack := append([]*TopologyNode{disk}, topology.Above(name)...)
+	for _, n := range stack {
+		for _, m := range n.MountPoints {
+			if err := checkMount(d.Device, m, n.Device); err != nil {
+				plan.Blocked = err
+				return plan
+			}
+		}
+	}
+	// Swap is never turned off for a wipe: the system may need it
+	for _, n := range stack {
+		if n.Swap {
+			plan.Blocked = fmt.Errorf("%s is in use: %s is active swap; run swapoff yourself before wiping the disk", d.Device, n.Device)
+			return plan
+		}
+	}
+
+	// Depth-first, so a device is released only after everything on it.
+	released := make(map[string]bool)
+	var release func(n *TopologyNode)
+	release = func(n *TopologyNode) {
+		if released[n.Name] {
+			return
+		}
+		released[n.Name] = true
+		for _, above := range append(append([]string(nil), n.Partitions...), n.Holders...) {
+			if a := topology.Nodes[above]; a != nil {
+				release(a)
+			}
+		}
+		plan.Teardown = append(plan.Teardown, teardownSteps(n)...)
+	}
+	release(disk)
+
+	for _, n := range stack {
+		if n.FileSystem == "crypto_LUKS" {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s is LUKS-encrypted; its header and key slots are destroyed along with the data", n.Device))
+		}
+		if n.Kind == NodeDisk || n.Kind == NodePartition {
+			continue
+		}
+		var others []string
+		for _, other := range topology.Disks(n.Name) {
+			if other != name {
+				others = append(others, other)
+			}
+		}
+		if len(others) > 0 {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s also spans %s; %s", describeNode(n), strings.Join(others, ", "), spanEffect(n)))
+		}
+	}
+	return plan
+}
+
+// teardownSteps releases n itself once nothing is stacked on it any more.
+func teardownSteps(n *TopologyNode) []TeardownStep {
+	var steps []TeardownStep
+	points := append([]string(nil), n.MountPoints...)
+	sort.Sort(sort.Reverse(sort.StringSlice(points)))
+	for _, p := range points {
+		steps = append(steps, TeardownStep{"Unmount " + p, []string{"umount", p}})
+	}
+
+	mapping := firstNonEmpty(n.Label, n.Device)
+	switch n.Kind {
+	case NodeCrypt:
+		steps = append(steps, TeardownStep{"Close encrypted volume " + mapping, []string{"cryptsetup", "close", mapping}})
+	case NodeLVM:
+		steps = append(steps, TeardownStep{"Deactivate logical volume " + mapping, []string{"lvchange", "-an", "/dev/mapper/" + mapping}})
+	case NodeDM:
+		steps = append(steps, TeardownStep{"Remove device-mapper target " + mapping, []string{"dmsetup", "remove", mapping}})
+	case NodeRAID:
+		steps = append(steps, TeardownStep{"Stop RAID array " + n.Device, []string{"mdadm", "--stop", n.Device}})
+	case NodeLoop:
+		steps = append(steps, TeardownStep{"Detach loop device " + n.Device, []string{"losetup", "-d", n.Device}})
+	}
+	return steps
+}
+
+func describeNode(n *TopologyNode) string {
+	switch n.Kind {
+	case NodeCrypt:
+		return fmt.Sprintf("encrypted volume %s", n.Label)
+	case NodeLVM:
+		return fmt.Sprintf("logical volume %s", n.Label)
+	case NodeRAID:
+		return fmt.Sprintf("%s array %s", n.Label, n.Device)
+	case NodeLoop:
+		return fmt.Sprintf("loop device %s (%s)", n.Device, n.BackingFile)
+	}
+	return n.Device
+}
+
+func spanEffect(n *TopologyNode) string {
+	switch n.Kind {
+	case NodeRAID:
+		return "the array is stopped and will be degraded or lost without this disk"
+	case NodeLVM:
+		return "the volume is deactivated and loses the extents on this disk"
+	}
+	return "it is taken down and will be incomplete without this disk"
+}
+
+// RunTeardown carries out plan's teardown steps in order.
+func RunTeardown(plan DiskWipePlan) error {
+	if plan.Blocked != nil {
+		return plan.Blocked
+	}
+	if err := checkTargets(plan.Device); err != nil {
+		return err
+	}
+	for _, s := range plan.Teardown {
+		if out, err := Commands.CombinedOutput(s.Command[0], s.Command[1:]...); err != nil {
+			return fmt.Errorf("%s failed: %v: %s", s.Description, err, strings.TrimSpace(string(out)))
+		}
+		fmt.Println(s.Description)
+	}
+	return nil
+}
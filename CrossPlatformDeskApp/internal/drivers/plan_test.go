@@ -0,0 +1,156 @@
+package drivers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func planFor(t *testing.T, h *fakeHost, device string) DiskWipePlan {
+	t.Helper()
+	topology, err := ScanTopology(h.roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return planDiskWipe(Drive{Device: device}, topology)
+}
+
+func teardownCommands(plan DiskWipePlan) [][]string {
+	var commands [][]string
+	for _, s := range plan.Teardown {
+		commands = append(commands, s.Command)
+	}
+	return commands
+}
+
+func TestPlanDiskWipeRefusesSystemStack(t *testing.T) {
+	// The root filesystem is two layers above sda2, on LVM inside LUKS.
+	plan := planFor(t, luksLVMHost(t), "/dev/sda")
+	if plan.Blocked == nil || !strings.Contains(plan.Blocked.Error(), "running system") {
+		t.Fatalf("Blocked = %v", plan.Blocked)
+	}
+	if plan.Teardown != nil {
+		t.Errorf("blocked plan still tears down %v", teardownCommands(plan))
+	}
+
+	plan = planFor(t, desktopHost(t), "/dev/nvme0n1")
+	if plan.Blocked == nil {
+		t.Fatal("the boot disk is not blocked")
+	}
+}
+
+// remount moves a mount point in h's mount table.
+func remount(t *testing.T, h *fakeHost, from, to string) {
+	t.Helper()
+	path := filepath.Join(h.roots.Proc, "self", "mountinfo")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.write(path, strings.Replace(string(data), " "+from+" ", " "+to+" ", 1))
+}
+
+func TestPlanDiskWipeTearsDownRAIDMember(t *testing.T) {
+	h := luksLVMHost(t)
+	remount(t, h, "/srv/data", "/mnt/data")
+	plan := planFor(t, h, "/dev/sdb")
+	if plan.Blocked != nil {
+		t.Fatal(plan.Blocked)
+	}
+	want := [][]string{
+		{"umount", "/mnt/data"},
+		{"lvchange", "-an", "/dev/mapper/data-vol"},
+		{"mdadm", "--stop", "/dev/md0"},
+	}
+	if got := teardownCommands(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("teardown = %q, want %q", got, want)
+	}
+	if len(plan.Warnings) != 2 {
+		t.Fatalf("warnings = %q", plan.Warnings)
+	}
+	if !strings.Contains(plan.Warnings[0], "raid1 array /dev/md0 also spans sdc") {
+		t.Errorf("array warning = %q", plan.Warnings[0])
+	}
+	if !strings.Contains(plan.Warnings[1], "logical volume data-vol also spans sdc") {
+		t.Errorf("volume warning = %q", plan.Warnings[1])
+	}
+}
+
+func TestPlanDiskWipeRefusesDiskInUse(t *testing.T) {
+	// The array's volume is mounted on /srv/data, which no one unmounts
+	// by accident.
+	plan := planFor(t, luksLVMHost(t), "/dev/sdb")
+	if plan.Blocked == nil || !strings.Contains(plan.Blocked.Error(), "/srv/data is mounted from /dev/dm-3") {
+		t.Fatalf("Blocked = %v", plan.Blocked)
+	}
+	if plan.Teardown != nil {
+		t.Errorf("blocked plan still tears down %v", teardownCommands(plan))
+	}
+
+	// Swap is left for the user to turn off.
+	plan = planFor(t, desktopHost(t), "/dev/sda")
+	if plan.Blocked == nil || !strings.Contains(plan.Blocked.Error(), "/dev/sda2 is active swap") {
+		t.Fatalf("Blocked = %v", plan.Blocked)
+	}
+	if plan.Teardown != nil {
+		t.Errorf("blocked plan still tears down %v", teardownCommands(plan))
+	}
+}
+
+func TestPlanDiskWipeUnmountsStick(t *testing.T) {
+	plan := planFor(t, desktopHost(t), "/dev/sdc")
+	want := [][]string{{"umount", "/media/alex/My Stick"}}
+	if got := teardownCommands(plan); plan.Blocked != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("teardown = %q (blocked: %v), want %q", got, plan.Blocked, want)
+	}
+
+	plan = planFor(t, desktopHost(t), "/dev/sdx")
+	if plan.Blocked == nil || !strings.Contains(plan.Blocked.Error(), "no longer present") {
+		t.Errorf("Blocked = %v for a missing disk", plan.Blocked)
+	}
+}
+
+func TestRunTeardown(t *testing.T) {
+	runner := stubCommands(t)
+	runner.outputs["umount /media/alex/My Stick"] = fakeOutput{}
+	plan := DiskWipePlan{Device: "/dev/sdc", Teardown: []TeardownStep{
+		{"Unmount /media/alex/My Stick", []string{"umount", "/media/alex/My Stick"}},
+		{"Close encrypted volume backup", []string{"cryptsetup", "close", "backup"}},
+	}}
+
+	// cryptsetup has no recording, so it fails as if not installed.
+	err := RunTeardown(plan)
+	if err == nil || !strings.Contains(err.Error(), "Close encrypted volume backup failed") {
+		t.Fatalf("err = %v", err)
+	}
+	if want := []string{"umount /media/alex/My Stick", "cryptsetup close backup"}; !reflect.DeepEqual(runner.ran, want) {
+		t.Errorf("ran %q, want %q", runner.ran, want)
+	}
+}
+
+func TestCheckDiskWipe(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("whole-disk wipes are Linux only")
+	}
+	tests := []struct {
+		drive Drive
+		want  string
+	}{
+		{Drive{Device: "/dev/sdc", MountPoints: []string{"/media/alex/My Stick"}}, ""},
+		{Drive{Device: "E:"}, "not a block device"},
+		{Drive{Device: "/dev/sdc", ReadOnly: true}, "read-only"},
+		{Drive{Device: "/dev/nvme0n1", MountPoints: []string{"/boot/efi"}}, "running system"},
+	}
+	for _, tt := range tests {
+		err := CheckDiskWipe(tt.drive)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%+v: unexpected error %v", tt.drive, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%+v: err = %v, want %q", tt.drive, err, tt.want)
+		}
+	}
+}
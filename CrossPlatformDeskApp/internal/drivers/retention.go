@@ -0,0 +1,149 @@
+package drivers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Wipe schemes a retention rule can apply to expired files.
+const (
+	SchemeClear = "clear"
+	SchemePurge = "purge"
+)
+
+// RetentionRule keeps a watched folder free of files older than MaxAgeDays.
+type RetentionRule struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Glob is matched against file names below Path; empty matches all.
+	Glob       string `json:"glob,omitempty"`
+	MaxAgeDays int    `json:"max_age_days"`
+	// AgeBy selects the timestamp the age is measured from: "mtime"
+	// (default) or "ctime", the inode change time, which a user cannot
+	// backdate with touch.
+	AgeBy string `json:"age_by,omitempty"`
+	// Scheme is SchemePurge (default) or SchemeClear.
+	Scheme string `json:"scheme,omitempty"`
+	// Schedule is the cron expression the rule is evaluated on; "@hourly"
+	// when empty.
+	Schedule string `json:"schedule,omitempty"`
+}
+
+// Validate checks a rule before it is evaluated.
+func (r RetentionRule) Validate() error {
+	if r.Path == "" {
+		return errors.New("retention rule needs a path")
+	}
+	if r.MaxAgeDays <= 0 {
+		return fmt.Errorf("retention rule %s: max_age_days must be positive", r.Name)
+	}
+	if r.Glob != "" {
+		if _, err := filepath.Match(r.Glob, ""); err != nil {
+			return fmt.Errorf("retention rule %s: bad glob %q: %v", r.Name, r.Glob, err)
+		}
+	}
+	switch r.AgeBy {
+	case "", "mtime", "ctime":
+	default:
+		return fmt.Errorf("retention rule %s: age_by must be mtime or ctime", r.Name)
+	}
+	switch r.Scheme {
+	case "", SchemePurge, SchemeClear:
+	default:
+		return fmt.Errorf("retention rule %s: scheme must be purge or clear", r.Name)
+	}
+	return nil
+}
+
+// ExpiredFiles lists the files the rule would remove if it ran at now,
+// oldest first. It never touches the files, so it doubles as the dry run.
+func ExpiredFiles(r RetentionRule, now time.Time) ([]string, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	if isCriticalPath(r.Path) {
+		return nil, fmt.Errorf("cannot apply retention to critical system path: %s", r.Path)
+	}
+
+	cutoff := now.AddDate(0, 0, -r.MaxAgeDays)
+	type expired struct {
+		path string
+		age  time.Time
+	}
+	var found []expired
+
+	err := filepath.Walk(r.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// An unreadable subfolder should not hide the rest of the tree.
+			if path != r.Path {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if r.Glob != "" {
+			if ok, _ := filepath.Match(r.Glob, info.Name()); !ok {
+				return nil
+			}
+		}
+
+		stamp := info.ModTime()
+		if r.AgeBy == "ctime" {
+			stamp = changeTime(info)
+		}
+		if stamp.Before(cutoff) {
+			found = append(found, expired{path, stamp})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retention rule %s: %v", r.Name, err)
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].age.Before(found[j].age) })
+	files := make([]string, len(found))
+	for i, f := range found {
+		files[i] = f.path
+	}
+	return files, nil
+}
+
+// ApplyRetention removes every expired file in one batch using the rule's
+// scheme. Files that fail are reported but do not stop the batch.
+func ApplyRetention(r RetentionRule, now time.Time) (PurgeReport, error) {
+	var report PurgeReport
+
+	files, err := ExpiredFiles(r, now)
+	if err == nil {
+		err = checkTargets(append([]string{r.Path}, files...)...)
+	}
+	if err != nil {
+		report.Trace.fail(r.Path, err)
+		return report, err
+	}
+
+	for _, f := range files {
+		var err error
+		if r.Scheme == SchemeClear {
+			err = clearChecked(f, &report.Trace)
+		} else {
+			err = purgeChecked(f, &report.Trace)
+		}
+		if err != nil {
+			report.Failures = append(report.Failures, PurgeFailure{Path: f, Err: err})
+			continue
+		}
+		report.Purged = append(report.Purged, f)
+	}
+
+	if len(report.Failures) > 0 {
+		return report, fmt.Errorf("%d of %d expired files in %s could not be removed", len(report.Failures), len(files), r.Path)
+	}
+	return report, nil
+}
@@ -0,0 +1,211 @@
+package drivers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+var retentionNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// retentionDir creates files in a temp directory, each last modified the
+// given time before retentionNow, and returns the directory.
+func retentionDir(t *testing.T, ages map[string]time.Duration) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, age := range ages {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		stamp := retentionNow.Add(-age)
+		if err := os.Chtimes(path, stamp, stamp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const day = 24 * time.Hour
+
+func TestExpiredFiles(t *testing.T) {
+	dir := retentionDir(t, map[string]time.Duration{
+		"a.log":     30 * day,
+		"edge.log":  7 * day,
+		"c.log":     7*day + time.Second,
+		"d.txt":     10 * day,
+		"sub/e.log": 20 * day,
+		"new.log":   time.Hour,
+	})
+	// A link is never followed out of the watched folder, however old
+	outside := retentionDir(t, map[string]time.Duration{"old.log": 90 * day})
+	if err := os.Symlink(filepath.Join(outside, "old.log"), filepath.Join(dir, "link.log")); err != nil {
+		t.Logf("no symlink: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		rule RetentionRule
+		want []string
+	}{
+		// Exactly seven days old is not yet older than seven days
+		{"cutoff", RetentionRule{MaxAgeDays: 7}, []string{"a.log", "sub/e.log", "d.txt", "c.log"}},
+		{"glob", RetentionRule{MaxAgeDays: 7, Glob: "*.log"}, []string{"a.log", "sub/e.log", "c.log"}},
+		{"longer age", RetentionRule{MaxAgeDays: 15}, []string{"a.log", "sub/e.log"}},
+		{"nothing old enough", RetentionRule{MaxAgeDays: 31}, []string{}},
+	}
+	for _, tt := range tests {
+		tt.rule.Name = tt.name
+		tt.rule.Path = dir
+		got, err := ExpiredFiles(tt.rule, retentionNow)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		want := make([]string, len(tt.want))
+		for i, name := range tt.want {
+			want[i] = filepath.Join(dir, name)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: ExpiredFiles = %q, want %q", tt.name, got, want)
+		}
+	}
+
+	for rule, want := range map[RetentionRule]string{
+		{Name: "r", Path: dir}:                                       "max_age_days",
+		{Name: "r", Path: dir, MaxAgeDays: 7, Glob: "["}:             "bad glob",
+		{Name: "r", Path: dir, MaxAgeDays: 7, AgeBy: "atime"}:        "age_by",
+		{Name: "r", Path: dir, MaxAgeDays: 7, Scheme: "burn"}:        "scheme",
+		{Name: "r", MaxAgeDays: 7}:                                   "needs a path",
+		{Name: "r", Path: filepath.Join(dir, "gone"), MaxAgeDays: 7}: "retention rule r",
+	} {
+		if _, err := ExpiredFiles(rule, retentionNow); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ExpiredFiles(%+v) = %v, want an error about %s", rule, err, want)
+		}
+	}
+}
+
+func TestExpiredFilesByChangeTime(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("no inode change time")
+	}
+	// Backdated with touch, but the inode changed just now
+	dir := retentionDir(t, map[string]time.Duration{"backdated.log": 365 * day})
+	path := filepath.Join(dir, "backdated.log")
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := changeTime(info)
+
+	rule := RetentionRule{Name: "ctime", Path: dir, MaxAgeDays: 7, AgeBy: "ctime"}
+	for now, want := range map[time.Time][]string{
+		changed.Add(-time.Hour):                   {},
+		changed.AddDate(0, 0, 7):                  {},
+		changed.AddDate(0, 0, 7).Add(time.Second): {path},
+	} {
+		got, err := ExpiredFiles(rule, now)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("at %s: ExpiredFiles = %q, %v, want %q", now.Sub(changed), got, err, want)
+		}
+	}
+	rule.AgeBy = "mtime"
+	if got, _ := ExpiredFiles(rule, changed); !reflect.DeepEqual(got, []string{path}) {
+		t.Errorf("by mtime: ExpiredFiles = %q", got)
+	}
+}
+
+func TestExpiredFilesUnreadableSubfolder(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("needs a folder the test cannot read")
+	}
+	dir := retentionDir(t, map[string]time.Duration{
+		"a.log":        30 * day,
+		"locked/b.log": 30 * day,
+	})
+	locked := filepath.Join(dir, "locked")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+
+	got, err := ExpiredFiles(RetentionRule{Name: "r", Path: dir, MaxAgeDays: 7}, retentionNow)
+	if want := []string{filepath.Join(dir, "a.log")}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpiredFiles = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	for _, scheme := range []string{SchemePurge, SchemeClear} {
+		dir := retentionDir(t, map[string]time.Duration{
+			"a.log":   30 * day,
+			"b.log":   10 * day,
+			"new.log": time.Hour,
+		})
+		rule := RetentionRule{Name: scheme, Path: dir, MaxAgeDays: 7, Scheme: scheme}
+		report, err := ApplyRetention(rule, retentionNow)
+		if err != nil {
+			t.Fatalf("%s: %v", scheme, err)
+		}
+		want := []string{filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")}
+		if !reflect.DeepEqual(report.Purged, want) || len(report.Failures) != 0 {
+			t.Errorf("%s: purged %q, failures %v", scheme, report.Purged, report.Failures)
+		}
+		for _, path := range want {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("%s: %s still present", scheme, path)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, "new.log")); err != nil {
+			t.Errorf("%s: file younger than the rule removed: %v", scheme, err)
+		}
+		if scheme == SchemeClear && !reflect.DeepEqual(report.Trace.Engines, []string{EngineUnlink}) {
+			t.Errorf("clear used %q", report.Trace.Engines)
+		}
+	}
+}
+
+func TestApplyRetentionPartialFailure(t *testing.T) {
+	dir := retentionDir(t, map[string]time.Duration{
+		"a.log": 30 * day,
+		"b.log": 20 * day,
+		"c.log": 10 * day,
+	})
+	vanished := filepath.Join(dir, "b.log")
+
+	// The guard runs between listing the files and removing them, which
+	// is when a file can vanish under the batch
+	saved := HostRoots
+	t.Cleanup(func() { HostRoots = saved })
+	HostRoots = desktopHost(t).roots
+	first := true
+	SetTargetGuard(func(Drive) error {
+		if first {
+			first = false
+			return os.Remove(vanished)
+		}
+		return nil
+	})
+	t.Cleanup(func() { SetTargetGuard(nil) })
+
+	report, err := ApplyRetention(RetentionRule{Name: "r", Path: dir, MaxAgeDays: 7}, retentionNow)
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 expired files") {
+		t.Errorf("err = %v, want one failure of three", err)
+	}
+	if len(report.Failures) != 1 || report.Failures[0].Path != vanished {
+		t.Errorf("failures %v, want %s", report.Failures, vanished)
+	}
+	if want := []string{filepath.Join(dir, "a.log"), filepath.Join(dir, "c.log")}; !reflect.DeepEqual(report.Purged, want) {
+		t.Errorf("purged %q, want %q", report.Purged, want)
+	}
+	if len(report.Trace.Errors) != 1 || report.Trace.Errors[0].Path != vanished {
+		t.Errorf("trace errors %v", report.Trace.Errors)
+	}
+}
@@ -0,0 +1,189 @@
+package drivers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// wearWarnPercent is the share of rated endurance used beyond which a drive
+// is flagged as worn out.
+const wearWarnPercent = 90
+
+// HealthReport is a snapshot of a drive's SMART or NVMe health, taken
+// before a wipe and embedded in its certificate.
+type HealthReport struct {
+	Device       string    `json:"device"`
+	Model        string    `json:"model,omitempty"`
+	Protocol     string    `json:"protocol,omitempty"`
+	Passed       bool      `json:"passed"`
+	PowerOnHours int64     `json:"power_on_hours,omitempty"`
+	TemperatureC int64     `json:"temperature_c,omitempty"`
+	CheckedAt    time.Time `json:"checked_at"`
+
+	ReallocatedSectors   int64 `json:"reallocated_sectors"`
+	PendingSectors       int64 `json:"pending_sectors"`
+	OfflineUncorrectable int64 `json:"offline_uncorrectable"`
+	MediaErrors          int64 `json:"media_errors"`
+	// PercentageUsed is the share of rated endurance consumed, -1 when the
+	// drive does not report wear.
+	PercentageUsed int64 `json:"percentage_used"`
+
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Healthy reports whether the drive passed its self-assessment and nothing
+// was flagged.
+func (h HealthReport) Healthy() bool {
+	return h.Passed && len(h.Warnings) == 0
+}
+
+// smartctlOutput is the part of `smartctl --json` output the health check
+// reads.
+type smartctlOutput struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String   string `json:"string"`
+			Severity string `json:"severity"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	Device struct {
+		Name     string `json:"name"`
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	ModelName   string `json:"model_name"`
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	PowerOnTime struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	Temperature struct {
+		Current int64 `json:"current"`
+	} `json:"temperature"`
+	ATASmartAttributes struct {
+		Table []struct {
+			ID    int   `json:"id"`
+			Value int64 `json:"value"`
+			Raw   struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeHealth *struct {
+		CriticalWarning         int64 `json:"critical_warning"`
+		AvailableSpare          int64 `json:"available_spare"`
+		AvailableSpareThreshold int64 `json:"available_spare_threshold"`
+		PercentageUsed          int64 `json:"percentage_used"`
+		MediaErrors             int64 `json:"media_errors"`
+	} `json:"nvme_smart_health_information_log"`
+	SCSIGrownDefectList *int64 `json:"scsi_grown_defect_list"`
+}
+
+// ATA attribute IDs the health check reads.
+const (
+	ataReallocatedSectors   = 5
+	ataWearLevelingCount    = 177
+	ataSSDLifeLeft          = 231
+	ataMediaWearout         = 233
+	ataPendingSectors       = 197
+	ataOfflineUncorrectable = 198
+)
+
+// CheckHealth reads the SMART or NVMe health of device through smartctl.
+func CheckHealth(device string) (HealthReport, error) {
+	out, err := Commands.Output("smartctl", "--json", "-H", "-A", "-i", device)
+	if len(out) == 0 {
+		if errors.Is(err, exec.ErrNotFound) {
+			return HealthReport{}, errors.New("smartctl is not installed")
+		}
+		return HealthReport{}, fmt.Errorf("smartctl failed for %s: %v", device, err)
+	}
+	// smartctl's exit status is a bit mask that is non-zero for failing
+	// drives too, so the JSON decides.
+	return parseSmartctl(out, time.Now())
+}
+
+func parseSmartctl(data []byte, now time.Time) (HealthReport, error) {
+	var out smartctlOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return HealthReport{}, fmt.Errorf("failed to parse smartctl output: %v", err)
+	}
+
+	// Bits 0 and 1: bad command line, or the device could not be opened.
+	if out.Smartctl.ExitStatus&0x03 != 0 {
+		var msgs []string
+		for _, m := range out.Smartctl.Messages {
+			msgs = append(msgs, m.String)
+		}
+		return HealthReport{}, fmt.Errorf("smartctl could not read %s: %s", out.Device.Name, strings.Join(msgs, "; "))
+	}
+	if out.SmartStatus == nil {
+		return HealthReport{}, fmt.Errorf("%s does not report SMART health", out.Device.Name)
+	}
+
+	h := HealthReport{
+		Device:         out.Device.Name,
+		Model:          out.ModelName,
+		Protocol:       out.Device.Protocol,
+		Passed:         out.SmartStatus.Passed,
+		PowerOnHours:   out.PowerOnTime.Hours,
+		TemperatureC:   out.Temperature.Current,
+		CheckedAt:      now.UTC(),
+		PercentageUsed: -1,
+	}
+
+	for _, attr := range out.ATASmartAttributes.Table {
+		switch attr.ID {
+		case ataReallocatedSectors:
+			h.ReallocatedSectors = attr.Raw.Value
+		case ataPendingSectors:
+			h.PendingSectors = attr.Raw.Value
+		case ataOfflineUncorrectable:
+			h.OfflineUncorrectable = attr.Raw.Value
+		case ataWearLevelingCount, ataSSDLifeLeft, ataMediaWearout:
+			// The normalized value counts down from 100 as the flash wears.
+			if attr.Value <= 100 {
+				h.PercentageUsed = 100 - attr.Value
+			}
+		}
+	}
+	if out.SCSIGrownDefectList != nil {
+		h.ReallocatedSectors = *out.SCSIGrownDefectList
+	}
+
+	if nvme := out.NVMeHealth; nvme != nil {
+		h.MediaErrors = nvme.MediaErrors
+		h.PercentageUsed = nvme.PercentageUsed
+		if nvme.CriticalWarning != 0 {
+			h.Warnings = append(h.Warnings, fmt.Sprintf("NVMe critical warning flags 0x%02x", nvme.CriticalWarning))
+		}
+		if nvme.AvailableSpare < nvme.AvailableSpareThreshold {
+			h.Warnings = append(h.Warnings, fmt.Sprintf("spare capacity %d%% is below the %d%% threshold", nvme.AvailableSpare, nvme.AvailableSpareThreshold))
+		}
+	}
+
+	if !h.Passed {
+		h.Warnings = append([]string{"SMART overall health self-assessment FAILED"}, h.Warnings...)
+	}
+	if h.ReallocatedSectors > 0 {
+		h.Warnings = append(h.Warnings, fmt.Sprintf("%d reallocated sectors; their old contents cannot be overwritten", h.ReallocatedSectors))
+	}
+	if h.PendingSectors > 0 {
+		h.Warnings = append(h.Warnings, fmt.Sprintf("%d sectors pending reallocation", h.PendingSectors))
+	}
+	if h.OfflineUncorrectable > 0 {
+		h.Warnings = append(h.Warnings, fmt.Sprintf("%d uncorrectable sectors", h.OfflineUncorrectable))
+	}
+	if h.MediaErrors > 0 {
+		h.Warnings = append(h.Warnings, fmt.Sprintf("%d media errors", h.MediaErrors))
+	}
+	if h.PercentageUsed >= wearWarnPercent {
+		h.Warnings = append(h.Warnings, fmt.Sprintf("%d%% of rated endurance used", h.PercentageUsed))
+	}
+	return h, nil
+}
@@ -0,0 +1,100 @@
+package drivers
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubSmartctl makes smartctl answer for device with a recorded fixture.
+func stubSmartctl(t *testing.T, device, fixture string, runErr error) {
+	t.Helper()
+	stubCommands(t).record(filepath.Join("smartctl", fixture), runErr, "smartctl", "--json", "-H", "-A", "-i", device)
+}
+
+func TestCheckHealthHealthyATA(t *testing.T) {
+	stubSmartctl(t, "/dev/sda", "ata_healthy.json", nil)
+
+	h, err := CheckHealth("/dev/sda")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.Healthy() {
+		t.Errorf("expected a healthy drive, got warnings %v", h.Warnings)
+	}
+	if h.Model != "Samsung SSD 870 EVO 500GB" || h.Protocol != "ATA" {
+		t.Errorf("identity = %q %q", h.Model, h.Protocol)
+	}
+	if h.PowerOnHours != 2310 || h.TemperatureC != 31 {
+		t.Errorf("power on %d h, %d C", h.PowerOnHours, h.TemperatureC)
+	}
+	if h.PercentageUsed != 2 {
+		t.Errorf("PercentageUsed = %d, want 2", h.PercentageUsed)
+	}
+}
+
+func TestCheckHealthFailingATA(t *testing.T) {
+	// smartctl exits non-zero for failing drives but still prints JSON.
+	stubSmartctl(t, "/dev/sdb", "ata_failing.json", &exec.ExitError{})
+
+	h, err := CheckHealth("/dev/sdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Passed || h.Healthy() {
+		t.Fatal("expected a failing drive")
+	}
+	if h.ReallocatedSectors != 1864 || h.PendingSectors != 48 || h.OfflineUncorrectable != 12 {
+		t.Errorf("sectors = %d/%d/%d", h.ReallocatedSectors, h.PendingSectors, h.OfflineUncorrectable)
+	}
+	if h.PercentageUsed != -1 {
+		t.Errorf("hard disk reported wear %d", h.PercentageUsed)
+	}
+	if len(h.Warnings) != 4 || !strings.Contains(h.Warnings[0], "FAILED") {
+		t.Errorf("warnings = %q", h.Warnings)
+	}
+}
+
+func TestCheckHealthWornNVMe(t *testing.T) {
+	stubSmartctl(t, "/dev/nvme0", "nvme_worn.json", nil)
+
+	h, err := CheckHealth("/dev/nvme0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.Passed {
+		t.Error("self-assessment should pass")
+	}
+	if h.MediaErrors != 3 || h.PercentageUsed != 97 {
+		t.Errorf("media errors %d, used %d%%", h.MediaErrors, h.PercentageUsed)
+	}
+	want := []string{"critical warning", "spare capacity", "media errors", "endurance"}
+	if len(h.Warnings) != len(want) {
+		t.Fatalf("warnings = %q", h.Warnings)
+	}
+	for i, w := range want {
+		if !strings.Contains(h.Warnings[i], w) {
+			t.Errorf("warning %d = %q, want it to mention %q", i, h.Warnings[i], w)
+		}
+	}
+}
+
+func TestCheckHealthOpenFailure(t *testing.T) {
+	stubSmartctl(t, "/dev/sdz", "open_failed.json", &exec.ExitError{})
+
+	_, err := CheckHealth("/dev/sdz")
+	if err == nil || !strings.Contains(err.Error(), "No such device") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestCheckHealthMissingTool(t *testing.T) {
+	// Nothing recorded, so smartctl is missing.
+	stubCommands(t)
+
+	_, err := CheckHealth("/dev/sda")
+	if err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package drivers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SweepProfile names a set of well-known artifact locations that are purged
+// together, e.g. a user's trash or shell history.
+// Paths may contain globs, a leading ~ and $VARS; the XDG base directory
+// variables fall back to their spec defaults when unset, and any other
+// unset variable makes the profile invalid.
+type SweepProfile struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Paths       []string `json:"paths"`
+	// OwnedOnly restricts matches to entries owned by the current user,
+	// which is what makes a shared location like /tmp safe to sweep. As
+	// root the user is the one sudo was invoked by; see sweepOwner.
+	OwnedOnly bool `json:"owned_only,omitempty"`
+}
+
+// PurgeReport lists what a purge run destroyed and what it could not.
+type PurgeReport struct {
+	Purged []string
+	// Linked lists derived artifacts (thumbnails, recent-file entries,
+	// editor backups) purged alongside the requested items.
+	Linked   []string
+	Failures []PurgeFailure
+	// Trace records the engines, passes and errors of the whole run.
+	Trace Trace
+}
+
+// fail records a failure the trace does not hold yet.
+func (r *PurgeReport) fail(path string, err error) {
+	r.Failures = append(r.Failures, PurgeFailure{Path: path, Err: err})
+	r.Trace.fail(path, err)
+}
+
+// PurgeFailure records a single item that could not be purged.
+type PurgeFailure struct {
+	Path string
+	Err  error
+}
+
+// BuiltinSweepProfiles are the profiles offered out of the box. User-defined
+// profiles from the config file are listed after these.
+var BuiltinSweepProfiles = []SweepProfile{
+	{
+		Name:        "Trash",
+		Description: "XDG trash contents and their .trashinfo records",
+		Paths: []string{
+			"$XDG_DATA_HOME/Trash/files/*",
+			"$XDG_DATA_HOME/Trash/info/*",
+		},
+	},
+	{
+		Name:        "Thumbnails",
+		Description: "Cached file previews in ~/.cache/thumbnails",
+		Paths: []string{
+			"$XDG_CACHE_HOME/thumbnails/*/*",
+		},
+	},
+	{
+		Name:        "Browser caches",
+		Description: "Firefox, Chrome, Chromium and Brave disk caches",
+		Paths: []string{
+			"$XDG_CACHE_HOME/mozilla/firefox/*/cache2",
+			"$XDG_CACHE_HOME/google-chrome/*/Cache",
+			"$XDG_CACHE_HOME/google-chrome/*/Code Cache",
+			"$XDG_CACHE_HOME/chromium/*/Cache",
+			"$XDG_CACHE_HOME/chromium/*/Code Cache",
+			"$XDG_CACHE_HOME/BraveSoftware/Brave-Browser/*/Cache",
+		},
+	},
+	{
+		Name:        "Recent files",
+		Description: "The desktop's recently-used file list",
+		Paths: []string{
+			"$XDG_DATA_HOME/recently-used.xbel",
+		},
+	},
+	{
+		Name:        "Shell history",
+		Description: "bash, zsh, fish, python and less history files",
+		Paths: []string{
+			"~/.bash_history",
+			"~/.zsh_history",
+			"$XDG_DATA_HOME/fish/fish_history",
+			"~/.python_history",
+			"~/.lesshst",
+		},
+	},
+	{
+		Name:        "Temporary files",
+		Description: "Files in /tmp owned by the current user",
+		Paths: []string{
+			"/tmp/*",
+		},
+		OwnedOnly: true,
+	},
+}
+
+// ExpandSweepProfile resolves a profile into the sorted list of existing
+// paths it would purge. Entries nested inside another planned directory are
+// dropped, since purging the parent already covers them, and critical system
+// paths are never planned.
+func ExpandSweepProfile(p SweepProfile) ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve home directory: %v", err)
+	}
+	var owner fileOwner
+	if p.OwnedOnly {
+		if owner, err = sweepOwner(); err != nil {
+			return nil, fmt.Errorf("profile %s: %v", p.Name, err)
+		}
+	}
+	seen := make(map[string]bool)
+	var plan []string
+
+	for _, pattern := range p.Paths {
+		expanded, err := expandSweepPath(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %v", p.Name, err)
+		}
+
+		matches, err := filepath.Glob(expanded)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: bad pattern %q: %v", p.Name, pattern, err)
+		}
+
+		for _, m := range matches {
+			m = filepath.Clean(m)
+			if seen[m] || isCriticalPath(m) || isSweepAnchor(m, home) {
+				continue
+			}
+			info, err := os.Lstat(m)
+			if err != nil {
+				continue
+			}
+			// PurgeItem follows symlinks, so a link in a swept location
+			// must never lead it to a file outside the profile.
+			if !info.Mode().IsRegular() && !info.IsDir() {
+				continue
+			}
+			if p.OwnedOnly && !owner.owns(m, info) {
+				continue
+			}
+			seen[m] = true
+			plan = append(plan, m)
+		}
+	}
+
+	sort.Strings(plan)
+
+	result := []string{}
+	for _, path := range plan {
+		covered := false
+		for _, dir := range result {
+			if isWithin(path, dir) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			result = append(result, path)
+		}
+	}
+
+	return result, nil
+}
+
+// PurgeSweepProfile expands a profile and purges every planned item. A
+// failure on one item does not stop the rest of the sweep; failures are
+// collected in the report instead.
+func PurgeSweepProfile(p SweepProfile) (PurgeReport, error) {
+	var report PurgeReport
+
+	plan, err := ExpandSweepProfile(p)
+	if err == nil {
+		err = checkTargets(plan...)
+	}
+	if err != nil {
+		report.Trace.fail("", err)
+		return report, err
+	}
+
+	for _, path := range plan {
+		if err := purgeChecked(path, &report.Trace); err != nil {
+			report.Failures = append(report.Failures, PurgeFailure{Path: path, Err: err})
+			continue
+		}
+		report.Purged = append(report.Purged, path)
+	}
+
+	if len(report.Failures) > 0 {
+		return report, fmt.Errorf("%d of %d items in %s could not be purged", len(report.Failures), len(plan), p.Name)
+	}
+	return report, nil
+}
+
+// expandSweepPath expands a leading ~, the XDG base directory variables and
+// any other environment variables in a profile path. A variable that is
+// unset or empty is an error rather than "": "$MYCACHE/*" must not turn
+// into "/*". Patterns that would sweep a filesystem root or a home
+// directory wholesale are refused.
+func expandSweepPath(pattern string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve home directory: %v", err)
+	}
+
+	if pattern == "~" || strings.HasPrefix(pattern, "~/") {
+		pattern = filepath.Join(home, strings.TrimPrefix(pattern, "~"))
+	}
+
+	xdgDefaults := map[string]string{
+		"XDG_DATA_HOME":   filepath.Join(home, ".local", "share"),
+		"XDG_CACHE_HOME":  filepath.Join(home, ".cache"),
+		"XDG_CONFIG_HOME": filepath.Join(home, ".config"),
+		"XDG_STATE_HOME":  filepath.Join(home, ".local", "state"),
+	}
+
+	var unset []string
+	expanded := os.Expand(pattern, func(name string) string {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+		if v, ok := xdgDefaults[name]; ok {
+			return v
+		}
+		unset = append(unset, "$"+name)
+		return ""
+	})
+	if len(unset) > 0 {
+		return "", fmt.Errorf("%q uses unset variable %s", pattern, strings.Join(unset, ", "))
+	}
+
+	expanded = filepath.Clean(expanded)
+	if !filepath.IsAbs(expanded) {
+		return "", fmt.Errorf("%q is not an absolute path", pattern)
+	}
+	if base := globBase(expanded); base != expanded && filepath.Dir(base) == base {
+		return "", fmt.Errorf("%q would sweep the filesystem root", pattern)
+	}
+	if isSweepAnchor(expanded, home) {
+		return "", fmt.Errorf("%q would sweep %s", pattern, expanded)
+	}
+	return expanded, nil
+}
+
+// globBase is the longest leading directory of pattern free of glob
+// metacharacters.
+func globBase(pattern string) string {
+	base := pattern
+	for strings.ContainsAny(base, "*?[") {
+		base = filepath.Dir(base)
+	}
+	return base
+}
+
+// isSweepAnchor reports whether a sweep must never purge path as a whole:
+// a filesystem root, a top-level directory such as /home, /opt or /media,
+// or a home directory.
+func isSweepAnchor(path, home string) bool {
+	path = filepath.Clean(path)
+	parent := filepath.Dir(path)
+	if parent == path || filepath.Dir(parent) == parent {
+		return true
+	}
+	home = filepath.Clean(home)
+	return path == home || isWithin(home, path) || parent == filepath.Dir(home) || path == "/root"
+}
+
+// isWithin reports whether path lies strictly below dir.
+func isWithin(path, dir string) bool {
+	return strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
@@ -0,0 +1,112 @@
+package drivers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExpandSweepPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("profile paths are Unix paths")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "/var/cache/alex")
+	t.Setenv("MYCACHE", "")
+
+	for pattern, want := range map[string]string{
+		"~/.bash_history":              home + "/.bash_history",
+		"$XDG_DATA_HOME/Trash/files/*": home + "/.local/share/Trash/files/*",
+		"$XDG_CACHE_HOME/thumbnails/*": "/var/cache/alex/thumbnails/*",
+		"${HOME}/.lesshst":             home + "/.lesshst",
+		"~/*":                          home + "/*",
+		"/tmp/*":                       "/tmp/*",
+	} {
+		got, err := expandSweepPath(pattern)
+		if err != nil || got != want {
+			t.Errorf("expandSweepPath(%q) = %q, %v, want %q", pattern, got, err, want)
+		}
+	}
+
+	for pattern, want := range map[string]string{
+		"$MYCACHE/*":        "unset variable $MYCACHE",
+		"$NO_SUCH_VAR/x/$Y": "unset variable $NO_SUCH_VAR, $Y",
+		"/*":                "filesystem root",
+		"/*/cache":          "filesystem root",
+		"~":                 "would sweep " + home,
+		"$HOME/..":          "would sweep",
+		"/home":             "would sweep /home",
+		"/opt/":             "would sweep /opt",
+		"cache/*":           "not an absolute path",
+	} {
+		got, err := expandSweepPath(pattern)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expandSweepPath(%q) = %q, %v, want an error saying %q", pattern, got, err, want)
+		}
+	}
+}
+
+func TestIsSweepAnchor(t *testing.T) {
+	home := "/home/alex"
+	for path, want := range map[string]bool{
+		"/":                   true,
+		"/home":               true,
+		"/media":              true,
+		"/mnt":                true,
+		"/srv":                true,
+		"/opt":                true,
+		"/root":               true,
+		"/home/alex":          true,
+		"/home/alex/":         true,
+		"/home/sam":           true,
+		"/tmp":                true,
+		"/tmp/build":          false,
+		"/home/alex/.cache":   false,
+		"/media/alex/Stick/x": false,
+		"/opt/app/cache":      false,
+	} {
+		if got := isSweepAnchor(path, home); got != want {
+			t.Errorf("isSweepAnchor(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestExpandSweepProfile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("profile paths are Unix paths")
+	}
+	home := tree(t, map[string]int{
+		".cache/app/a":     1,
+		".cache/app/sub/b": 1,
+		".cache/other/c":   1,
+	})
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", "")
+	if err := os.Symlink("/etc/passwd", filepath.Join(home, ".cache", "app", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := ExpandSweepProfile(SweepProfile{Name: "test", Paths: []string{
+		"$XDG_CACHE_HOME/app/*",
+		"$XDG_CACHE_HOME/app/sub/*",
+		"~/.cache/other",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(home, ".cache")
+	want := []string{cache + "/app/a", cache + "/app/sub", cache + "/other"}
+	if !reflect.DeepEqual(plan, want) {
+		t.Errorf("plan = %q, want %q", plan, want)
+	}
+
+	// One bad entry invalidates the whole profile
+	if _, err := ExpandSweepProfile(SweepProfile{Name: "test", Paths: []string{"~/.cache/other", "$MYCACHE/*"}}); err == nil {
+		t.Error("expanded a profile using an unset variable")
+	}
+}
@@ -0,0 +1,399 @@
+package drivers
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Roots locates the kernel and udev interfaces drive detection reads. Tests
+// point it at fixture trees instead of the live system.
+type Roots struct {
+	Sys  string // sysfs, normally /sys
+	Proc string // procfs, normally /proc
+	Udev string // udev database, normally /run/udev/data
+}
+
+// HostRoots are the interfaces of the running system.
+var HostRoots = Roots{Sys: "/sys", Proc: "/proc", Udev: "/run/udev/data"}
+
+// sectorSize is the unit sysfs reports size and start in, whatever the
+// device's real sector size is.
+const sectorSize = 512
+
+// Partition is one partition of a Drive.
+type Partition struct {
+	Name   string
+	Device string
+	Number int
+	// Start is the byte offset of the partition on its disk.
+	Start       int64
+	Size        int64
+	ReadOnly    bool
+	FileSystem  string
+	Label       string
+	MountPoints []string
+	// Holders is what is stacked on the partition: LUKS mappings, LVM
+	// volumes, md arrays and their filesystems.
+	Holders []Holder
+}
+
+// mountEntry is one line of mountinfo.
+type mountEntry struct {
+	devID  string // major:minor
+	root   string // subtree of the filesystem mounted, "/" unless a bind mount
+	point  string
+	fsType string
+	source string
+	// options are the per-mount options followed by the filesystem's own.
+	options []string
+}
+
+// ScanBlockDevices lists the physical disks under roots.Sys/block with their
+// partitions, the devices stacked on them, and every mount point recorded
+// in roots.Proc/self/mountinfo. Unmounted disks are included. Virtual
+// devices (loop, ram, zram, device-mapper and md) and empty media slots are
+// not listed as drives of their own.
+func ScanBlockDevices(roots Roots) ([]Drive, error) {
+	entries, err := os.ReadDir(filepath.Join(roots.Sys, "block"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block devices: %v", err)
+	}
+
+	mounts, err := readMountInfo(filepath.Join(roots.Proc, "self", "mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+
+	topology, err := scanTopology(roots, mounts)
+	if err != nil {
+		return nil, err
+	}
+
+	var drives []Drive
+	for _, e := range entries {
+		if d, ok := readBlockDevice(roots, e.Name(), mounts, topology); ok {
+			drives = append(drives, d)
+		}
+	}
+	return drives, nil
+}
+
+func readBlockDevice(roots Roots, name string, mounts []mountEntry, topology *Topology) (Drive, bool) {
+	dir := filepath.Join(roots.Sys, "block", name)
+
+	// /sys/block entries are symlinks into the device tree; the target tells
+	// virtual devices apart and names the bus the disk hangs off.
+	link, _ := os.Readlink(dir)
+	if strings.Contains(link, "/devices/virtual/") {
+		return Drive{}, false
+	}
+
+	size := readSysInt(dir, "size") * sectorSize
+	if size == 0 {
+		return Drive{}, false
+	}
+
+	devID := readSysString(dir, "dev")
+	udev := readUdevProperties(roots, devID)
+
+	d := Drive{
+		Device:             "/dev/" + name,
+		Size:               size,
+		Model:              firstNonEmpty(readSysString(dir, "device/model"), strings.ReplaceAll(udev["ID_MODEL"], "_", " ")),
+		Serial:             firstNonEmpty(readSysString(dir, "device/serial"), udev["ID_SERIAL_SHORT"]),
+		WWN:                firstNonEmpty(readSysString(dir, "wwid"), readSysString(dir, "device/wwid"), udev["ID_WWN"]),
+		Vendor:             firstNonEmpty(vendorName(readSysString(dir, "device/vendor")), udev["ID_VENDOR"]),
+		Rotational:         readSysInt(dir, "queue/rotational") == 1,
+		ReadOnly:           readSysInt(dir, "ro") == 1,
+		Transport:          transportOf(link, name, udev["ID_BUS"]),
+		LogicalSectorSize:  int(readSysInt(dir, "queue/logical_block_size")),
+		PhysicalSectorSize: int(readSysInt(dir, "queue/physical_block_size")),
+	}
+	d.Fingerprint = Fingerprint(d)
+	d.IsRemovable = readSysInt(dir, "removable") == 1 || d.Transport == "usb"
+	d.Type = "internal"
+	if d.Transport == "usb" {
+		d.Type = "usb"
+		d.USB = readUSBInfo(roots, dir)
+	}
+
+	// Filesystems are either on the whole disk (superfloppy sticks) or on
+	// its partitions; both contribute mount points.
+	own := mountsOf(mounts, devID, d.Device)
+	d.FileSystem = firstNonEmpty(fsTypeOf(own), udev["ID_FS_TYPE"])
+	d.MountPoints = mountPoints(own)
+	d.Holders = topology.holderTree(name)
+	all := own
+
+	parts, _ := os.ReadDir(dir)
+	for _, p := range parts {
+		partDir := filepath.Join(dir, p.Name())
+		if _, err := os.Stat(filepath.Join(partDir, "partition")); err != nil {
+			continue
+		}
+
+		partID := readSysString(partDir, "dev")
+		partUdev := readUdevProperties(roots, partID)
+		partMounts := mountsOf(mounts, partID, "/dev/"+p.Name())
+		all = append(all, partMounts...)
+
+		d.Partitions = append(d.Partitions, Partition{
+			Name:        p.Name(),
+			Device:      "/dev/" + p.Name(),
+			Number:      int(readSysInt(partDir, "partition")),
+			Start:       readSysInt(partDir, "start") * sectorSize,
+			Size:        readSysInt(partDir, "size") * sectorSize,
+			ReadOnly:    readSysInt(partDir, "ro") == 1,
+			FileSystem:  firstNonEmpty(fsTypeOf(partMounts), partUdev["ID_FS_TYPE"]),
+			Label:       partUdev["ID_FS_LABEL"],
+			MountPoints: mountPoints(partMounts),
+			Holders:     topology.holderTree(p.Name()),
+		})
+		d.MountPoints = append(d.MountPoints, mountPoints(partMounts)...)
+	}
+	sort.Slice(d.Partitions, func(i, j int) bool { return d.Partitions[i].Number < d.Partitions[j].Number })
+
+	// Filesystems on LUKS, LVM or RAID live on the disk just as much as
+	// those on its partitions. Loop devices mount image files stored on
+	// the disk, such as snaps, so they are listed but never browsed.
+	for _, n := range topology.Above(name) {
+		if n.Kind == NodePartition {
+			continue
+		}
+		if n.Kind != NodeLoop {
+			all = append(all, n.mounts...)
+		}
+		d.MountPoints = append(d.MountPoints, n.MountPoints...)
+	}
+
+	// Path is where the drive's contents are browsed: the first real mount
+	// that is not part of the running system.
+	for _, m := range all {
+		if m.root != "/" || isSystemMount(m.point) {
+			continue
+		}
+		d.Path = m.point
+		d.FileSystem = m.fsType
+		d.MountOptions = m.options
+		break
+	}
+
+	d.Name = name
+	if d.Model != "" {
+		d.Name = d.Model
+	} else if d.Path != "" {
+		d.Name = filepath.Base(d.Path)
+	}
+
+	return d, true
+}
+
+// transportOf names the bus a disk is attached to, the way lsblk's TRAN
+// column does.
+func transportOf(link, name, udevBus string) string {
+	switch {
+	case strings.Contains(link, "/usb"):
+		return "usb"
+	case strings.Contains(link, "/nvme/"):
+		return "nvme"
+	case strings.Contains(link, "/mmc_host/"):
+		return "mmc"
+	case strings.Contains(link, "/ata"):
+		return "sata"
+	case strings.Contains(link, "/virtio"):
+		return "virtio"
+	case strings.HasPrefix(name, "nvme"):
+		return "nvme"
+	case strings.HasPrefix(name, "mmcblk"):
+		return "mmc"
+	}
+	switch udevBus {
+	case "ata":
+		return "sata"
+	case "":
+		return ""
+	default:
+		return udevBus
+	}
+}
+
+// vendorName drops the numeric PCI vendor IDs virtio and some controllers
+// expose in place of a SCSI vendor string.
+func vendorName(v string) string {
+	if strings.HasPrefix(v, "0x") {
+		return ""
+	}
+	return v
+}
+
+// isSystemMount reports mounts that belong to the running system and must
+// not be offered for browsing or wiping.
+func isSystemMount(point string) bool {
+	return point == "/" || point == "/boot" || strings.HasPrefix(point, "/boot/")
+}
+
+// removableMediaDirs are where desktops and administrators mount media
+// that comes and goes. A whole-disk wipe may unmount what is mounted below
+// them; any other mount means the disk is in use.
+var removableMediaDirs = []string{"/media", "/run/media", "/mnt"}
+
+func isRemovableMediaMount(point string) bool {
+	for _, dir := range removableMediaDirs {
+		if strings.HasPrefix(point, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func mountsOf(mounts []mountEntry, devID, device string) []mountEntry {
+	var found []mountEntry
+	for _, m := range mounts {
+		// btrfs reports an anonymous device number, so fall back to the
+		// mount source.
+		if (devID != "" && m.devID == devID) || m.source == device {
+			found = append(found, m)
+		}
+	}
+	return found
+}
+
+func mountPoints(mounts []mountEntry) []string {
+	var points []string
+	for _, m := range mounts {
+		points = append(points, m.point)
+	}
+	return points
+}
+
+func fsTypeOf(mounts []mountEntry) string {
+	if len(mounts) == 0 {
+		return ""
+	}
+	return mounts[0].fsType
+}
+
+// readMountInfo parses a mountinfo file (see proc(5)). Unlike /proc/mounts it
+// carries each mount's device number, so mounts can be matched to sysfs
+// devices even when the source is a symlink such as /dev/disk/by-uuid/...
+func readMountInfo(path string) ([]mountEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mount table: %v", err)
+	}
+	defer file.Close()
+
+	var mounts []mountEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt/parent rw,noatime master:1 - ext3 /dev/root rw
+		fields := strings.Split(scanner.Text(), " ")
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 0 || sep+2 >= len(fields) {
+			continue
+		}
+		mounts = append(mounts, mountEntry{
+			devID:   fields[2],
+			root:    unescapeMountField(fields[3]),
+			point:   unescapeMountField(fields[4]),
+			fsType:  fields[sep+1],
+			source:  unescapeMountField(fields[sep+2]),
+			options: mountOptions(fields[5], fields[sep+3:]),
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// mountOptions merges the per-mount and superblock options of a mountinfo
+// line, dropping duplicates such as rw.
+func mountOptions(mount string, super []string) []string {
+	options := strings.Split(mount, ",")
+	seen := make(map[string]bool)
+	for _, o := range options {
+		seen[o] = true
+	}
+	if len(super) > 0 {
+		for _, o := range strings.Split(super[0], ",") {
+			if !seen[o] {
+				seen[o] = true
+				options = append(options, o)
+			}
+		}
+	}
+	return options
+}
+
+// unescapeMountField undoes the octal escapes the kernel uses for spaces,
+// tabs, newlines and backslashes in mount paths.
+func unescapeMountField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// readUdevProperties returns the E: properties udev recorded for the block
+// device with the given major:minor, or nil when udev has no entry.
+func readUdevProperties(roots Roots, devID string) map[string]string {
+	if devID == "" || roots.Udev == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(roots.Udev, "b"+devID))
+	if err != nil {
+		return nil
+	}
+	props := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "E:") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line[2:], "="); ok {
+			props[key] = value
+		}
+	}
+	return props
+}
+
+func readSysString(dir, attr string) string {
+	data, err := os.ReadFile(filepath.Join(dir, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readSysInt(dir, attr string) int64 {
+	n, _ := strconv.ParseInt(readSysString(dir, attr), 10, 64)
+	return n
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,394 @@
+package drivers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const (
+	ataPort  = "pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0"
+	nvmePort = "pci0000:00/0000:00:1d.0/0000:3d:00.0/nvme/nvme0"
+	usbStick = "pci0000:00/0000:00:14.0/usb2/2-1"
+	usbSSD   = "pci0000:00/0000:00:14.0/usb2/2-2"
+)
+
+// desktopHost is a laptop booted from NVMe, with a SATA data disk carrying
+// a swap partition, a USB stick, a USB SSD in an enclosure, a snap loop
+// device and an empty optical drive.
+func desktopHost(t *testing.T) *fakeHost {
+	h := newFakeHost(t, "desktop.txt")
+
+	h.disk(nvmePort, "nvme0n1", "259:0", 2000409264, map[string]string{
+		"device/model":     "WD_BLACK SN770 1TB",
+		"device/serial":    "22471Z800123",
+		"wwid":             "eui.e8238fa6bf530001001b448b4a1b2c3d",
+		"queue/rotational": "0",
+	})
+	h.partition("nvme0n1", "nvme0n1p1", "259:1", 1, 2048, 1050624)
+	h.partition("nvme0n1", "nvme0n1p2", "259:2", 2, 1052672, 1999354511)
+
+	h.disk(ataPort, "sda", "8:0", 3907029168, map[string]string{
+		"device/model":     "ST2000DM008-2FR1",
+		"device/vendor":    "ATA",
+		"queue/rotational": "1",
+	})
+	h.udev("8:0", "ID_SERIAL_SHORT=ZFL5K2QX", "ID_WWN=0x5000c500e1a2b3c4")
+	h.partition("sda", "sda1", "8:1", 1, 2048, 3890249728)
+	h.partition("sda", "sda2", "8:2", 2, 3890251776, 16775168)
+	h.udev("8:1", "ID_FS_TYPE=ntfs", "ID_FS_LABEL=Data")
+	h.udev("8:2", "ID_FS_TYPE=swap")
+	h.swap("/dev/sda2")
+
+	h.usbDevice(usbStick, map[string]string{
+		"idVendor":     "0781",
+		"idProduct":    "5583",
+		"manufacturer": "SanDisk",
+		"product":      "Ultra Fit",
+		"serial":       "4C530001230712114275",
+		"speed":        "5000",
+		"version":      " 3.20",
+	}, "usb-storage")
+	h.disk(usbStick+"/2-1:1.0/host6/target6:0:0/6:0:0:0", "sdc", "8:32", 60063744, map[string]string{
+		"device/model":     "Ultra Fit",
+		"device/vendor":    "SanDisk",
+		"removable":        "1",
+		"queue/rotational": "1",
+	})
+	h.udev("8:32", "ID_BUS=usb", "ID_SERIAL_SHORT=4C530001230712114275")
+	h.partition("sdc", "sdc1", "8:33", 1, 2048, 60061696)
+
+	h.usbDevice(usbSSD, map[string]string{
+		"idVendor":  "152d",
+		"idProduct": "0578",
+		"serial":    "0000000000AB",
+		"speed":     "10000",
+	}, "uas")
+	h.disk(usbSSD+"/2-2:1.0/host7/target7:0:0/7:0:0:0", "sdd", "8:48", 976773168, map[string]string{
+		"device/model":     "CT500MX500SSD1",
+		"queue/rotational": "0",
+	})
+	h.udev("8:48", "ID_BUS=usb", "ID_SERIAL_SHORT=0000000000AB")
+	h.partition("sdd", "sdd1", "8:49", 1, 2048, 976771072)
+	h.udev("8:49", "ID_FS_TYPE=exfat", "ID_FS_LABEL=T7")
+
+	h.virtual("loop0", "7:0", 151824, map[string]string{
+		"loop/backing_file": "/var/lib/snapd/snaps/core22_1122.snap",
+	})
+	h.disk("pci0000:00/0000:00:17.0/ata2/host1/target1:0:0/1:0:0:0", "sr0", "11:0", 0, map[string]string{
+		"removable": "1",
+	})
+	return h
+}
+
+// luksLVMHost boots from LVM on LUKS on sda2. sdb1 and sdc1 form a RAID 1
+// array carrying an LVM volume group mounted at /srv/data.
+func luksLVMHost(t *testing.T) *fakeHost {
+	h := newFakeHost(t, "luks_lvm.txt")
+
+	h.disk(ataPort, "sda", "8:0", 1000215216, map[string]string{"device/model": "Samsung SSD 870 EVO 500GB"})
+	h.partition("sda", "sda1", "8:1", 1, 2048, 2097152)
+	h.partition("sda", "sda2", "8:2", 2, 2099200, 998113280)
+	h.udev("8:2", "ID_FS_TYPE=crypto_LUKS")
+	h.virtual("dm-0", "253:0", 998080512, map[string]string{
+		"dm/name": "luks-3f2a",
+		"dm/uuid": "CRYPT-LUKS2-3f2a9d7c41b04e0a8f2b6c1d5e7a9b3c-luks-3f2a",
+	})
+	h.virtual("dm-1", "253:1", 981303296, map[string]string{
+		"dm/name": "vg0-root",
+		"dm/uuid": "LVM-Xk2Lq9vRt0aBcDeFgHiJkLmNoPqRsTuVwXyZ01234567",
+	})
+	h.virtual("dm-2", "253:2", 16777216, map[string]string{
+		"dm/name": "vg0-swap",
+		"dm/uuid": "LVM-Xk2Lq9vRt0aBcDeFgHiJkLmNoPqRsTuVwXyZ89abcdef",
+	})
+	h.stack("sda2", "dm-0")
+	h.stack("dm-0", "dm-1")
+	h.stack("dm-0", "dm-2")
+	h.swap("/dev/dm-2")
+
+	for _, disk := range []struct{ name, dev, part, partDev, port string }{
+		{"sdb", "8:16", "sdb1", "8:17", "pci0000:00/0000:00:17.0/ata2/host1/target1:0:0/1:0:0:0"},
+		{"sdc", "8:32", "sdc1", "8:33", "pci0000:00/0000:00:17.0/ata3/host2/target2:0:0/2:0:0:0"},
+	} {
+		h.disk(disk.port, disk.name, disk.dev, 7814037168, map[string]string{"device/model": "WDC WD40EFPX-68C"})
+		h.partition(disk.name, disk.part, disk.partDev, 1, 2048, 7814035087)
+		h.udev(disk.partDev, "ID_FS_TYPE=linux_raid_member")
+	}
+	h.virtual("md0", "9:0", 7813771264, map[string]string{"md/level": "raid1"})
+	h.udev("9:0", "ID_FS_TYPE=LVM2_member")
+	h.stack("sdb1", "md0")
+	h.stack("sdc1", "md0")
+	h.virtual("dm-3", "253:3", 7813767168, map[string]string{
+		"dm/name": "data-vol",
+		"dm/uuid": "LVM-q8Wm3nP0xYz1AbCdEfGhIjKlMnOpQrStUvWx98765432",
+	})
+	h.stack("md0", "dm-3")
+	return h
+}
+
+func scanDrives(t *testing.T, h *fakeHost) map[string]Drive {
+	t.Helper()
+	drives, err := ScanBlockDevices(h.roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]Drive)
+	for _, d := range drives {
+		byName[d.Device] = d
+	}
+	return byName
+}
+
+func TestScanBlockDevicesDesktop(t *testing.T) {
+	drives := scanDrives(t, desktopHost(t))
+
+	var devices []string
+	for dev := range drives {
+		devices = append(devices, dev)
+	}
+	slices.Sort(devices)
+	// loop0 is virtual and sr0 has no medium.
+	if want := []string{"/dev/nvme0n1", "/dev/sda", "/dev/sdc", "/dev/sdd"}; !reflect.DeepEqual(devices, want) {
+		t.Fatalf("drives = %v, want %v", devices, want)
+	}
+
+	nvme := drives["/dev/nvme0n1"]
+	if nvme.Transport != "nvme" || nvme.Type != "internal" || nvme.IsRemovable {
+		t.Errorf("nvme0n1: transport %q, type %q, removable %v", nvme.Transport, nvme.Type, nvme.IsRemovable)
+	}
+	if nvme.Name != "WD_BLACK SN770 1TB" || nvme.Serial != "22471Z800123" || nvme.Size != 2000409264*512 {
+		t.Errorf("nvme0n1: %q %q %d", nvme.Name, nvme.Serial, nvme.Size)
+	}
+	// The snap's loop device lives on the root filesystem, and is not
+	// offered for browsing either.
+	if want := []string{"/boot/efi", "/", "/snap/core22/1122"}; !reflect.DeepEqual(nvme.MountPoints, want) {
+		t.Errorf("nvme0n1 mount points = %q, want %q", nvme.MountPoints, want)
+	}
+	if nvme.Path != "" {
+		t.Errorf("system disk offered for browsing at %q", nvme.Path)
+	}
+	if len(nvme.Partitions) != 2 || nvme.Partitions[0].FileSystem != "vfat" || nvme.Partitions[1].Start != 1052672*512 {
+		t.Errorf("nvme0n1 partitions = %+v", nvme.Partitions)
+	}
+
+	sda := drives["/dev/sda"]
+	if sda.Transport != "sata" || !sda.Rotational || sda.Vendor != "ATA" || sda.Path != "" {
+		t.Errorf("sda: transport %q, rotational %v, vendor %q, path %q", sda.Transport, sda.Rotational, sda.Vendor, sda.Path)
+	}
+	if sda.Serial != "ZFL5K2QX" || sda.WWN != "0x5000c500e1a2b3c4" {
+		t.Errorf("sda serial %q, WWN %q from udev", sda.Serial, sda.WWN)
+	}
+	if len(sda.Partitions) != 2 {
+		t.Fatalf("sda partitions = %+v", sda.Partitions)
+	}
+	if p := sda.Partitions[0]; p.FileSystem != "ntfs" || p.Label != "Data" || p.MountPoints != nil {
+		t.Errorf("sda1 = %+v", p)
+	}
+	if p := sda.Partitions[1]; p.Number != 2 || p.FileSystem != "swap" {
+		t.Errorf("sda2 = %+v", p)
+	}
+
+	stick := drives["/dev/sdc"]
+	if stick.Type != "usb" || !stick.IsRemovable || stick.Name != "Ultra Fit" {
+		t.Errorf("sdc: type %q, removable %v, name %q", stick.Type, stick.IsRemovable, stick.Name)
+	}
+	if stick.Path != "/media/alex/My Stick" || stick.FileSystem != "vfat" {
+		t.Errorf("sdc mounted at %q as %q", stick.Path, stick.FileSystem)
+	}
+	if !slices.Contains(stick.MountOptions, "nosuid") || !slices.Contains(stick.MountOptions, "showexec") {
+		t.Errorf("sdc mount options = %q", stick.MountOptions)
+	}
+	if u := stick.USB; u == nil || u.ID() != "0781:5583" || u.Driver != "usb-storage" || u.SpeedMbps != 5000 || u.Version != "3.20" || u.Bridge != "" {
+		t.Errorf("sdc USB = %+v", u)
+	}
+
+	ssd := drives["/dev/sdd"]
+	if ssd.Path != "/media/alex/T7" || ssd.FileSystem != "exfat" {
+		t.Errorf("sdd mounted at %q as %q", ssd.Path, ssd.FileSystem)
+	}
+	if u := ssd.USB; u == nil || u.Driver != "uas" || u.Bridge != "JMicron JMS578" || u.SpeedName() != "USB 3.2 Gen 2 (10 Gbps)" {
+		t.Errorf("sdd USB = %+v", u)
+	}
+
+	for dev, d := range drives {
+		if d.Fingerprint == "" {
+			t.Errorf("%s has no fingerprint", dev)
+		}
+	}
+}
+
+func TestScanBlockDevicesStacked(t *testing.T) {
+	drives := scanDrives(t, luksLVMHost(t))
+
+	sda := drives["/dev/sda"]
+	if !slices.Contains(sda.MountPoints, "/") || !slices.Contains(sda.MountPoints, "/boot") || sda.Path != "" {
+		t.Errorf("sda mount points %q, path %q", sda.MountPoints, sda.Path)
+	}
+	holders := sda.Partitions[1].Holders
+	if len(holders) != 1 || holders[0].Kind != NodeCrypt || holders[0].Label != "luks-3f2a" || len(holders[0].Holders) != 2 {
+		t.Fatalf("sda2 holders = %+v", holders)
+	}
+	if root := holders[0].Holders[0]; root.Kind != NodeLVM || root.Label != "vg0-root" || !reflect.DeepEqual(root.MountPoints, []string{"/"}) {
+		t.Errorf("dm-1 = %+v", root)
+	}
+
+	// The array's filesystem is browsable from either member disk.
+	for _, dev := range []string{"/dev/sdb", "/dev/sdc"} {
+		d := drives[dev]
+		if d.Path != "/srv/data" || d.FileSystem != "xfs" {
+			t.Errorf("%s mounted at %q as %q", dev, d.Path, d.FileSystem)
+		}
+		h := d.Partitions[0].Holders
+		if len(h) != 1 || h[0].Kind != NodeRAID || h[0].Label != "raid1" || h[0].FileSystem != "LVM2_member" {
+			t.Fatalf("%s1 holders = %+v", dev, h)
+		}
+		if len(h[0].Holders) != 1 || h[0].Holders[0].Label != "data-vol" {
+			t.Errorf("md0 holders = %+v", h[0].Holders)
+		}
+	}
+}
+
+func TestScanBlockDevicesBtrfsSubvolumes(t *testing.T) {
+	h := newFakeHost(t, "btrfs.txt")
+	h.disk(nvmePort, "nvme0n1", "259:0", 1000215216, nil)
+	h.partition("nvme0n1", "nvme0n1p1", "259:1", 1, 2048, 1050624)
+	h.partition("nvme0n1", "nvme0n1p2", "259:2", 2, 1052672, 999161856)
+	h.disk(ataPort, "sda", "8:0", 7814037168, nil)
+	h.partition("sda", "sda1", "8:1", 1, 2048, 7814035087)
+
+	drives := scanDrives(t, h)
+
+	// btrfs reports anonymous device numbers, so mounts are matched by
+	// their source.
+	if got := drives["/dev/nvme0n1"].MountPoints; !reflect.DeepEqual(got, []string{"/", "/home"}) {
+		t.Errorf("nvme0n1 mount points = %q", got)
+	}
+	sda := drives["/dev/sda"]
+	if !reflect.DeepEqual(sda.MountPoints, []string{"/data", "/mnt/pool"}) {
+		t.Errorf("sda mount points = %q", sda.MountPoints)
+	}
+	// A subvolume is not the whole filesystem, so the top level is browsed.
+	if sda.Path != "/mnt/pool" || sda.FileSystem != "btrfs" {
+		t.Errorf("sda mounted at %q as %q", sda.Path, sda.FileSystem)
+	}
+	if sda.Name != "pool" {
+		t.Errorf("sda without a model is named %q", sda.Name)
+	}
+}
+
+func TestUnescapeMountField(t *testing.T) {
+	tests := map[string]string{
+		`/media/alex/My\040Stick`: "/media/alex/My Stick",
+		`/mnt/tab\011here`:        "/mnt/tab\there",
+		`/mnt/back\134slash`:      `/mnt/back\slash`,
+		`/mnt/plain`:              "/mnt/plain",
+		`/mnt/short\04`:           `/mnt/short\04`,
+	}
+	for in, want := range tests {
+		if got := unescapeMountField(in); got != want {
+			t.Errorf("unescapeMountField(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestReadMountInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mountinfo")
+	lines := []string{
+		// No optional fields, no superblock options
+		"28 1 259:2 / / rw - ext4 /dev/nvme0n1p2",
+		// Several optional fields, escaped paths and shared options
+		`91 28 8:33 / /media/alex/My\040Stick rw,nosuid,nodev shared:48 master:3 propagate_from:2 - vfat /dev/sdc1 rw,fmask=0022,nosuid`,
+		// A bind mount of a subtree
+		"95 28 8:1 /backups/2026 /srv/backups ro,relatime - ntfs3 /dev/sda1 rw,uid=1000",
+		// Malformed: no separator, nothing after it, too short
+		"97 28 8:17 / /mnt/broken rw shared:9 ext4 /dev/sdb1 rw",
+		"98 28 8:18 / /mnt/cut rw -",
+		"99 28 8:19",
+		"",
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mounts, err := readMountInfo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []mountEntry{
+		{devID: "259:2", root: "/", point: "/", fsType: "ext4", source: "/dev/nvme0n1p2", options: []string{"rw"}},
+		{devID: "8:33", root: "/", point: "/media/alex/My Stick", fsType: "vfat", source: "/dev/sdc1", options: []string{"rw", "nosuid", "nodev", "fmask=0022"}},
+		{devID: "8:1", root: "/backups/2026", point: "/srv/backups", fsType: "ntfs3", source: "/dev/sda1", options: []string{"ro", "relatime", "rw", "uid=1000"}},
+	}
+	if !reflect.DeepEqual(mounts, want) {
+		t.Errorf("readMountInfo =\n%+v\nwant\n%+v", mounts, want)
+	}
+
+	if _, err := readMountInfo(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("read a missing mount table")
+	}
+}
+
+func TestMountsOf(t *testing.T) {
+	mounts := []mountEntry{
+		{devID: "8:33", point: "/media/stick", source: "/dev/sdc1"},
+		{devID: "8:33", point: "/mnt/again", source: "/dev/disk/by-uuid/1234"},
+		// btrfs mounts carry an anonymous device number
+		{devID: "0:45", point: "/mnt/pool", source: "/dev/sdb"},
+		{devID: "8:1", point: "/data", source: "/dev/sda1"},
+	}
+	if got := mountPoints(mountsOf(mounts, "8:33", "/dev/sdc1")); !slices.Equal(got, []string{"/media/stick", "/mnt/again"}) {
+		t.Errorf("sdc1 mounted at %q", got)
+	}
+	if got := mountPoints(mountsOf(mounts, "8:16", "/dev/sdb")); !slices.Equal(got, []string{"/mnt/pool"}) {
+		t.Errorf("sdb mounted at %q", got)
+	}
+	if got := mountsOf(mounts, "8:48", "/dev/sdd"); len(got) != 0 || fsTypeOf(got) != "" {
+		t.Errorf("sdd mounted at %+v", got)
+	}
+}
+
+func TestTransportOf(t *testing.T) {
+	tests := []struct {
+		link, name, bus, want string
+	}{
+		{"../devices/" + ataPort + "/block/sda", "sda", "", "sata"},
+		{"../devices/" + nvmePort + "/nvme0n1", "nvme0n1", "", "nvme"},
+		{"../devices/" + usbStick + "/2-1:1.0/host6/target6:0:0/6:0:0:0/block/sdc", "sdc", "usb", "usb"},
+		{"../devices/platform/fe320000.mmc/mmc_host/mmc0/mmc0:aaaa/block/mmcblk0", "mmcblk0", "", "mmc"},
+		{"../devices/pci0000:00/0000:00:04.0/virtio1/block/vda", "vda", "", "virtio"},
+		// Names and udev fill in what the link does not show
+		{"", "nvme1n1", "", "nvme"},
+		{"../devices/pci0000:00/0000:00:1f.2/host0/target0:0:0/0:0:0:0/block/sdb", "sdb", "ata", "sata"},
+		{"../devices/pci0000:00/0000:00:1f.2/host0/target0:0:0/0:0:0:0/block/sdb", "sdb", "scsi", "scsi"},
+		{"", "loop0", "", ""},
+	}
+	for _, tt := range tests {
+		if got := transportOf(tt.link, tt.name, tt.bus); got != tt.want {
+			t.Errorf("transportOf(%q, %q, %q) = %q, want %q", tt.link, tt.name, tt.bus, got, tt.want)
+		}
+	}
+}
+
+func TestSystemMountsAndVendors(t *testing.T) {
+	for point, want := range map[string]bool{
+		"/":              true,
+		"/boot":          true,
+		"/boot/efi":      true,
+		"/bootstrap":     false,
+		"/home":          false,
+		"/media/alex/T7": false,
+	} {
+		if got := isSystemMount(point); got != want {
+			t.Errorf("isSystemMount(%q) = %v", point, got)
+		}
+	}
+	for vendor, want := range map[string]string{"ATA": "ATA", "SanDisk": "SanDisk", "0x1af4": "", "": ""} {
+		if got := vendorName(vendor); got != want {
+			t.Errorf("vendorName(%q) = %q, want %q", vendor, got, want)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package drivers
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kinds of block device in a Topology.
+const (
+	NodeDisk      = "disk"
+	NodePartition = "part"
+	NodeCrypt     = "crypt" // dm-crypt / LUKS mapping
+	NodeLVM       = "lvm"   // LVM logical volume
+	NodeDM        = "dm"    // any other device-mapper target
+	NodeRAID      = "raid"  // md array
+	NodeLoop      = "loop"
+)
+
+// TopologyNode is one block device in a Topology.
+type TopologyNode struct {
+	Name   string // kernel name: sda, sda2, dm-0, md127, loop3
+	Device string
+	Kind   string
+	// Label is the device-mapper name for dm devices (the /dev/mapper
+	// entry) and the RAID level for md arrays.
+	Label      string
+	Size       int64
+	FileSystem string
+	// MountPoints are the mounts of this device itself, not of what is
+	// stacked on it.
+	MountPoints []string
+	// Swap is set while the device is in use as swap space.
+	Swap bool
+	// BackingFile is the file a loop device reads from.
+	BackingFile string
+	// Parent is the disk a partition belongs to.
+	Parent     string
+	Partitions []string
+	// Holders are the devices built on this one. Besides the kernel's
+	// holders this includes loop devices whose backing file lives on this
+	// device's filesystem, since they pin it just the same.
+	Holders []string
+	// Slaves are the devices this one is built on; the inverse of Holders.
+	Slaves []string
+
+	mounts []mountEntry
+}
+
+// Topology is the graph of block devices and how they stack: disks carry
+// partitions, partitions carry md arrays, device-mapper targets (LVM and
+// dm-crypt) and filesystems, in any combination and depth.
+type Topology struct {
+	Nodes map[string]*TopologyNode
+}
+
+// Holder is a device stacked on a disk or partition, with whatever is in
+// turn stacked on it.
+type Holder struct {
+	Name        string
+	Device      string
+	Kind        string
+	Label       string
+	FileSystem  string
+	MountPoints []string
+	Holders     []Holder
+}
+
+// ScanTopology reads the block device graph of the running system from the
+// holders and slaves links under roots.Sys/class/block.
+func ScanTopology(roots Roots) (*Topology, error) {
+	mounts, err := readMountInfo(filepath.Join(roots.Proc, "self", "mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+	return scanTopology(roots, mounts)
+}
+
+func scanTopology(roots Roots, mounts []mountEntry) (*Topology, error) {
+	classDir := filepath.Join(roots.Sys, "class", "block")
+	entries, err := os.ReadDir(classDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block devices: %v", err)
+	}
+	swaps := readSwaps(filepath.Join(roots.Proc, "swaps"))
+
+	t := &Topology{Nodes: make(map[string]*TopologyNode)}
+	for _, e := range entries {
+		name := e.Name()
+		dir := filepath.Join(classDir, name)
+
+		// Unattached loop devices and empty card readers have no size and
+		// nothing stacked on them.
+		size := readSysInt(dir, "size") * sectorSize
+		if size == 0 {
+			continue
+		}
+
+		devID := readSysString(dir, "dev")
+		n := &TopologyNode{
+			Name:    name,
+			Device:  "/dev/" + name,
+			Kind:    nodeKind(dir, name),
+			Size:    size,
+			Holders: listDir(filepath.Join(dir, "holders")),
+			Slaves:  listDir(filepath.Join(dir, "slaves")),
+			Swap:    swaps["/dev/"+name],
+		}
+		switch n.Kind {
+		case NodePartition:
+			// Partitions sit inside their disk's directory in the device tree.
+			link, _ := os.Readlink(dir)
+			n.Parent = filepath.Base(filepath.Dir(link))
+		case NodeCrypt, NodeLVM, NodeDM:
+			n.Label = readSysString(dir, "dm/name")
+			n.Swap = n.Swap || swaps["/dev/mapper/"+n.Label]
+		case NodeRAID:
+			n.Label = readSysString(dir, "md/level")
+		case NodeLoop:
+			n.BackingFile = strings.TrimSuffix(readSysString(dir, "loop/backing_file"), " (deleted)")
+		}
+		n.mounts = mountsOf(mounts, devID, n.Device)
+		n.MountPoints = mountPoints(n.mounts)
+		n.FileSystem = firstNonEmpty(fsTypeOf(n.mounts), readUdevProperties(roots, devID)["ID_FS_TYPE"])
+		t.Nodes[name] = n
+	}
+
+	for _, n := range t.Nodes {
+		if p := t.Nodes[n.Parent]; p != nil {
+			p.Partitions = append(p.Partitions, n.Name)
+		}
+		if n.BackingFile != "" {
+			if host := t.hostOf(n.BackingFile); host != nil {
+				host.Holders = append(host.Holders, n.Name)
+				n.Slaves = append(n.Slaves, host.Name)
+			}
+		}
+	}
+	for _, n := range t.Nodes {
+		sort.Strings(n.Partitions)
+		sort.Strings(n.Holders)
+		sort.Strings(n.Slaves)
+	}
+	return t, nil
+}
+
+// hostOf returns the device whose filesystem holds path: the one with the
+// longest mount point containing it.
+func (t *Topology) hostOf(path string) *TopologyNode {
+	var host *TopologyNode
+	best := -1
+	for _, n := range t.Nodes {
+		for _, m := range n.MountPoints {
+			if len(m) > best && (path == m || strings.HasPrefix(path, strings.TrimSuffix(m, "/")+"/")) {
+				host, best = n, len(m)
+			}
+		}
+	}
+	return host
+}
+
+// Above returns every device built on name, directly or not, including a
+// disk's partitions. Nearer devices come first.
+func (t *Topology) Above(name string) []*TopologyNode {
+	return t.walk(name, func(n *TopologyNode) []string {
+		return append(append([]string(nil), n.Partitions...), n.Holders...)
+	})
+}
+
+// Below returns every device name is built on, down to the physical disks.
+func (t *Topology) Below(name string) []*TopologyNode {
+	return t.walk(name, func(n *TopologyNode) []string {
+		if n.Parent == "" {
+			return n.Slaves
+		}
+		return append([]string{n.Parent}, n.Slaves...)
+	})
+}
+
+// Disks returns the names of the physical disks name lives on.
+func (t *Topology) Disks(name string) []string {
+	var disks []string
+	for _, n := range append([]*TopologyNode{t.Nodes[name]}, t.Below(name)...) {
+		if n != nil && n.Kind == NodeDisk {
+			disks = append(disks, n.Name)
+		}
+	}
+	sort.Strings(disks)
+	return disks
+}
+
+func (t *Topology) walk(name string, next func(*TopologyNode) []string) []*TopologyNode {
+	var found []*TopologyNode
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		n := t.Nodes[queue[0]]
+		queue = queue[1:]
+		if n == nil {
+			continue
+		}
+		for _, m := range next(n) {
+			if seen[m] || t.Nodes[m] == nil {
+				continue
+			}
+			seen[m] = true
+			found = append(found, t.Nodes[m])
+			queue = append(queue, m)
+		}
+	}
+	return found
+}
+
+// holderTree returns what is stacked on name as a tree.
+func (t *Topology) holderTree(name string) []Holder {
+	n := t.Nodes[name]
+	if n == nil {
+		return nil
+	}
+	var holders []Holder
+	for _, h := range n.Holders {
+		hn := t.Nodes[h]
+		if hn == nil {
+			continue
+		}
+		holders = append(holders, Holder{
+			Name:        hn.Name,
+			Device:      hn.Device,
+			Kind:        hn.Kind,
+			Label:       hn.Label,
+			FileSystem:  hn.FileSystem,
+			MountPoints: hn.MountPoints,
+			Holders:     t.holderTree(h),
+		})
+	}
+	return holders
+}
+
+// nodeKind classifies the block device whose sysfs directory is dir.
+func nodeKind(dir, name string) string {
+	if _, err := os.Stat(filepath.Join(dir, "partition")); err == nil {
+		return NodePartition
+	}
+	switch {
+	case strings.HasPrefix(name, "dm-"):
+		// The uuid prefix names the subsystem that created the mapping.
+		uuid := readSysString(dir, "dm/uuid")
+		switch {
+		case strings.HasPrefix(uuid, "CRYPT-"):
+			return NodeCrypt
+		case strings.HasPrefix(uuid, "LVM-"):
+			return NodeLVM
+		}
+		return NodeDM
+	case strings.HasPrefix(name, "md"):
+		return NodeRAID
+	case strings.HasPrefix(name, "loop"):
+		return NodeLoop
+	}
+	return NodeDisk
+}
+
+// readSwaps returns the devices listed in /proc/swaps.
+func readSwaps(path string) map[string]bool {
+	swaps := make(map[string]bool)
+	file, err := os.Open(path)
+	if err != nil {
+		return swaps
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			swaps[unescapeMountField(fields[0])] = true
+		}
+	}
+	return swaps
+}
+
+func listDir(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
@@ -0,0 +1,74 @@
+package drivers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func nodeNames(nodes []*TopologyNode) []string {
+	var names []string
+	for _, n := range nodes {
+		names = append(names, n.Name)
+	}
+	return names
+}
+
+func TestTopologyWalks(t *testing.T) {
+	topology, err := ScanTopology(luksLVMHost(t).roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		above, below []string
+		disks        []string
+	}{
+		{"sda", []string{"sda1", "sda2", "dm-0", "dm-1", "dm-2"}, nil, []string{"sda"}},
+		{"dm-1", nil, []string{"dm-0", "sda2", "sda"}, []string{"sda"}},
+		{"sdb", []string{"sdb1", "md0", "dm-3"}, nil, []string{"sdb"}},
+		// The array's volume lives on both members
+		{"dm-3", nil, []string{"md0", "sdb1", "sdc1", "sdb", "sdc"}, []string{"sdb", "sdc"}},
+		{"sdx", nil, nil, nil},
+	}
+	for _, tt := range tests {
+		if got := nodeNames(topology.Above(tt.name)); !reflect.DeepEqual(got, tt.above) {
+			t.Errorf("Above(%s) = %q, want %q", tt.name, got, tt.above)
+		}
+		if got := nodeNames(topology.Below(tt.name)); !reflect.DeepEqual(got, tt.below) {
+			t.Errorf("Below(%s) = %q, want %q", tt.name, got, tt.below)
+		}
+		if got := topology.Disks(tt.name); !reflect.DeepEqual(got, tt.disks) {
+			t.Errorf("Disks(%s) = %q, want %q", tt.name, got, tt.disks)
+		}
+	}
+
+	if n := topology.Nodes["dm-2"]; n == nil || !n.Swap {
+		t.Errorf("dm-2 = %+v, want swap", n)
+	}
+	if n := topology.hostOf("/srv/data/backups/2026"); n == nil || n.Name != "dm-3" {
+		t.Errorf("hostOf(/srv/data/backups/2026) = %+v", n)
+	}
+	if n := topology.hostOf("/srv/database"); n == nil || n.Name != "dm-1" {
+		t.Errorf("hostOf(/srv/database) = %+v", n)
+	}
+}
+
+func TestReadSwaps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swaps")
+	data := "Filename\t\t\t\tType\t\tSize\t\tUsed\t\tPriority\n" +
+		"/dev/dm-2                               partition\t8388604\t\t0\t\t-2\n" +
+		"/var/swap\\040file                       file\t\t1048572\t\t0\t\t-3\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"/dev/dm-2": true, "/var/swap file": true}
+	if got := readSwaps(path); !reflect.DeepEqual(got, want) {
+		t.Errorf("readSwaps = %v, want %v", got, want)
+	}
+	if got := readSwaps(filepath.Join(t.TempDir(), "missing")); len(got) != 0 {
+		t.Errorf("missing file gave %v", got)
+	}
+}
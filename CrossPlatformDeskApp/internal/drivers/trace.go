@@ -0,0 +1,104 @@
+package drivers
+
+import "strings"
+
+// Engines a Trace can name besides external tools such as shred.
+const (
+	// EngineOverwrite is the built-in multi-pass file overwrite.
+	EngineOverwrite = "overwrite"
+	// EngineUnlink is a plain delete that leaves the data on the medium.
+	EngineUnlink = "unlink"
+	// EngineFreeSpace fills a filesystem's free space with random data.
+	EngineFreeSpace = "free_space_fill"
+)
+
+// Trace records what a wipe actually did, so its certificate states what
+// happened rather than what was intended. A nil *Trace records nothing.
+type Trace struct {
+	// Engines lists what removed the data, in the order first used.
+	Engines []string `json:"engines"`
+	// Passes are summed over all files, per engine and pass number.
+	Passes  []Pass       `json:"passes,omitempty"`
+	Files   int          `json:"files"`
+	Dirs    int          `json:"dirs"`
+	Skipped int          `json:"skipped"`
+	Errors  []TraceError `json:"errors,omitempty"`
+}
+
+// Pass is one overwrite pass of an engine.
+type Pass struct {
+	Engine  string `json:"engine"`
+	Number  int    `json:"number"`
+	Pattern string `json:"pattern"`
+	Bytes   int64  `json:"bytes_written"`
+	// Estimated is set when Bytes is the size of the files an external
+	// tool was given rather than a count of what was written.
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// TraceError is one failure, with the full message and any tool output.
+type TraceError struct {
+	Path  string `json:"path,omitempty"`
+	Error string `json:"error"`
+}
+
+// Method names the engines for a certificate, e.g. "shred+overwrite".
+func (t *Trace) Method() string {
+	if t == nil || len(t.Engines) == 0 {
+		return "none"
+	}
+	return strings.Join(t.Engines, "+")
+}
+
+func (t *Trace) useEngine(name string) {
+	if t == nil {
+		return
+	}
+	for _, e := range t.Engines {
+		if e == name {
+			return
+		}
+	}
+	t.Engines = append(t.Engines, name)
+}
+
+func (t *Trace) addPass(engine string, number int, pattern string, n int64, estimated bool) {
+	if t == nil {
+		return
+	}
+	t.useEngine(engine)
+	for i := range t.Passes {
+		p := &t.Passes[i]
+		if p.Engine == engine && p.Number == number {
+			p.Bytes += n
+			p.Estimated = p.Estimated || estimated
+			return
+		}
+	}
+	t.Passes = append(t.Passes, Pass{Engine: engine, Number: number, Pattern: pattern, Bytes: n, Estimated: estimated})
+}
+
+func (t *Trace) fail(path string, err error) {
+	if t == nil || err == nil {
+		return
+	}
+	t.Errors = append(t.Errors, TraceError{Path: path, Error: err.Error()})
+}
+
+func (t *Trace) countFile() {
+	if t != nil {
+		t.Files++
+	}
+}
+
+func (t *Trace) countDir() {
+	if t != nil {
+		t.Dirs++
+	}
+}
+
+func (t *Trace) countSkipped() {
+	if t != nil {
+		t.Skipped++
+	}
+}
@@ -0,0 +1,120 @@
+package drivers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// tree lays out files with the given sizes below a temporary directory.
+func tree(t *testing.T, files map[string]int) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPurgeTraceOverwrite(t *testing.T) {
+	stubCommands(t)
+	dir := tree(t, map[string]int{"a.txt": 100, "sub/b.bin": 4000})
+	if err := os.Symlink("a.txt", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	var trace Trace
+	if err := PurgeItemTraced(dir, &trace); err != nil {
+		t.Fatal(err)
+	}
+	want := Trace{
+		Engines: []string{EngineOverwrite},
+		Passes: []Pass{
+			{Engine: EngineOverwrite, Number: 1, Pattern: "zeros", Bytes: 4100},
+			{Engine: EngineOverwrite, Number: 2, Pattern: "ones", Bytes: 4100},
+			{Engine: EngineOverwrite, Number: 3, Pattern: "random", Bytes: 4100},
+		},
+		Files:   2,
+		Dirs:    2,
+		Skipped: 1,
+	}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("trace = %+v\nwant %+v", trace, want)
+	}
+	if trace.Method() != "overwrite" {
+		t.Errorf("Method = %q", trace.Method())
+	}
+}
+
+func TestPurgeTraceTool(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("shred is the Linux tool")
+	}
+	f := stubCommands(t)
+	dir := tree(t, map[string]int{"a.txt": 100, "b.txt": 50})
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	f.outputs["shred -vfz -n 3 -u "+a] = fakeOutput{}
+	f.outputs["shred -vfz -n 3 -u "+b] = fakeOutput{[]byte("shred: b.txt: failed to open for writing: Permission denied\n"), errors.New("exit status 1")}
+
+	var trace Trace
+	// shred is faked, so a.txt is still there afterwards
+	if err := PurgeItemTraced(a, &trace); err != nil {
+		t.Fatal(err)
+	}
+	if err := PurgeItemTraced(b, &trace); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"shred", EngineOverwrite}; !reflect.DeepEqual(trace.Engines, want) {
+		t.Errorf("Engines = %v, want %v", trace.Engines, want)
+	}
+	if p := trace.Passes[0]; p != (Pass{Engine: "shred", Number: 1, Pattern: "random", Bytes: 100, Estimated: true}) {
+		t.Errorf("first pass = %+v", p)
+	}
+	if len(trace.Passes) != 4+3 || trace.Files != 2 {
+		t.Errorf("%d passes over %d files, want 7 over 2", len(trace.Passes), trace.Files)
+	}
+	if len(trace.Errors) != 1 || trace.Errors[0].Path != b || !strings.Contains(trace.Errors[0].Error, "Permission denied") {
+		t.Errorf("Errors = %+v, want shred's output for %s", trace.Errors, b)
+	}
+}
+
+func TestClearTrace(t *testing.T) {
+	dir := tree(t, map[string]int{"a": 1, "b/c": 1, "b/d/e": 1})
+
+	var trace Trace
+	if err := ClearItemTraced(dir, &trace); err != nil {
+		t.Fatal(err)
+	}
+	if trace.Method() != EngineUnlink || trace.Files != 3 || trace.Dirs != 3 || len(trace.Passes) != 0 {
+		t.Errorf("trace = %+v", trace)
+	}
+
+	missing := filepath.Join(dir, "gone")
+	if err := ClearItemTraced(missing, &trace); err == nil {
+		t.Fatal("clearing a missing path succeeded")
+	}
+	if len(trace.Errors) != 1 || trace.Errors[0].Path != missing {
+		t.Errorf("Errors = %+v", trace.Errors)
+	}
+}
+
+func TestTraceNil(t *testing.T) {
+	var trace *Trace
+	trace.addPass(EngineOverwrite, 1, "zeros", 1, false)
+	trace.fail("x", errors.New("boom"))
+	trace.countFile()
+	if trace.Method() != "none" {
+		t.Errorf("Method = %q", trace.Method())
+	}
+}
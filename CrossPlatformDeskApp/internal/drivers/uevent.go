@@ -0,0 +1,27 @@
+package drivers
+
+import "strings"
+
+// ueventSource yields kernel uevents as property maps (ACTION, DEVPATH,
+// SUBSYSTEM, DEVNAME, DEVTYPE, ...).
+type ueventSource interface {
+	Receive() (map[string]string, error)
+	Close() error
+}
+
+// parseUevent decodes a kernel uevent datagram: an "action@devpath" header
+// followed by NUL-separated KEY=VALUE pairs. Messages relayed by udev, which
+// start with "libudev", are not kernel uevents and yield nil.
+func parseUevent(msg []byte) map[string]string {
+	parts := strings.Split(string(msg), "\x00")
+	if len(parts) == 0 || !strings.Contains(parts[0], "@") {
+		return nil
+	}
+	props := make(map[string]string)
+	for _, p := range parts[1:] {
+		if key, value, ok := strings.Cut(p, "="); ok {
+			props[key] = value
+		}
+	}
+	return props
+}
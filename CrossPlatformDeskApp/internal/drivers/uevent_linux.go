@@ -0,0 +1,48 @@
+package drivers
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// netlinkUevents reads kernel uevents from a NETLINK_KOBJECT_UEVENT socket.
+type netlinkUevents struct {
+	file *os.File
+	buf  []byte
+}
+
+func openUevents() (ueventSource, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uevent socket: %v", err)
+	}
+	// Group 1 carries the kernel's own broadcasts.
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: 1}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to bind uevent socket: %v", err)
+	}
+	// Non-blocking, so the runtime poller owns the descriptor and Close
+	// wakes a pending Receive.
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return &netlinkUevents{file: os.NewFile(uintptr(fd), "uevent"), buf: make([]byte, 64*1024)}, nil
+}
+
+func (n *netlinkUevents) Receive() (map[string]string, error) {
+	for {
+		size, err := n.file.Read(n.buf)
+		if err != nil {
+			return nil, err
+		}
+		if props := parseUevent(n.buf[:size]); props != nil {
+			return props, nil
+		}
+	}
+}
+
+func (n *netlinkUevents) Close() error {
+	return n.file.Close()
+}
@@ -0,0 +1,85 @@
+package drivers
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// freeSpaceWriteSpeed is the sustained write rate free-space wipe estimates
+// assume, in bytes per second.
+const freeSpaceWriteSpeed = 50 * 1024 * 1024
+
+// Usage is the capacity of a mounted filesystem as statfs reports it.
+type Usage struct {
+	Total int64 `json:"total_bytes"`
+	Used  int64 `json:"used_bytes"`
+	Free  int64 `json:"free_bytes"`
+	// Available is what an unprivileged user can still write; it is less
+	// than Free where the filesystem reserves blocks for root.
+	Available  int64 `json:"available_bytes"`
+	Inodes     int64 `json:"inodes,omitempty"`
+	InodesFree int64 `json:"inodes_free,omitempty"`
+}
+
+// UsedFraction is the share of the filesystem in use, from 0 to 1.
+func (u Usage) UsedFraction() float64 {
+	if u.Total <= 0 {
+		return 0
+	}
+	return float64(u.Used) / float64(u.Total)
+}
+
+// FilesystemInfo describes the filesystem a wipe ran on, for its
+// certificate.
+type FilesystemInfo struct {
+	MountPoint string   `json:"mount_point"`
+	Type       string   `json:"type,omitempty"`
+	Options    []string `json:"options,omitempty"`
+	Usage
+}
+
+// FilesystemUsage returns the capacity of the filesystem holding path.
+func FilesystemUsage(path string) (Usage, error) {
+	if path == "" {
+		return Usage{}, errors.New("path cannot be empty")
+	}
+	return statfs(path)
+}
+
+// FilesystemFor describes the filesystem holding path: where it is
+// mounted, its type and options where the platform reports them, and its
+// current usage.
+func FilesystemFor(path string) (FilesystemInfo, error) {
+	usage, err := FilesystemUsage(path)
+	if err != nil {
+		return FilesystemInfo{}, err
+	}
+	info := FilesystemInfo{MountPoint: path, Usage: usage}
+
+	// mountinfo only exists on Linux; elsewhere the path stands in for the
+	// mount point.
+	mounts, err := readMountInfo(filepath.Join(HostRoots.Proc, "self", "mountinfo"))
+	if err != nil {
+		return info, nil
+	}
+	abs, _ := filepath.Abs(path)
+	best := -1
+	for _, m := range mounts {
+		if len(m.point) > best && (abs == m.point || strings.HasPrefix(abs, strings.TrimSuffix(m.point, "/")+"/")) {
+			info.MountPoint, info.Type, info.Options = m.point, m.fsType, m.options
+			best = len(m.point)
+		}
+	}
+	return info, nil
+}
+
+// EstimateFreeSpaceWipe returns how many bytes a free-space wipe of the
+// filesystem holding dir will write and roughly how many seconds it takes.
+func EstimateFreeSpaceWipe(dir string) (int64, int, error) {
+	usage, err := FilesystemUsage(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	return usage.Available, max(1, int(usage.Available/freeSpaceWriteSpeed)), nil
+}
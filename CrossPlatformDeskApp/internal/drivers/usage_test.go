@@ -0,0 +1,97 @@
+package drivers
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestUsedFraction(t *testing.T) {
+	tests := []struct {
+		usage Usage
+		want  float64
+	}{
+		{Usage{Total: 1000, Used: 250}, 0.25},
+		{Usage{Total: 1000, Used: 1000}, 1},
+		{Usage{Total: 0, Used: 10}, 0},
+		{Usage{Total: -1, Used: 10}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.usage.UsedFraction(); got != tt.want {
+			t.Errorf("%+v.UsedFraction() = %v, want %v", tt.usage, got, tt.want)
+		}
+	}
+}
+
+func TestFilesystemFor(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("mount points are read from mountinfo")
+	}
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data")
+	if err := os.MkdirAll(filepath.Join(data, "deep"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	proc := t.TempDir()
+	mountinfo := strings.Join([]string{
+		"28 1 259:2 / / rw,relatime shared:1 - ext4 /dev/nvme0n1p2 rw,errors=remount-ro",
+		"40 28 8:33 / " + data + " rw,noatime shared:20 - xfs /dev/sdc1 rw,noquota",
+		// A sibling sharing the prefix must not be taken for a parent
+		"41 28 8:34 / " + data + "2 rw shared:21 - vfat /dev/sdc2 rw",
+	}, "\n") + "\n"
+	if err := os.MkdirAll(filepath.Join(proc, "self"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(proc, "self", "mountinfo"), []byte(mountinfo), 0600); err != nil {
+		t.Fatal(err)
+	}
+	saved := HostRoots
+	HostRoots.Proc = proc
+	t.Cleanup(func() { HostRoots = saved })
+
+	info, err := FilesystemFor(filepath.Join(data, "deep"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MountPoint != data || info.Type != "xfs" || strings.Join(info.Options, ",") != "rw,noatime,noquota" {
+		t.Errorf("FilesystemFor = %+v", info)
+	}
+	if info.Total <= 0 || info.Available > info.Free || info.Used+info.Free != info.Total {
+		t.Errorf("usage = %+v", info.Usage)
+	}
+
+	if info, err := FilesystemFor(dir); err != nil || info.MountPoint != "/" || info.Type != "ext4" {
+		t.Errorf("FilesystemFor(%s) = %+v, %v", dir, info, err)
+	}
+	if _, err := FilesystemFor(filepath.Join(dir, "missing")); err == nil {
+		t.Error("no error for a missing path")
+	}
+	if _, err := FilesystemFor(""); err == nil {
+		t.Error("no error for an empty path")
+	}
+}
+
+func TestEstimateFreeSpaceWipe(t *testing.T) {
+	dir := t.TempDir()
+	usage, err := FilesystemUsage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bytes, seconds, err := EstimateFreeSpaceWipe(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only what the user may write is filled; the root reserve is not
+	if bytes > usage.Free || bytes <= 0 {
+		t.Errorf("estimate %d bytes with %+v", bytes, usage)
+	}
+	if want := max(1, int(bytes/freeSpaceWriteSpeed)); seconds != want {
+		t.Errorf("estimate %d seconds, want %d", seconds, want)
+	}
+	if _, _, err := EstimateFreeSpaceWipe(filepath.Join(dir, "missing")); err == nil {
+		t.Error("no error for a missing directory")
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package drivers
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func statfs(path string) (Usage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return Usage{}, fmt.Errorf("failed to stat filesystem of %s: %v", path, err)
+	}
+	bsize := int64(st.Bsize)
+	u := Usage{
+		Total:      int64(st.Blocks) * bsize,
+		Free:       int64(st.Bfree) * bsize,
+		Available:  int64(st.Bavail) * bsize,
+		Inodes:     int64(st.Files),
+		InodesFree: int64(st.Ffree),
+	}
+	u.Used = u.Total - u.Free
+	return u, nil
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+package drivers
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// statfs reads the volume's capacity; NTFS does not report inode counts.
+func statfs(path string) (Usage, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return Usage{}, err
+	}
+	var available, total, free uint64
+	r, _, callErr := getDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(p)),
+		uintptr(unsafe.Pointer(&available)),
+		uintptr(unsafe.Pointer(&total)),
+		uintptr(unsafe.Pointer(&free)),
+	)
+	if r == 0 {
+		return Usage{}, fmt.Errorf("failed to stat volume of %s: %v", path, callErr)
+	}
+	return Usage{
+		Total:     int64(total),
+		Used:      int64(total - free),
+		Free:      int64(free),
+		Available: int64(available),
+	}, nil
+}
@@ -0,0 +1,128 @@
+package drivers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// USBInfo identifies the USB device a drive is attached through: the stick
+// itself, or the bridge of an enclosure or adapter.
+type USBInfo struct {
+	VendorID     string `json:"vendor_id"`
+	ProductID    string `json:"product_id"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Product      string `json:"product,omitempty"`
+	Serial       string `json:"serial,omitempty"`
+	// SpeedMbps is the negotiated link speed: 12, 480, 5000, 10000 or 20000.
+	SpeedMbps int    `json:"speed_mbps,omitempty"`
+	Version   string `json:"usb_version,omitempty"`
+	// Driver is the kernel driver bound to the storage interface: uas, or
+	// usb-storage for the older bulk-only transport.
+	Driver string `json:"driver,omitempty"`
+	// Bridge names the USB-to-SATA/NVMe bridge or flash controller chip,
+	// when the vendor and product IDs are known.
+	Bridge string `json:"bridge,omitempty"`
+}
+
+// ID is the vendor:product pair the way lsusb prints it.
+func (u USBInfo) ID() string {
+	return u.VendorID + ":" + u.ProductID
+}
+
+// SpeedName describes the link speed in USB marketing terms.
+func (u USBInfo) SpeedName() string {
+	switch {
+	case u.SpeedMbps >= 20000:
+		return "USB 3.2 Gen 2x2 (20 Gbps)"
+	case u.SpeedMbps >= 10000:
+		return "USB 3.2 Gen 2 (10 Gbps)"
+	case u.SpeedMbps >= 5000:
+		return "USB 3.2 Gen 1 (5 Gbps)"
+	case u.SpeedMbps >= 480:
+		return "USB 2.0 High Speed (480 Mbps)"
+	case u.SpeedMbps > 0:
+		return fmt.Sprintf("USB 1.x (%d Mbps)", u.SpeedMbps)
+	}
+	return "unknown speed"
+}
+
+// usbBridges names common bridge and controller chips by vendor:product.
+var usbBridges = map[string]string{
+	"152d:0567": "JMicron JMS567",
+	"152d:0578": "JMicron JMS578",
+	"152d:0580": "JMicron JMS580",
+	"152d:0583": "JMicron JMS583 (NVMe)",
+	"174c:1153": "ASMedia ASM1153",
+	"174c:2362": "ASMedia ASM2362 (NVMe)",
+	"174c:55aa": "ASMedia ASM1051/1053/1153",
+	"0bda:9210": "Realtek RTL9210 (NVMe)",
+	"2109:0711": "VIA VL711",
+	"090c:1000": "Silicon Motion flash controller",
+	"13fe:4200": "Phison flash controller",
+	"058f:6387": "Alcor Micro flash controller",
+}
+
+// usbVendors names the chip maker when the exact product is not listed.
+var usbVendors = map[string]string{
+	"152d": "JMicron",
+	"174c": "ASMedia",
+	"0bda": "Realtek",
+	"2109": "VIA Labs",
+	"05e3": "Genesys Logic",
+	"067b": "Prolific",
+	"090c": "Silicon Motion",
+	"13fe": "Phison",
+	"058f": "Alcor Micro",
+	"13fd": "Initio",
+}
+
+// readUSBInfo finds the USB device above the block device at dir, or
+// returns nil when the disk is not attached through USB.
+func readUSBInfo(roots Roots, dir string) *USBInfo {
+	sys, err := filepath.EvalSymlinks(roots.Sys)
+	if err != nil {
+		return nil
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(dir, "device"))
+	if err != nil {
+		return nil
+	}
+
+	// The SCSI device hangs below the storage interface (1-2:1.0), which
+	// hangs below the USB device (1-2) carrying the descriptors.
+	var iface string
+	for ; strings.HasPrefix(path, sys) && path != sys; path = filepath.Dir(path) {
+		if _, err := os.Stat(filepath.Join(path, "idVendor")); err == nil {
+			break
+		}
+		if _, err := os.Stat(filepath.Join(path, "bInterfaceClass")); err == nil {
+			iface = path
+		}
+	}
+	vendor := readSysString(path, "idVendor")
+	if vendor == "" {
+		return nil
+	}
+
+	u := &USBInfo{
+		VendorID:     vendor,
+		ProductID:    readSysString(path, "idProduct"),
+		Manufacturer: readSysString(path, "manufacturer"),
+		Product:      readSysString(path, "product"),
+		Serial:       readSysString(path, "serial"),
+		SpeedMbps:    int(readSysInt(path, "speed")),
+		Version:      readSysString(path, "version"),
+	}
+	if iface != "" {
+		if driver, err := os.Readlink(filepath.Join(iface, "driver")); err == nil {
+			u.Driver = filepath.Base(driver)
+		}
+	}
+	u.Bridge = usbBridges[u.ID()]
+	if u.Bridge == "" && usbVendors[u.VendorID] != "" {
+		u.Bridge = fmt.Sprintf("%s (product %s)", usbVendors[u.VendorID], u.ProductID)
+	}
+	return u
+}
@@ -0,0 +1,201 @@
+package drivers
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Kinds of DriveEvent.
+const (
+	DriveAdded   = "add"
+	DriveRemoved = "remove"
+	DriveChanged = "change"
+)
+
+// DefaultWatchInterval is how often the watcher rescans when it has no
+// kernel notifications, and how often it checks for mount changes, which
+// the kernel does not announce as uevents.
+const DefaultWatchInterval = 3 * time.Second
+
+// ueventSettle is how long the watcher waits after a kernel event before
+// rescanning, so the burst of events for one stick and its partitions, and
+// udev's processing of them, collapse into a single scan.
+const ueventSettle = 500 * time.Millisecond
+
+// DriveEvent reports a drive appearing, disappearing or changing (for
+// example being mounted). Drive is the last known state; for removals it is
+// the state before the drive went away.
+type DriveEvent struct {
+	Kind  string
+	Drive Drive
+}
+
+// DriveWatcher tracks the drive list and reports differences as events.
+type DriveWatcher struct {
+	scan     func() ([]Drive, error)
+	interval time.Duration
+	events   chan DriveEvent
+	stop     chan struct{}
+	once     sync.Once
+
+	mu     sync.Mutex
+	drives []Drive
+}
+
+// WatchDrives starts watching the drives GetDrives reports. On Linux it
+// rescans as soon as the kernel announces a block device through a
+// NETLINK_KOBJECT_UEVENT socket; everywhere it also rescans every interval,
+// which is the only source of updates when the socket is unavailable.
+func WatchDrives(interval time.Duration) *DriveWatcher {
+	return newDriveWatcher(GetDrives, openUevents, interval)
+}
+
+func newDriveWatcher(scan func() ([]Drive, error), uevents func() (ueventSource, error), interval time.Duration) *DriveWatcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	w := &DriveWatcher{
+		scan:     scan,
+		interval: interval,
+		events:   make(chan DriveEvent, 32),
+		stop:     make(chan struct{}),
+	}
+	w.drives, _ = scan()
+
+	trigger := make(chan struct{}, 1)
+	if uevents != nil {
+		if src, err := uevents(); err != nil {
+			fmt.Printf("Drive watcher: kernel events unavailable, polling every %s: %v\n", interval, err)
+		} else {
+			go w.listen(src, trigger)
+		}
+	}
+	go w.loop(trigger)
+	return w
+}
+
+// Events delivers drive changes. The channel is closed by Close.
+func (w *DriveWatcher) Events() <-chan DriveEvent {
+	return w.events
+}
+
+// Drives returns the drive list as of the last scan.
+func (w *DriveWatcher) Drives() []Drive {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Drive(nil), w.drives...)
+}
+
+// Close stops watching.
+func (w *DriveWatcher) Close() {
+	w.once.Do(func() { close(w.stop) })
+}
+
+// listen forwards block-device uevents to the scan loop as triggers.
+func (w *DriveWatcher) listen(src ueventSource, trigger chan<- struct{}) {
+	go func() {
+		<-w.stop
+		src.Close()
+	}()
+	for {
+		props, err := src.Receive()
+		if err != nil {
+			select {
+			case <-w.stop:
+			default:
+				fmt.Printf("Drive watcher: kernel events stopped: %v\n", err)
+			}
+			return
+		}
+		if props["SUBSYSTEM"] != "block" {
+			continue
+		}
+		select {
+		case trigger <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *DriveWatcher) loop(trigger <-chan struct{}) {
+	defer close(w.events)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		case <-trigger:
+			select {
+			case <-w.stop:
+				return
+			case <-time.After(ueventSettle):
+			}
+		}
+		if !w.rescan() {
+			return
+		}
+	}
+}
+
+// rescan diffs a fresh scan against the last one and emits the
+// differences. It returns false once the watcher is closed.
+func (w *DriveWatcher) rescan() bool {
+	drives, err := w.scan()
+	if err != nil {
+		return true
+	}
+
+	w.mu.Lock()
+	events := diffDrives(w.drives, drives)
+	w.drives = drives
+	w.mu.Unlock()
+
+	for _, e := range events {
+		select {
+		case w.events <- e:
+		case <-w.stop:
+			return false
+		}
+	}
+	return true
+}
+
+// DriveKey identifies a drive across scans: its device node, or its path
+// where the platform reports none.
+func DriveKey(d Drive) string {
+	if d.Device != "" {
+		return d.Device
+	}
+	return d.Path
+}
+
+func diffDrives(old, current []Drive) []DriveEvent {
+	before := make(map[string]Drive, len(old))
+	for _, d := range old {
+		before[DriveKey(d)] = d
+	}
+
+	var events []DriveEvent
+	seen := make(map[string]bool, len(current))
+	for _, d := range current {
+		key := DriveKey(d)
+		seen[key] = true
+		prev, ok := before[key]
+		switch {
+		case !ok:
+			events = append(events, DriveEvent{Kind: DriveAdded, Drive: d})
+		case !reflect.DeepEqual(prev, d):
+			events = append(events, DriveEvent{Kind: DriveChanged, Drive: d})
+		}
+	}
+	for _, d := range old {
+		if !seen[DriveKey(d)] {
+			events = append(events, DriveEvent{Kind: DriveRemoved, Drive: d})
+		}
+	}
+	return events
+}
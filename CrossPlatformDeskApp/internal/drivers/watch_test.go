@@ -0,0 +1,145 @@
+package drivers
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseUevent(t *testing.T) {
+	msg := []byte("add@/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/host6/target6:0:0/6:0:0:0/block/sdc\x00" +
+		"ACTION=add\x00DEVPATH=/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/host6/target6:0:0/6:0:0:0/block/sdc\x00" +
+		"SUBSYSTEM=block\x00DEVNAME=sdc\x00DEVTYPE=disk\x00SEQNUM=4711\x00")
+	props := parseUevent(msg)
+	if props["ACTION"] != "add" || props["SUBSYSTEM"] != "block" || props["DEVNAME"] != "sdc" || props["SEQNUM"] != "4711" {
+		t.Errorf("parseUevent = %v", props)
+	}
+	// udev's relayed messages start with a binary libudev header
+	if props := parseUevent([]byte("libudev\x00\xfe\xed\xca\xfe")); props != nil {
+		t.Errorf("libudev message parsed as %v", props)
+	}
+	if props := parseUevent(nil); props != nil {
+		t.Errorf("empty message parsed as %v", props)
+	}
+}
+
+func TestDiffDrives(t *testing.T) {
+	stick := Drive{Device: "/dev/sdc", Name: "Ultra Fit"}
+	mounted := stick
+	mounted.MountPoints = []string{"/media/alex/My Stick"}
+	disk := Drive{Device: "/dev/sda", Name: "ST2000DM008"}
+	volume := Drive{Path: "/Volumes/Backup", Name: "Backup"}
+
+	events := diffDrives([]Drive{disk, stick, volume}, []Drive{mounted, disk, {Device: "/dev/sdd"}})
+	want := []DriveEvent{
+		{Kind: DriveChanged, Drive: mounted},
+		{Kind: DriveAdded, Drive: Drive{Device: "/dev/sdd"}},
+		{Kind: DriveRemoved, Drive: volume},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("diffDrives = %+v\nwant %+v", events, want)
+	}
+	if events := diffDrives([]Drive{disk, stick}, []Drive{stick, disk}); len(events) != 0 {
+		t.Errorf("reordered list gave %+v", events)
+	}
+}
+
+// fakeUevents delivers the messages sent on its channel.
+type fakeUevents struct {
+	c      chan map[string]string
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (f *fakeUevents) Receive() (map[string]string, error) {
+	select {
+	case props := <-f.c:
+		return props, nil
+	case <-f.closed:
+		return nil, errors.New("closed")
+	}
+}
+
+func (f *fakeUevents) Close() error {
+	f.once.Do(func() { close(f.closed) })
+	return nil
+}
+
+func TestDriveWatcher(t *testing.T) {
+	var mu sync.Mutex
+	drives := []Drive{{Device: "/dev/sda"}}
+	scan := func() ([]Drive, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]Drive(nil), drives...), nil
+	}
+	src := &fakeUevents{c: make(chan map[string]string), closed: make(chan struct{})}
+	// Only kernel events trigger scans within the test
+	w := newDriveWatcher(scan, func() (ueventSource, error) { return src, nil }, time.Hour)
+
+	mu.Lock()
+	drives = append(drives, Drive{Device: "/dev/sdc"})
+	mu.Unlock()
+	src.c <- map[string]string{"SUBSYSTEM": "usb", "ACTION": "add"}
+	src.c <- map[string]string{"SUBSYSTEM": "block", "ACTION": "add", "DEVNAME": "sdc"}
+	src.c <- map[string]string{"SUBSYSTEM": "block", "ACTION": "add", "DEVNAME": "sdc1"}
+
+	select {
+	case e := <-w.Events():
+		if e.Kind != DriveAdded || e.Drive.Device != "/dev/sdc" {
+			t.Errorf("event %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event for an added drive")
+	}
+	if got := w.Drives(); len(got) != 2 {
+		t.Errorf("Drives = %+v", got)
+	}
+
+	w.Close()
+	w.Close()
+	select {
+	case _, open := <-w.Events():
+		if open {
+			t.Error("event after Close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Events not closed")
+	}
+	select {
+	case <-src.closed:
+	case <-time.After(5 * time.Second):
+		t.Error("uevent source left open")
+	}
+}
+
+func TestDriveWatcherPolls(t *testing.T) {
+	scans := 0
+	var mu sync.Mutex
+	scan := func() ([]Drive, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		scans++
+		if scans == 1 {
+			return nil, nil
+		}
+		if scans == 2 {
+			return nil, errors.New("scan failed")
+		}
+		return []Drive{{Device: "/dev/sdc"}}, nil
+	}
+	w := newDriveWatcher(scan, func() (ueventSource, error) { return nil, errors.New("no netlink") }, 10*time.Millisecond)
+	defer w.Close()
+
+	// A failed scan is skipped rather than reported as every drive gone
+	select {
+	case e := <-w.Events():
+		if e.Kind != DriveAdded || e.Drive.Device != "/dev/sdc" {
+			t.Errorf("event %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("polling found nothing")
+	}
+}
@@ -0,0 +1,513 @@
+// Package ledger keeps an append-only, tamper-evident record of every
+// signed wipe certificate. Each entry holds a certificate and the hash of
+// the entry before it, and the certificate's own signature covers that
+// link, so an entry cannot be removed or altered without breaking the
+// chain or a signature. The entries are also the leaves of a Merkle tree,
+// and a head of the tree is signed with every entry, so the newest entries
+// cannot be removed unnoticed either. Auditors who keep a copy of a signed
+// head can later prove nothing before it has changed, even if the ledger
+// directory, heads and all, is rewritten.
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"data_wiper/internal/certs"
+	"data_wiper/internal/config"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	entriesFile = "entries.jsonl"
+	headsFile   = "heads.jsonl"
+)
+
+// ErrHeadNotKept is returned by Append when the entry was recorded but the
+// tree head signed for it could not be. The entry stays in the ledger, and
+// Verify reports it as not covered by a signed head.
+var ErrHeadNotKept = errors.New("tree head not kept")
+
+// Link places an entry in the ledger. Certificates carry the link of the
+// entry they are recorded in, inside what they sign.
+type Link struct {
+	Index int `json:"index"`
+	// PrevHash is the hex Merkle leaf hash of the previous entry's line,
+	// empty for the first entry.
+	PrevHash string `json:"prev_hash"`
+}
+
+// Entry is one line of the ledger: a signed certificate and its link.
+type Entry struct {
+	Link
+	Record json.RawMessage `json:"record"`
+}
+
+// TreeHead commits to the first Size entries of the ledger.
+type TreeHead struct {
+	Size     int    `json:"tree_size"`
+	RootHash string `json:"root_hash"`
+	Time     string `json:"timestamp"`
+}
+
+// Ledger is a ledger directory: entries.jsonl, one canonical JSON entry
+// per line, and heads.jsonl, one signed tree head per line.
+type Ledger struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+// Open returns the ledger kept in dir.
+func Open(dir string) *Ledger {
+	return &Ledger{Dir: dir}
+}
+
+// Dir is where the app keeps its ledger.
+func Dir() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ledger"), nil
+}
+
+// Default opens the app's ledger.
+func Default() (*Ledger, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return Open(dir), nil
+}
+
+// Append adds an entry and signs a tree head over the ledger with it
+// using key. build is given the new entry's link and returns the record
+// to store, normally a certificate signed with the link in it. Appends are
+// serialised, so the link stays valid until build returns. When Append
+// fails, even after build returned, the link is not in the ledger and will
+// be given to the next entry, unless the error is ErrHeadNotKept.
+func (l *Ledger) Append(key *certs.Key, build func(Link) ([]byte, error)) (Link, error) {
+	if key == nil {
+		return Link{}, errors.New("no key to sign the tree head with")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines, err := readLines(filepath.Join(l.Dir, entriesFile))
+	if err != nil {
+		return Link{}, err
+	}
+	link := Link{Index: len(lines)}
+	if len(lines) > 0 {
+		link.PrevHash = hex.EncodeToString(LeafHash(lines[len(lines)-1]))
+	}
+	record, err := build(link)
+	if err != nil {
+		return Link{}, err
+	}
+	line, err := certs.Canonical(Entry{Link: link, Record: record})
+	if err != nil {
+		return Link{}, err
+	}
+	// The head is signed before anything is written, so a key that cannot
+	// sign leaves the ledger as it was
+	_, head, err := signedHead(key, append(lines, line))
+	if err != nil {
+		return Link{}, err
+	}
+	if err := appendLine(filepath.Join(l.Dir, entriesFile), line); err != nil {
+		return Link{}, err
+	}
+	if err := appendLine(filepath.Join(l.Dir, headsFile), head); err != nil {
+		return link, fmt.Errorf("entry %d: %w: %v", link.Index, ErrHeadNotKept, err)
+	}
+	return link, nil
+}
+
+// Entries reads the ledger's entries and their Merkle leaf hashes. A line
+// that is not an entry is returned as a zero Entry with Index -1.
+func (l *Ledger) Entries() ([]Entry, [][]byte, error) {
+	lines, err := readLines(filepath.Join(l.Dir, entriesFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := make([]Entry, len(lines))
+	leaves := make([][]byte, len(lines))
+	for i, line := range lines {
+		if json.Unmarshal(line, &entries[i]) != nil {
+			entries[i] = Entry{Link: Link{Index: -1}}
+		}
+		leaves[i] = LeafHash(line)
+	}
+	return entries, leaves, nil
+}
+
+// Head is the current, unsigned tree head.
+func (l *Ledger) Head() (TreeHead, error) {
+	_, leaves, err := l.Entries()
+	if err != nil {
+		return TreeHead{}, err
+	}
+	return treeHead(leaves), nil
+}
+
+func treeHead(leaves [][]byte) TreeHead {
+	return TreeHead{
+		Size:     len(leaves),
+		RootHash: hex.EncodeToString(RootHash(leaves)),
+		Time:     time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// signedHead signs the head of the tree over lines, and returns it and
+// its line of heads.jsonl.
+func signedHead(key *certs.Key, lines [][]byte) (certs.Payload, []byte, error) {
+	leaves := make([][]byte, len(lines))
+	for i, line := range lines {
+		leaves[i] = LeafHash(line)
+	}
+	p, err := certs.Sign(key, treeHead(leaves))
+	if err != nil {
+		return certs.Payload{}, nil, err
+	}
+	line, err := certs.PayloadJSON(p)
+	return p, line, err
+}
+
+// SignedHeads reads the tree heads signed so far, oldest first.
+func (l *Ledger) SignedHeads() ([]certs.Payload, error) {
+	lines, err := readLines(filepath.Join(l.Dir, headsFile))
+	if err != nil {
+		return nil, err
+	}
+	heads := make([]certs.Payload, 0, len(lines))
+	for _, line := range lines {
+		p, err := certs.ParseJSON(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", headsFile, err)
+		}
+		heads = append(heads, p)
+	}
+	return heads, nil
+}
+
+// ParseHead reads the tree head a signed head payload commits to. The
+// signature is not checked.
+func ParseHead(p certs.Payload) (TreeHead, error) {
+	var head TreeHead
+	if err := json.Unmarshal(p.Cert, &head); err != nil {
+		return head, fmt.Errorf("not a tree head: %v", err)
+	}
+	if _, err := hex.DecodeString(head.RootHash); err != nil || head.Size < 0 {
+		return head, errors.New("not a tree head")
+	}
+	return head, nil
+}
+
+// SignHead signs the current tree head with key and keeps it. Append
+// already signs one with every entry; this is for handing a fresh head to
+// an auditor.
+func (l *Ledger) SignHead(key *certs.Key) (certs.Payload, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines, err := readLines(filepath.Join(l.Dir, entriesFile))
+	if err != nil {
+		return certs.Payload{}, err
+	}
+	p, line, err := signedHead(key, lines)
+	if err != nil {
+		return certs.Payload{}, err
+	}
+	return p, appendLine(filepath.Join(l.Dir, headsFile), line)
+}
+
+// Inclusion proves an entry is in the tree of a given size.
+type Inclusion struct {
+	Index    int      `json:"leaf_index"`
+	LeafHash string   `json:"leaf_hash"`
+	TreeSize int      `json:"tree_size"`
+	RootHash string   `json:"root_hash"`
+	Path     []string `json:"audit_path"`
+}
+
+// Consistency proves the tree of OldSize entries is a prefix of the tree
+// of NewSize entries.
+type Consistency struct {
+	OldSize int      `json:"old_size"`
+	OldRoot string   `json:"old_root_hash"`
+	NewSize int      `json:"new_size"`
+	NewRoot string   `json:"new_root_hash"`
+	Path    []string `json:"consistency_path"`
+}
+
+// ProveInclusion proves entry index is in the tree of the first size
+// entries.
+func (l *Ledger) ProveInclusion(index, size int) (Inclusion, error) {
+	_, leaves, err := l.Entries()
+	if err != nil {
+		return Inclusion{}, err
+	}
+	if size < 1 || size > len(leaves) || index < 0 || index >= size {
+		return Inclusion{}, fmt.Errorf("no entry %d in a tree of %d of the ledger's %d entries", index, size, len(leaves))
+	}
+	return Inclusion{
+		Index:    index,
+		LeafHash: hex.EncodeToString(leaves[index]),
+		TreeSize: size,
+		RootHash: hex.EncodeToString(RootHash(leaves[:size])),
+		Path:     encodeHashes(InclusionProof(leaves[:size], index)),
+	}, nil
+}
+
+// Verify checks the proof leads from the leaf to the root.
+func (p Inclusion) Verify() error {
+	leaf, root, path, err := decodeProof(p.LeafHash, p.RootHash, p.Path)
+	if err != nil {
+		return err
+	}
+	return VerifyInclusion(leaf, p.Index, p.TreeSize, path, root)
+}
+
+// ProveConsistency proves the tree of the first oldSize entries is a
+// prefix of the tree of the first newSize.
+func (l *Ledger) ProveConsistency(oldSize, newSize int) (Consistency, error) {
+	_, leaves, err := l.Entries()
+	if err != nil {
+		return Consistency{}, err
+	}
+	if oldSize < 0 || oldSize > newSize || newSize > len(leaves) {
+		return Consistency{}, fmt.Errorf("cannot prove %d entries consistent with %d of the ledger's %d", oldSize, newSize, len(leaves))
+	}
+	return Consistency{
+		OldSize: oldSize,
+		OldRoot: hex.EncodeToString(RootHash(leaves[:oldSize])),
+		NewSize: newSize,
+		NewRoot: hex.EncodeToString(RootHash(leaves[:newSize])),
+		Path:    encodeHashes(ConsistencyProof(leaves[:newSize], oldSize)),
+	}, nil
+}
+
+// Verify checks the proof links the old root to the new one.
+func (p Consistency) Verify() error {
+	oldRoot, newRoot, path, err := decodeProof(p.OldRoot, p.NewRoot, p.Path)
+	if err != nil {
+		return err
+	}
+	return VerifyConsistency(p.OldSize, p.NewSize, oldRoot, newRoot, path)
+}
+
+// Report is the outcome of Verify. The ledger is intact when it lists no
+// problems.
+type Report struct {
+	Entries int
+	// Heads counts the signed tree heads checked, kept and published
+	Heads    int
+	Problems []string
+}
+
+// OK reports whether no problems were found.
+func (r Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *Report) problem(format string, args ...any) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// Verify checks the whole ledger: that the entries form an unbroken chain,
+// that each holds a certificate trusted by store and signed for its place
+// in the chain, that the ledger still extends every signed tree head, the
+// ledger's own and those published to auditors, and that its own heads
+// cover every entry. A removed or altered entry shows up as at least one
+// problem, unless the heads that covered it were removed with it. Only failure to read the ledger
+// is an error.
+func (l *Ledger) Verify(store certs.TrustStore, published []certs.Payload) (Report, error) {
+	entries, leaves, err := l.Entries()
+	if err != nil {
+		return Report{}, err
+	}
+	heads, err := l.SignedHeads()
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{Entries: len(entries)}
+
+	next := 0
+	for i, e := range entries {
+		switch {
+		case e.Index < 0:
+			report.problem("line %d is not a ledger entry", i+1)
+			next++
+			continue
+		case e.Index == next+1:
+			report.problem("entry %d is missing", next)
+		case e.Index > next:
+			report.problem("entries %d to %d are missing", next, e.Index-1)
+		case e.Index < next:
+			report.problem("line %d holds entry %d out of order", i+1, e.Index)
+		}
+		next = e.Index + 1
+		want := ""
+		if i > 0 {
+			want = hex.EncodeToString(leaves[i-1])
+		}
+		if e.PrevHash != want {
+			report.problem("entry %d does not follow the entry before it: that entry was altered, or entries between them removed", e.Index)
+		}
+		if err := verifyRecord(store, e); err != nil {
+			report.problem("entry %d: %v", e.Index, err)
+		}
+	}
+
+	current := RootHash(leaves)
+	covered := 0
+	for i, p := range append(heads, published...) {
+		report.Heads++
+		name := fmt.Sprintf("signed tree head %d", i+1)
+		if i >= len(heads) {
+			name = fmt.Sprintf("published tree head %d", i-len(heads)+1)
+		}
+		if _, err := store.Verify(p); err != nil {
+			report.problem("%s: %v", name, err)
+			continue
+		}
+		head, err := ParseHead(p)
+		if err != nil {
+			report.problem("%s: %v", name, err)
+			continue
+		}
+		if head.Size > len(leaves) {
+			report.problem("%s covers %d entries but the ledger has %d: entries were removed", name, head.Size, len(leaves))
+			continue
+		}
+		root, _ := hex.DecodeString(head.RootHash)
+		if VerifyConsistency(head.Size, len(leaves), root, current, ConsistencyProof(leaves, head.Size)) != nil {
+			report.problem("%s: the first %d entries no longer match it: entries were altered or removed", name, head.Size)
+			continue
+		}
+		if i < len(heads) {
+			covered = max(covered, head.Size)
+		}
+	}
+	switch {
+	case covered == len(leaves)-1:
+		report.problem("entry %d is not covered by a signed tree head: its head was removed or never kept", covered)
+	case covered < len(leaves):
+		report.problem("entries %d to %d are not covered by a signed tree head: their heads were removed or never kept", covered, len(leaves)-1)
+	}
+	return report, nil
+}
+
+// verifyRecord checks an entry's certificate signature and timestamp, and
+// that it was signed for this place in the ledger.
+func verifyRecord(store certs.TrustStore, e Entry) error {
+	p, err := certs.ParseJSON(e.Record)
+	if err != nil {
+		return err
+	}
+	stamp, err := store.VerifyTimestamp(p)
+	if err != nil {
+		return err
+	}
+	if stamp != nil {
+		store.At = stamp.Time
+	}
+	if _, err := store.Verify(p); err != nil {
+		return err
+	}
+	var signed struct {
+		Ledger *Link `json:"ledger"`
+	}
+	json.Unmarshal(p.Cert, &signed)
+	if signed.Ledger == nil {
+		return errors.New("certificate was not signed with its ledger link")
+	}
+	if *signed.Ledger != e.Link {
+		return fmt.Errorf("certificate was signed as entry %d but recorded as entry %d", signed.Ledger.Index, e.Index)
+	}
+	return nil
+}
+
+// FindRecord returns the index of the entry holding the certificate whose
+// signed bytes hash to logHash, as its signature block's log_hash says.
+func (l *Ledger) FindRecord(logHash string) (int, error) {
+	entries, _, err := l.Entries()
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		if p, err := certs.ParseJSON(e.Record); err == nil {
+			if hash, err := p.Hash(); err == nil && hash == logHash {
+				return e.Index, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no certificate with log hash %s in the ledger", logHash)
+}
+
+func encodeHashes(hashes [][]byte) []string {
+	encoded := make([]string, len(hashes))
+	for i, h := range hashes {
+		encoded[i] = hex.EncodeToString(h)
+	}
+	return encoded
+}
+
+func decodeProof(first, second string, path []string) ([]byte, []byte, [][]byte, error) {
+	var hashes [][]byte
+	for _, s := range append([]string{first, second}, path...) {
+		h, err := hex.DecodeString(s)
+		if err != nil || len(h) != sha256.Size {
+			return nil, nil, nil, fmt.Errorf("%w: %q is not a SHA-256 hash", ErrProof, s)
+		}
+		hashes = append(hashes, h)
+	}
+	return hashes[0], hashes[1], hashes[2:], nil
+}
+
+// readLines reads the non-empty lines of a JSON lines file; a missing
+// file has none.
+func readLines(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lines [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			lines = append(lines, append([]byte(nil), line...))
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// appendLine appends line to a JSON lines file and syncs it.
+func appendLine(path string, line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
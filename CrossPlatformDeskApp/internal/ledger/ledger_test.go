@@ -0,0 +1,237 @@
+package ledger
+
+import (
+	"crypto"
+	"data_wiper/internal/certs"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// signedRecord signs a certificate for link the way the app does: the
+// link inside what is signed, the signature block beside it.
+func signedRecord(key *certs.Key, status string) func(Link) ([]byte, error) {
+	return func(link Link) ([]byte, error) {
+		cert := map[string]any{"wipe": map[string]any{"status": status}, "ledger": link}
+		p, err := certs.Sign(key, cert)
+		if err != nil {
+			return nil, err
+		}
+		cert["signature"] = map[string]any{"algorithm": p.Alg, "key_id": p.KeyID, "sig": p.Sig}
+		return json.Marshal(cert)
+	}
+}
+
+func testLedger(t *testing.T) (*Ledger, *certs.Key) {
+	t.Helper()
+	key, err := certs.OpenKeyring(t.TempDir(), []byte("passphrase")).Active()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := Open(t.TempDir())
+	for i, status := range []string{"success", "failed", "success", "success", "success"} {
+		link, err := l.Append(key, signedRecord(key, status))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if link.Index != i || (i == 0) != (link.PrevHash == "") {
+			t.Fatalf("entry %d got link %+v", i, link)
+		}
+	}
+	return l, key
+}
+
+// rewrite replaces the lines of one of the ledger's files with edit's
+// result.
+func rewrite(t *testing.T, l *Ledger, file string, edit func(lines []string) []string) {
+	t.Helper()
+	path := filepath.Join(l.Dir, file)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := edit(strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"))
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	l, key := testLedger(t)
+	store := certs.TrustStore{Keys: []crypto.PublicKey{key.Public()}}
+	// A head is signed with every entry
+	report, err := l.Verify(store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.Entries != 5 || report.Heads != 5 {
+		t.Fatalf("intact ledger: %+v", report)
+	}
+	// An auditor's copy of a head is checked like the ledger's own
+	head, err := l.SignHead(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report, _ := l.Verify(store, []certs.Payload{head}); !report.OK() || report.Heads != 7 {
+		t.Errorf("with a published head: %+v", report)
+	}
+	rewrite(t, l, headsFile, func(lines []string) []string { return lines[:5] })
+
+	saved := map[string][]byte{}
+	for _, file := range []string{entriesFile, headsFile} {
+		saved[file], _ = os.ReadFile(filepath.Join(l.Dir, file))
+	}
+	restore := func() {
+		for file, data := range saved {
+			os.WriteFile(filepath.Join(l.Dir, file), data, 0600)
+		}
+	}
+	cases := []struct {
+		name string
+		file string
+		edit func([]string) []string
+		want string
+	}{
+		{"failure removed", entriesFile, func(lines []string) []string { return append(lines[:1:1], lines[2:]...) }, "entry 1 is missing"},
+		{"failure altered", entriesFile, func(lines []string) []string {
+			lines[1] = strings.Replace(lines[1], `"failed"`, `"success"`, 1)
+			return lines
+		}, "entry 1: signature does not match"},
+		{"latest removed", entriesFile, func(lines []string) []string { return lines[:4] }, "covers 5 entries but the ledger has 4"},
+		{"relinked", entriesFile, func(lines []string) []string {
+			// Entry 1 removed and entry 2 moved up with a fresh link
+			var e Entry
+			json.Unmarshal([]byte(lines[2]), &e)
+			e.Link = Link{Index: 1, PrevHash: e.PrevHash}
+			line, _ := certs.Canonical(e)
+			return []string{lines[0], string(line)}
+		}, "signed as entry 2 but recorded as entry 1"},
+		{"latest head removed", headsFile, func(lines []string) []string { return lines[:4] }, "entry 4 is not covered by a signed tree head"},
+		{"heads removed", headsFile, func(lines []string) []string { return lines[:2] }, "entries 2 to 4 are not covered"},
+	}
+	for _, c := range cases {
+		rewrite(t, l, c.file, c.edit)
+		report, err := l.Verify(store, nil)
+		restore()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.OK() || !strings.Contains(strings.Join(report.Problems, "\n"), c.want) {
+			t.Errorf("%s: problems %q, want one saying %q", c.name, report.Problems, c.want)
+		}
+	}
+
+	// Certificates must be signed by a trusted key
+	if report, _ := l.Verify(certs.TrustStore{}, nil); report.OK() {
+		t.Error("verified without trusted keys")
+	}
+}
+
+func TestAppendWriteFailure(t *testing.T) {
+	l, key := testLedger(t)
+	path := filepath.Join(l.Dir, entriesFile)
+	saved := path + ".saved"
+
+	// The entries file turns into a directory between build and the write
+	var built Link
+	_, err := l.Append(key, func(link Link) ([]byte, error) {
+		built = link
+		if err := os.Rename(path, saved); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Mkdir(path, 0700); err != nil {
+			t.Fatal(err)
+		}
+		return signedRecord(key, "success")(link)
+	})
+	if err == nil || errors.Is(err, ErrHeadNotKept) {
+		t.Fatalf("Append err = %v, want the entry's write failure", err)
+	}
+	os.Remove(path)
+	if err := os.Rename(saved, path); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _, err := l.Entries()
+	if err != nil || len(entries) != 5 {
+		t.Fatalf("%d entries after a failed append, %v", len(entries), err)
+	}
+	// A record signed with the failed link would claim the next entry's place
+	link, err := l.Append(key, signedRecord(key, "success"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != built {
+		t.Errorf("next entry linked %+v, failed one %+v", link, built)
+	}
+
+	// The heads file turns into a directory: the entry stays, headless
+	heads := filepath.Join(l.Dir, headsFile)
+	os.Remove(heads)
+	if err := os.Mkdir(heads, 0700); err != nil {
+		t.Fatal(err)
+	}
+	link, err = l.Append(key, signedRecord(key, "success"))
+	if !errors.Is(err, ErrHeadNotKept) || link.Index != 6 {
+		t.Errorf("Append without a heads file = %+v, %v, want entry 6 and ErrHeadNotKept", link, err)
+	}
+	if entries, _, _ := l.Entries(); len(entries) != 7 {
+		t.Errorf("%d entries after the head was lost, want 7", len(entries))
+	}
+	if _, err := l.Append(nil, signedRecord(key, "success")); err == nil {
+		t.Error("Append without a key succeeded")
+	}
+}
+
+func TestProofs(t *testing.T) {
+	l, key := testLedger(t)
+	heads, err := l.SignedHeads()
+	if err != nil || len(heads) != 5 {
+		t.Fatalf("SignedHeads = %d, %v", len(heads), err)
+	}
+	old, err := ParseHead(heads[2])
+	if err != nil || old.Size != 3 {
+		t.Fatalf("first head %+v, %v", old, err)
+	}
+	latest, err := l.SignHead(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, _ := ParseHead(latest)
+
+	c, err := l.ProveConsistency(old.Size, head.Size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.OldRoot != old.RootHash || c.NewRoot != head.RootHash {
+		t.Errorf("consistency proof between roots %s and %s, want the signed heads'", c.OldRoot, c.NewRoot)
+	}
+	if err := c.Verify(); err != nil {
+		t.Error(err)
+	}
+
+	entries, _, _ := l.Entries()
+	p, _ := certs.ParseJSON(entries[1].Record)
+	hash, _ := p.Hash()
+	index, err := l.FindRecord(hash)
+	if err != nil || index != 1 {
+		t.Fatalf("FindRecord = %d, %v", index, err)
+	}
+	in, err := l.ProveInclusion(index, head.Size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Verify(); err != nil || in.RootHash != head.RootHash {
+		t.Errorf("inclusion proof to %s: %v", in.RootHash, err)
+	}
+	in.LeafHash = strings.Repeat("00", 32)
+	if in.Verify() == nil {
+		t.Error("inclusion proof verified for another leaf")
+	}
+	if _, err := l.ProveInclusion(5, 5); err == nil {
+		t.Error("proved an entry past the end")
+	}
+}
@@ -0,0 +1,175 @@
+package ledger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+// The Merkle tree is RFC 9162's (Certificate Transparency 2.0): leaves and
+// interior nodes are hashed with distinct prefixes, so a leaf can never be
+// passed off as a subtree.
+
+// ErrProof is returned when an inclusion or consistency proof does not
+// lead to the claimed root.
+var ErrProof = errors.New("proof does not match the tree head")
+
+// LeafHash is the Merkle leaf hash of data.
+func LeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// split is the largest power of two smaller than n, n > 1.
+func split(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// RootHash is the Merkle tree hash of the given leaf hashes.
+func RootHash(leaves [][]byte) []byte {
+	switch n := len(leaves); n {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return leaves[0]
+	default:
+		k := split(n)
+		return nodeHash(RootHash(leaves[:k]), RootHash(leaves[k:]))
+	}
+}
+
+// InclusionProof is the audit path showing leaf index is in the tree of
+// the given leaves.
+func InclusionProof(leaves [][]byte, index int) [][]byte {
+	n := len(leaves)
+	if n <= 1 || index < 0 || index >= n {
+		return nil
+	}
+	k := split(n)
+	if index < k {
+		return append(InclusionProof(leaves[:k], index), RootHash(leaves[k:]))
+	}
+	return append(InclusionProof(leaves[k:], index-k), RootHash(leaves[:k]))
+}
+
+// VerifyInclusion checks that leaf is at index in the tree of the given
+// size and root, by its audit path.
+func VerifyInclusion(leaf []byte, index, size int, proof [][]byte, root []byte) error {
+	if index < 0 || index >= size {
+		return ErrProof
+	}
+	fn, sn := index, size-1
+	r := leaf
+	for _, p := range proof {
+		if sn == 0 {
+			return ErrProof
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || !bytes.Equal(r, root) {
+		return ErrProof
+	}
+	return nil
+}
+
+// ConsistencyProof shows the tree of the first m leaves is a prefix of the
+// tree of all of them: nothing it held was changed or removed.
+func ConsistencyProof(leaves [][]byte, m int) [][]byte {
+	if m <= 0 || m >= len(leaves) {
+		return nil
+	}
+	return subproof(leaves, m, true)
+}
+
+func subproof(leaves [][]byte, m int, whole bool) [][]byte {
+	n := len(leaves)
+	if m == n {
+		if whole {
+			return nil
+		}
+		return [][]byte{RootHash(leaves)}
+	}
+	k := split(n)
+	if m <= k {
+		return append(subproof(leaves[:k], m, whole), RootHash(leaves[k:]))
+	}
+	return append(subproof(leaves[k:], m-k, false), RootHash(leaves[:k]))
+}
+
+// VerifyConsistency checks that the tree of size m and root oldRoot is a
+// prefix of the tree of size n and root newRoot.
+func VerifyConsistency(m, n int, oldRoot, newRoot []byte, proof [][]byte) error {
+	switch {
+	case m < 0 || m > n:
+		return ErrProof
+	case m == n:
+		if len(proof) != 0 || !bytes.Equal(oldRoot, newRoot) {
+			return ErrProof
+		}
+		return nil
+	case m == 0:
+		// Every tree extends the empty one
+		if len(proof) != 0 {
+			return ErrProof
+		}
+		return nil
+	}
+	if m&(m-1) == 0 {
+		proof = append([][]byte{oldRoot}, proof...)
+	}
+	if len(proof) == 0 {
+		return ErrProof
+	}
+	fn, sn := m-1, n-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return ErrProof
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = nodeHash(c, fr)
+			sr = nodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = nodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || !bytes.Equal(fr, oldRoot) || !bytes.Equal(sr, newRoot) {
+		return ErrProof
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func testLeaves(n int) [][]byte {
+	leaves := make([][]byte, n)
+	for i := range leaves {
+		leaves[i] = LeafHash([]byte(fmt.Sprintf("entry %d", i)))
+	}
+	return leaves
+}
+
+func TestRootHash(t *testing.T) {
+	// RFC 9162's empty tree hash, and the root of a single leaf
+	if got := hex.EncodeToString(RootHash(nil)); got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("empty root %s", got)
+	}
+	if got := hex.EncodeToString(RootHash([][]byte{LeafHash(nil)})); got != "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d" {
+		t.Errorf("root of an empty leaf %s", got)
+	}
+	leaves := testLeaves(3)
+	want := nodeHash(nodeHash(leaves[0], leaves[1]), leaves[2])
+	if !bytes.Equal(RootHash(leaves), want) {
+		t.Error("root of three leaves is not h(h(0,1),2)")
+	}
+}
+
+func TestInclusionProofs(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		leaves := testLeaves(n)
+		root := RootHash(leaves)
+		for i := 0; i < n; i++ {
+			proof := InclusionProof(leaves, i)
+			if err := VerifyInclusion(leaves[i], i, n, proof, root); err != nil {
+				t.Fatalf("leaf %d of %d: %v", i, n, err)
+			}
+			if n > 1 && VerifyInclusion(leaves[(i+1)%n], i, n, proof, root) == nil {
+				t.Fatalf("leaf %d of %d: another leaf verified in its place", i, n)
+			}
+			if len(proof) > 0 {
+				proof[len(proof)-1] = LeafHash([]byte("altered"))
+				if VerifyInclusion(leaves[i], i, n, proof, root) == nil {
+					t.Fatalf("leaf %d of %d: verified with an altered proof", i, n)
+				}
+			}
+		}
+	}
+}
+
+func TestConsistencyProofs(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		leaves := testLeaves(n)
+		root := RootHash(leaves)
+		for m := 0; m <= n; m++ {
+			old := RootHash(leaves[:m])
+			proof := ConsistencyProof(leaves, m)
+			if err := VerifyConsistency(m, n, old, root, proof); err != nil {
+				t.Fatalf("%d to %d: %v", m, n, err)
+			}
+			if m == 0 || m == n {
+				continue
+			}
+			// The same size with one leaf changed is not a prefix
+			altered := append([][]byte(nil), leaves...)
+			altered[m-1] = LeafHash([]byte("altered"))
+			if err := VerifyConsistency(m, n, RootHash(altered[:m]), root, proof); !errors.Is(err, ErrProof) {
+				t.Fatalf("%d to %d: altered old tree = %v", m, n, err)
+			}
+			// Nor is the tree with a leaf removed
+			removed := append(append([][]byte(nil), leaves[:m-1]...), leaves[m:]...)
+			if err := VerifyConsistency(m, n-1, old, RootHash(removed), ConsistencyProof(removed, m)); !errors.Is(err, ErrProof) {
+				t.Fatalf("%d to %d: tree with leaf %d removed = %v", m, n-1, m-1, err)
+			}
+		}
+	}
+}
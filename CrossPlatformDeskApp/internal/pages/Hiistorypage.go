@@ -18,6 +18,10 @@ var pdfDetails map[string]string
 var pdfSearchQuery string
 var pdfSearchActive bool = false
 var historyFileScrollOffset int
+// ledgerStatus says whether the certificate ledger verified when the tab
+// was opened or refreshed; deleting a PDF here leaves its ledger entry
+var ledgerStatus string
+var ledgerIntact bool
 
 func drawHistoryTab() {
 	screenWidth := float32(rl.GetScreenWidth())
@@ -27,6 +31,7 @@ func drawHistoryTab() {
 	if !historyActive {
 		historyActive = true
 		historyScrollOffset = 0
+		ledgerStatus, ledgerIntact = ledgerSummary()
 		if _, err := os.Stat("pdfs"); os.IsNotExist(err) {
 			os.Mkdir("pdfs", 0755)
 		}
@@ -49,6 +54,16 @@ func drawHistoryTab() {
 	const spacing = 12.0
 	dialogsActive := IsConfirmPurgeActive() || IsConfirmClearActive() || IsCertificateActive()
 
+	ledgerColor := rl.NewColor(0, 200, 150, 200)
+	if !ledgerIntact {
+		ledgerColor = rl.NewColor(255, 100, 100, 255)
+	}
+	statusDisplay := ledgerStatus
+	if len(statusDisplay) > 110 {
+		statusDisplay = statusDisplay[:110] + "..."
+	}
+	rl.DrawText(statusDisplay, int32(margin), 112, 14, ledgerColor)
+
 	if selectedPdf == nil {
 		totalPdfs := len(pdfFiles)
 		if totalPdfs == 0 {
@@ -285,6 +300,7 @@ func drawHistoryTab() {
 				}
 			}
 			historyScrollOffset = 0
+			ledgerStatus, ledgerIntact = ledgerSummary()
 		}
 	}
 
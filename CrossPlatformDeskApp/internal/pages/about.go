@@ -0,0 +1,60 @@
+package pages
+
+import (
+	"data_wiper/internal/sysinfo"
+	"fmt"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// aboutInfo is what the About tab shows: the same collector output every
+// certificate records, read when the tab is first opened or refreshed.
+var aboutInfo *sysinfo.Info
+var aboutCollected time.Time
+
+func drawAboutTab(screenWidth, screenHeight float32) {
+	const margin = 30.0
+	labelColor := rl.NewColor(0, 255, 180, 255)
+	textColor := rl.NewColor(200, 200, 200, 255)
+	mutedColor := rl.NewColor(0, 200, 150, 200)
+	dialogsActive := IsConfirmPurgeActive() || IsConfirmClearActive() || IsConfirmDiskWipeActive() || IsCertificateActive()
+
+	if aboutInfo == nil {
+		info := sysinfo.Collect()
+		aboutInfo = &info
+		aboutCollected = time.Now()
+	}
+
+	y := float32(150)
+	panelWidth := screenWidth - 2*margin
+	lines := systemLines(*aboutInfo)
+	if signingKey != nil {
+		lines = append(lines, "Signing Key ID: "+signingKey.ID)
+	} else {
+		lines = append(lines, fmt.Sprintf("Signing Key: unavailable, certificates are not signed (%v)", signingKeyErr))
+	}
+
+	rl.DrawText("Null Byters Secure Wipe", int32(margin), int32(y), 20, labelColor)
+	y += 30
+	rl.DrawText("Every certificate records these details as its System block.", int32(margin), int32(y), 14, mutedColor)
+	y += 24
+
+	panel := rl.NewRectangle(margin, y, panelWidth, float32(40+20*len(lines)))
+	rl.DrawRectangleRounded(panel, 0.1, 8, rl.NewColor(10, 50, 30, 200))
+	rl.DrawRectangleRoundedLines(panel, 0.1, 8, labelColor)
+	rl.DrawText("System", int32(margin+12), int32(y+10), 16, labelColor)
+	rowY := y + 32
+	for _, line := range lines {
+		rl.DrawText(line, int32(margin+24), int32(rowY), 14, textColor)
+		rowY += 20
+	}
+	y += panel.Height + 12
+	rl.DrawText("Collected "+aboutCollected.Format("2006-01-02 15:04:05"), int32(margin), int32(y), 12, mutedColor)
+
+	refreshBtn := rl.NewRectangle(screenWidth-margin-100, 150, 100, 40)
+	drawGlowingButton(refreshBtn, "🔄 Refresh", rl.NewColor(0, 180, 255, 255), rl.NewColor(255, 255, 255, 255))
+	if !dialogsActive && rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(rl.GetMousePosition(), refreshBtn) {
+		aboutInfo = nil
+	}
+}
@@ -1,20 +1,22 @@
 package pages
 
 import (
-	"crypto/ed25519"
-	cryptoRand "crypto/rand"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
+	"bytes"
+	"data_wiper/internal/certs"
+	"data_wiper/internal/config"
+	"data_wiper/internal/drivers"
+	"data_wiper/internal/sysinfo"
+	"data_wiper/internal/wipelog"
+	"encoding/base64"
 	"fmt"
 	"image/png"
 	"math"
-	mathRand "math/rand"
+	"net/http"
 	"os"
-	"os/exec"
-	"runtime"
-	"strconv"
+	"os/user"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
@@ -22,370 +24,175 @@ import (
 	"github.com/skip2/go-qrcode"
 )
 
-type WipeLog struct {
-	Device struct {
-		Name   string `json:"name"`
-		Serial string `json:"serial"`
-		SizeGB int    `json:"size_gb"`
-		Type   string `json:"type"`
-	} `json:"device"`
-	Wipe struct {
-		Method      string `json:"method"`
-		NistLevel   string `json:"nist_level"`
-		Status      string `json:"status"`
-		StartedAt   string `json:"started_at"`
-		FinishedAt  string `json:"finished_at"`
-		DurationSec int    `json:"duration_sec"`
-	} `json:"wipe"`
-	System struct {
-		ToolVersion string `json:"tool_version"`
-		HostOS      string `json:"host_os"`
-		ExecutedBy  string `json:"executed_by"`
-	} `json:"system"`
-	Signature struct {
-		Algorithm            string `json:"algorithm"`
-		Sig                  string `json:"sig"`
-		PublicKeyFingerprint string `json:"public_key_fingerprint"`
-		LogHash              string `json:"log_hash"`
-	} `json:"signature"`
-}
-
-// Device information structure
-type DeviceInfo struct {
-	Name   string
-	Serial string
-	SizeGB int
-	Type   string
-}
-
 var (
 	certificateActive        bool = false
-	certificateLog           WipeLog
+	certificateLog           wipelog.Log
 	certificateAnimationTime float32 = 0
 	certificateScrollOffset  float32 = 0
 	qrTexture                rl.Texture2D
-	privateKey               ed25519.PrivateKey
-	publicKey                ed25519.PublicKey
+	// certificateURL is the verifier site link the QR code encodes
+	certificateURL string
+	// signingKey is the key in certs.KeyringDir that signs certificates;
+	// nil, with signingKeyErr saying why, when it cannot be loaded
+	signingKey    *certs.Key
+	signingKeyErr error
+	// tsaClient bounds how long a finished wipe waits on an unreachable TSA
+	tsaClient = &http.Client{Timeout: 10 * time.Second}
+	// certificateMu serialises signing, recording and writing certificates,
+	// which scheduled jobs do on their own goroutine alongside the dialogs
+	certificateMu sync.Mutex
+	// certificateSigned delivers the dialog's log once it has been signed,
+	// time-stamped and recorded off the render thread
+	certificateSigned  chan signedCertificate
+	certificateSigning bool
+	// certificateSignErr says why the dialog's log could not be signed
+	certificateSignErr error
 )
 
 func init() {
-	var err error
-	publicKey, privateKey, err = ed25519.GenerateKey(cryptoRand.Reader)
+	keyring, err := certs.DefaultKeyring()
+	if err == nil {
+		signingKey, err = keyring.Active()
+	}
 	if err != nil {
-		fmt.Printf("Failed to generate key: %v\n", err)
+		// No certificate is signed with a key nobody could verify it by
+		signingKey, signingKeyErr = nil, err
+		fmt.Printf("Failed to load signing key, certificates will not be signed: %v\n", err)
 	}
-	mathRand.Seed(time.Now().UnixNano())
 }
 
-// DetectDeviceInfo attempts to detect real device information
-func DetectDeviceInfo(devicePath string) DeviceInfo {
-	info := DeviceInfo{}
-
-	switch runtime.GOOS {
-	case "linux":
-		info = detectLinuxDeviceInfo(devicePath)
-	case "windows":
-		info = detectWindowsDeviceInfo(devicePath)
-	case "darwin":
-		info = detectMacDeviceInfo(devicePath)
-	default:
-		info = generateFakeDeviceInfo()
-	}
-
-	// If detection failed, generate realistic fake data
-	if info.Name == "" || info.Serial == "" || info.SizeGB == 0 {
-		fakeInfo := generateFakeDeviceInfo()
-		if info.Name == "" {
-			info.Name = fakeInfo.Name
-		}
-		if info.Serial == "" {
-			info.Serial = fakeInfo.Serial
-		}
-		if info.SizeGB == 0 {
-			info.SizeGB = fakeInfo.SizeGB
-		}
-		if info.Type == "" {
-			info.Type = fakeInfo.Type
-		}
-	}
-
-	return info
+type signedCertificate struct {
+	log wipelog.Log
+	err error
 }
 
-func detectLinuxDeviceInfo(devicePath string) DeviceInfo {
-	info := DeviceInfo{}
-
-	// Extract device name from path
-	deviceName := strings.TrimPrefix(devicePath, "/dev/")
-	deviceName = strings.TrimRight(deviceName, "0123456789")
-
-	// Try to get device model and size using lsblk
-	cmd := exec.Command("lsblk", "-rno", "MODEL,SIZE,TYPE", "/dev/"+deviceName)
-	if output, err := cmd.Output(); err == nil {
-		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-		if len(lines) > 0 {
-			fields := strings.Fields(lines[0])
-			if len(fields) >= 3 {
-				if fields[0] != "" && fields[0] != "N/A" {
-					info.Name = fields[0]
-				}
-				if sizeStr := fields[1]; sizeStr != "" {
-					if size := parseSizeString(sizeStr); size > 0 {
-						info.SizeGB = size
-					}
-				}
-				info.Type = fields[2]
-			}
-		}
-	}
-
-	// Try to get serial number
-	serialPaths := []string{
-		fmt.Sprintf("/sys/block/%s/device/serial", deviceName),
-		fmt.Sprintf("/sys/class/block/%s/device/serial", deviceName),
-	}
+// ShowCertificate opens the certificate dialog for the log of a finished
+// wipe, which must already describe what was wiped. The log is signed,
+// time-stamped and recorded in the background, since the TSA may take
+// seconds to answer; the dialog fills in once that is done.
+func ShowCertificate(log wipelog.Log) {
+	certificateActive = true
+	certificateLog = log
+	certificateAnimationTime = 0
+	certificateScrollOffset = 0
+	qrTexture = rl.Texture2D{}
+	certificateURL = ""
+
+	signed := make(chan signedCertificate, 1)
+	certificateSigned = signed
+	certificateSigning = true
+	certificateSignErr = nil
+	go func() {
+		certificateMu.Lock()
+		err := signWipeLog(&log)
+		recordWipe(log)
+		certificateMu.Unlock()
+		signed <- signedCertificate{log, err}
+	}()
+}
 
-	for _, path := range serialPaths {
-		if data, err := os.ReadFile(path); err == nil {
-			serial := strings.TrimSpace(string(data))
-			if serial != "" && serial != "N/A" {
-				info.Serial = serial
-				break
-			}
-		}
+// receiveSignedCertificate takes the signed log into the dialog once it
+// is ready. Textures can only be made on the render thread, so the QR
+// code is built here.
+func receiveSignedCertificate() {
+	if !certificateSigning {
+		return
 	}
-
-	// Try alternative methods for serial
-	if info.Serial == "" {
-		cmd := exec.Command("udevadm", "info", "--name=/dev/"+deviceName, "--query=property")
-		if output, err := cmd.Output(); err == nil {
-			lines := strings.Split(string(output), "\n")
-			for _, line := range lines {
-				if strings.HasPrefix(line, "ID_SERIAL_SHORT=") {
-					info.Serial = strings.TrimPrefix(line, "ID_SERIAL_SHORT=")
-					break
-				} else if strings.HasPrefix(line, "ID_SERIAL=") {
-					info.Serial = strings.TrimPrefix(line, "ID_SERIAL=")
-					break
-				}
-			}
-		}
+	select {
+	case signed := <-certificateSigned:
+		certificateLog, certificateSignErr = signed.log, signed.err
+		certificateSigning = false
+	default:
+		return
 	}
-
-	return info
-}
-
-func detectWindowsDeviceInfo(devicePath string) DeviceInfo {
-	info := DeviceInfo{}
-
-	// Extract drive letter
-	driveLetter := strings.TrimSuffix(devicePath, "\\")
-
-	// Use WMIC to get detailed information
-	cmd := exec.Command("wmic", "logicaldisk", "where", fmt.Sprintf("DeviceID='%s'", driveLetter), "get", "Size,VolumeSerialNumber,VolumeName")
-	if output, err := cmd.Output(); err == nil {
-		lines := strings.Split(string(output), "\n")
-		for _, line := range lines {
-			fields := strings.Fields(strings.TrimSpace(line))
-			if len(fields) >= 3 && !strings.Contains(line, "Size") {
-				if size, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
-					info.SizeGB = int(size / (1024 * 1024 * 1024))
-				}
-				if len(fields) > 1 {
-					info.Serial = fields[1]
-				}
-				if len(fields) > 2 {
-					info.Name = strings.Join(fields[2:], " ")
-				}
-				break
-			}
-		}
+	if certificateSignErr != nil {
+		return
 	}
 
-	// Try to get physical disk information
-	cmd = exec.Command("wmic", "diskdrive", "get", "Model,SerialNumber,Size")
-	if output, err := cmd.Output(); err == nil && info.Name == "" {
-		lines := strings.Split(string(output), "\n")
-		if len(lines) > 1 {
-			fields := strings.Fields(strings.TrimSpace(lines[1]))
-			if len(fields) >= 1 {
-				info.Name = strings.Join(fields[:len(fields)-2], " ")
-			}
-		}
+	qr, url, err := verificationQR(certificateLog)
+	certificateURL = url
+	if err != nil {
+		fmt.Printf("QR code generation failed: %v\n", err)
+	} else {
+		qrImg := qr.Image(256)
+		rlImg := rl.NewImageFromImage(qrImg)
+		qrTexture = rl.LoadTextureFromImage(rlImg)
+		rl.UnloadImage(rlImg)
 	}
-
-	info.Type = "disk"
-	return info
 }
 
-func detectMacDeviceInfo(devicePath string) DeviceInfo {
-	info := DeviceInfo{}
-
-	// Use diskutil to get device information
-	cmd := exec.Command("diskutil", "info", devicePath)
-	if output, err := cmd.Output(); err == nil {
-		outputStr := string(output)
-		lines := strings.Split(outputStr, "\n")
-
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if strings.HasPrefix(line, "Device / Media Name:") {
-				info.Name = strings.TrimSpace(strings.TrimPrefix(line, "Device / Media Name:"))
-			} else if strings.HasPrefix(line, "Disk Size:") {
-				sizeStr := strings.TrimSpace(strings.TrimPrefix(line, "Disk Size:"))
-				if size := parseSizeString(sizeStr); size > 0 {
-					info.SizeGB = size
-				}
-			} else if strings.HasPrefix(line, "Volume UUID:") {
-				info.Serial = strings.TrimSpace(strings.TrimPrefix(line, "Volume UUID:"))
-			}
-		}
-	}
-
-	info.Type = "disk"
-	return info
+// signWipeLog signs everything in the log but its signature block and
+// records it in the ledger, whose link the signature covers. A log the
+// ledger cannot take is still signed, without a link: the append may have
+// failed after the log was signed with one, and that link will be given
+// to the next certificate. The error says why the log is left unsigned.
+func signWipeLog(log *wipelog.Log) error {
+	if err := appendToLedger(log); err != nil {
+		fmt.Printf("Failed to record certificate in the ledger: %v\n", err)
+		log.Ledger = nil
+		log.Signature = wipelog.Log{}.Signature
+		return signLog(log)
+	}
+	return nil
 }
 
-// Parse size strings like "500 GB", "1.5 TB", etc.
-func parseSizeString(sizeStr string) int {
-	sizeStr = strings.ToUpper(strings.TrimSpace(sizeStr))
-	
-	// Extract numeric part
-	var numStr string
-	var unit string
-	parts := strings.Fields(sizeStr)
-	if len(parts) >= 2 {
-		numStr = parts[0]
-		unit = parts[1]
-	} else {
-		// Try to split by letters
-		for i, r := range sizeStr {
-			if (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') {
-				numStr = sizeStr[:i]
-				unit = sizeStr[i:]
-				break
-			}
-		}
+// signLog signs the log as it stands
+func signLog(log *wipelog.Log) error {
+	if signingKey == nil {
+		return fmt.Errorf("no signing key: %v", signingKeyErr)
 	}
-
-	if numStr == "" {
-		return 0
+	if err := log.Sign(signingKey, stampPayload); err != nil {
+		fmt.Printf("Failed to sign certificate: %v\n", err)
+		return err
 	}
+	return nil
+}
 
-	// Parse the number (handle decimals)
-	var size float64
-	var err error
-	if size, err = strconv.ParseFloat(numStr, 64); err != nil {
-		return 0
+// stampPayload has the configured TSA vouch for when the log was signed,
+// since its own times come from the workstation clock
+func stampPayload(payload certs.Payload) *certs.Timestamp {
+	cfg, _ := config.Load()
+	url := cfg.TSA()
+	if url == "" {
+		return &certs.Timestamp{Status: certs.Unstamped, Error: "time-stamping is turned off"}
 	}
-
-	// Convert to GB based on unit
-	switch unit {
-	case "TB", "T":
-		return int(size * 1024)
-	case "GB", "G":
-		return int(size)
-	case "MB", "M":
-		return int(size / 1024)
-	case "KB", "K":
-		return int(size / (1024 * 1024))
-	case "B", "BYTES":
-		return int(size / (1024 * 1024 * 1024))
-	default:
-		// Assume bytes if no unit
-		return int(size / (1024 * 1024 * 1024))
+	ts := certs.StampPayload(tsaClient, url, payload)
+	if ts.Status != certs.Stamped {
+		fmt.Printf("Certificate left unstamped: %s\n", ts.Error)
 	}
+	return &ts
 }
 
-// Generate realistic fake device information for demo purposes
-func generateFakeDeviceInfo() DeviceInfo {
-	// Realistic device names
-	deviceNames := []string{
-		"Samsung SSD 970 EVO Plus",
-		"Western Digital Blue",
-		"Seagate Barracuda",
-		"Kingston DataTraveler",
-		"SanDisk Ultra",
-		"Crucial MX500",
-		"Toshiba Canvio",
-		"ADATA XPG SX8200",
-		"Intel 660p SSD",
-		"Transcend ESD230C",
-	}
-
-	// Common sizes in GB
-	sizes := []int{128, 256, 500, 512, 1000, 1024, 2000, 2048, 4096}
-
-	// Device types
-	types := []string{"SSD", "HDD", "USB", "NVMe"}
-
-	// Generate serial number (realistic format)
-	serialChars := "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	serial := ""
-	for i := 0; i < 10; i++ {
-		serial += string(serialChars[mathRand.Intn(len(serialChars))])
-	}
-
-	return DeviceInfo{
-		Name:   deviceNames[mathRand.Intn(len(deviceNames))],
-		Serial: serial,
-		SizeGB: sizes[mathRand.Intn(len(sizes))],
-		Type:   types[mathRand.Intn(len(types))],
+// verificationQR links the verifier site to a signed log, with the signed
+// payload compressed into the URL fragment as CertificateTOOL does, and
+// encodes the link at the highest error correction it fits.
+func verificationQR(log wipelog.Log) (*qrcode.QRCode, string, error) {
+	payload, err := log.Payload()
+	if err != nil {
+		return nil, "", err
+	}
+	// The chain and timestamp token would double the link; the PDF and
+	// JSON carry them
+	payload.Chain = nil
+	payload.Timestamp = ""
+	cfg, _ := config.Load()
+	url, err := certs.VerificationURL(cfg.Verifier(), payload)
+	if err != nil {
+		return nil, "", err
 	}
-}
-
-// Enhanced ShowCertificate function that populates device info
-func ShowCertificate(log WipeLog) {
-	certificateActive = true
-	
-	// Detect or generate device information
-	deviceInfo := DetectDeviceInfo("/dev/sda") // You can pass the actual device path here
-	
-	// Populate the log with device information
-	log.Device.Name = deviceInfo.Name
-	log.Device.Serial = deviceInfo.Serial
-	log.Device.SizeGB = deviceInfo.SizeGB
-	log.Device.Type = deviceInfo.Type
-
-	// Generate signature
-	log.Signature.Algorithm = "Ed25519"
-	log.Signature.PublicKeyFingerprint = generateFingerprint(publicKey)
-	
-	// Create log hash and sign it
-	logForSigning := log
-	logForSigning.Signature.Sig = "" // Clear signature for hashing
-	logForSigning.Signature.LogHash = ""
-	
-	jsonBytes, _ := json.Marshal(logForSigning)
-	hash := sha256.Sum256(jsonBytes)
-	log.Signature.LogHash = hex.EncodeToString(hash[:])
-	
-	signature := ed25519.Sign(privateKey, hash[:])
-	log.Signature.Sig = hex.EncodeToString(signature)
-
-	certificateLog = log
-	certificateAnimationTime = 0
-	certificateScrollOffset = 0
-
-	// Generate QR code
-	jsonBytes, _ = json.Marshal(log)
-	qr, err := qrcode.New(string(jsonBytes), qrcode.Medium)
-	if err == nil {
-		qrImg := qr.Image(256)
-		rlImg := rl.NewImageFromImage(qrImg)
-		qrTexture = rl.LoadTextureFromImage(rlImg)
-		rl.UnloadImage(rlImg)
+	for _, level := range []qrcode.RecoveryLevel{qrcode.High, qrcode.Medium, qrcode.Low} {
+		var qr *qrcode.QRCode
+		if qr, err = qrcode.New(url, level); err == nil {
+			return qr, url, nil
+		}
 	}
-}
-
-// Helper function to generate public key fingerprint
-func generateFingerprint(pubKey ed25519.PublicKey) string {
-	hash := sha256.Sum256(pubKey)
-	return hex.EncodeToString(hash[:8]) // First 8 bytes as fingerprint
+	return nil, url, err
 }
 
 func HideCertificate() {
 	certificateActive = false
+	// A log still being signed is recorded all the same
+	certificateSigning = false
 	certificateAnimationTime = 0
 	certificateScrollOffset = 0
 	if qrTexture.ID > 0 {
@@ -401,6 +208,7 @@ func DrawCertificate() {
 	if !certificateActive {
 		return
 	}
+	receiveSignedCertificate()
 
 	certificateAnimationTime += rl.GetFrameTime()
 
@@ -497,7 +305,20 @@ func DrawCertificate() {
 	rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Size: %d GB", certificateLog.Device.SizeGB), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
 	contentY += 20
 	rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Type: %s", certificateLog.Device.Type), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
-	contentY += 30
+	contentY += 20
+	if certificateLog.Device.Path != "" && certificateLog.Device.Path != certificateLog.Device.Name {
+		rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Path: %s", certificateLog.Device.Path), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
+		contentY += 20
+	}
+	if c := certificateLog.Device.Container; c != nil {
+		rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("On drive: %s (%s, serial %s)", c.Device, c.Model, c.Serial), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
+		contentY += 20
+	}
+	if certificateLog.Device.Fingerprint != "" {
+		rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Drive ID: %s", certificateLog.Device.Fingerprint), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
+		contentY += 20
+	}
+	contentY += 10
 
 	// Wipe section
 	rl.DrawTextEx(rl.GetFontDefault(), "Wipe:", rl.NewVector2(modalX+20, modalY+headerHeight+20+contentY), 16, 1, labelColor)
@@ -515,20 +336,90 @@ func DrawCertificate() {
 	rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Duration: %d sec", certificateLog.Wipe.DurationSec), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
 	contentY += 30
 
+	// Health section
+	if h := certificateLog.Health; h != nil {
+		rl.DrawTextEx(rl.GetFontDefault(), "Drive Health:", rl.NewVector2(modalX+20, modalY+headerHeight+20+contentY), 16, 1, labelColor)
+		contentY += 25
+		for _, line := range healthLines(*h) {
+			rl.DrawTextEx(rl.GetFontDefault(), line, rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
+			contentY += 20
+		}
+		contentY += 10
+	}
+
+	// USB section
+	if u := certificateLog.USB; u != nil {
+		rl.DrawTextEx(rl.GetFontDefault(), "USB Identity:", rl.NewVector2(modalX+20, modalY+headerHeight+20+contentY), 16, 1, labelColor)
+		contentY += 25
+		for _, line := range usbLines(*u) {
+			rl.DrawTextEx(rl.GetFontDefault(), line, rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
+			contentY += 20
+		}
+		contentY += 10
+	}
+
+	// Capacity section
+	if c := certificateLog.Capacity; c != nil {
+		color := textColor
+		if c.Fake() {
+			color = rl.NewColor(255, 100, 100, 255)
+		}
+		rl.DrawTextEx(rl.GetFontDefault(), "Capacity Check:", rl.NewVector2(modalX+20, modalY+headerHeight+20+contentY), 16, 1, labelColor)
+		contentY += 25
+		for _, line := range capacityLines(*c) {
+			rl.DrawTextEx(rl.GetFontDefault(), line, rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, color)
+			contentY += 20
+		}
+		contentY += 10
+	}
+
+	// Filesystem section
+	if fs := certificateLog.Filesystem; fs != nil {
+		rl.DrawTextEx(rl.GetFontDefault(), "Filesystem:", rl.NewVector2(modalX+20, modalY+headerHeight+20+contentY), 16, 1, labelColor)
+		contentY += 25
+		for _, line := range filesystemLines(*fs) {
+			rl.DrawTextEx(rl.GetFontDefault(), line, rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
+			contentY += 20
+		}
+		contentY += 10
+	}
+
+	// Execution section
+	if e := certificateLog.Execution; e != nil {
+		rl.DrawTextEx(rl.GetFontDefault(), "Execution:", rl.NewVector2(modalX+20, modalY+headerHeight+20+contentY), 16, 1, labelColor)
+		contentY += 25
+		for _, line := range executionLines(*e) {
+			color := textColor
+			if strings.HasPrefix(line, "Error") {
+				color = rl.NewColor(255, 100, 100, 255)
+			}
+			rl.DrawTextEx(rl.GetFontDefault(), line, rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, color)
+			contentY += 20
+		}
+		contentY += 10
+	}
+
 	// System section
 	rl.DrawTextEx(rl.GetFontDefault(), "System:", rl.NewVector2(modalX+20, modalY+headerHeight+20+contentY), 16, 1, labelColor)
 	contentY += 25
-	rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Tool Version: %s", certificateLog.System.ToolVersion), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
-	contentY += 20
-	rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Host OS: %s", certificateLog.System.HostOS), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
-	contentY += 20
+	for _, line := range systemLines(certificateLog.System.Info) {
+		rl.DrawTextEx(rl.GetFontDefault(), line, rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
+		contentY += 20
+	}
 	rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Executed By: %s", certificateLog.System.ExecutedBy), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
 	contentY += 30
 
 	// Signature section
 	rl.DrawTextEx(rl.GetFontDefault(), "Signature:", rl.NewVector2(modalX+20, modalY+headerHeight+20+contentY), 16, 1, labelColor)
 	contentY += 25
-	rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Algorithm: %s", certificateLog.Signature.Algorithm), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
+	if certificateSigning {
+		rl.DrawTextEx(rl.GetFontDefault(), "Signing and time-stamping...", rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
+		contentY += 30
+	} else if certificateSignErr != nil {
+		rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Not signed: %v", certificateSignErr), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, rl.NewColor(255, 100, 100, 255))
+		contentY += 30
+	}
+	rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Algorithm: %s   Key ID: %s", certificateLog.Signature.Algorithm, certificateLog.Signature.KeyID), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
 	contentY += 20
 	sigDisplay := certificateLog.Signature.Sig
 	if len(sigDisplay) > 50 {
@@ -543,14 +434,37 @@ func DrawCertificate() {
 	rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Public Key Fingerprint: %s", fingerprintDisplay), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
 	contentY += 20
 	rl.DrawTextEx(rl.GetFontDefault(), fmt.Sprintf("Log Hash (SHA256): %s", certificateLog.Signature.LogHash), rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
-	contentY += 30
+	contentY += 20
+	for _, line := range chainLines(certificateLog.Signature.Chain) {
+		rl.DrawTextEx(rl.GetFontDefault(), line, rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
+		contentY += 20
+	}
+	for _, line := range timestampLines(certificateLog.Signature.Timestamp) {
+		rl.DrawTextEx(rl.GetFontDefault(), line, rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
+		contentY += 20
+	}
+	contentY += 10
 
 	// QR Code
-	rl.DrawTextEx(rl.GetFontDefault(), "QR Code (Scan for Log):", rl.NewVector2(modalX+20, modalY+headerHeight+20+contentY), 16, 1, labelColor)
+	rl.DrawTextEx(rl.GetFontDefault(), "QR Code (Scan to Verify):", rl.NewVector2(modalX+20, modalY+headerHeight+20+contentY), 16, 1, labelColor)
 	contentY += 25
+	if certificateURL != "" {
+		urlDisplay := certificateURL
+		if len(urlDisplay) > 70 {
+			urlDisplay = urlDisplay[:70] + "..."
+		}
+		rl.DrawTextEx(rl.GetFontDefault(), urlDisplay, rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
+		contentY += 25
+	}
 	if qrTexture.ID > 0 {
 		rl.DrawTexture(qrTexture, int32(modalX+40), int32(modalY+headerHeight+20+contentY), rl.White)
 		contentY += float32(qrTexture.Height) + 20
+	} else if certificateSigning {
+		rl.DrawTextEx(rl.GetFontDefault(), "Waiting for the signature", rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, textColor)
+		contentY += 20
+	} else if certificateSignErr != nil {
+		rl.DrawTextEx(rl.GetFontDefault(), "An unsigned certificate cannot be verified", rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, rl.Red)
+		contentY += 20
 	} else {
 		rl.DrawTextEx(rl.GetFontDefault(), "QR Code generation failed", rl.NewVector2(modalX+40, modalY+headerHeight+20+contentY), 14, 1, rl.Red)
 		contentY += 20
@@ -597,6 +511,13 @@ func DrawCertificate() {
 	exportBg := rl.NewColor(0, 255, 180, 255)
 	exportBorder := rl.NewColor(50, 255, 200, 255)
 	exportTextColor := rl.NewColor(5, 15, 20, 255)
+	if certificateSigning || certificateSignErr != nil {
+		// Nothing to export until the log is signed
+		exportHover = false
+		exportBg = rl.NewColor(60, 60, 60, 255)
+		exportBorder = rl.NewColor(120, 120, 120, 255)
+		exportTextColor = rl.NewColor(160, 160, 160, 255)
+	}
 	if exportHover {
 		exportBg = rl.NewColor(50, 255, 200, 255)
 		exportBorder = rl.NewColor(100, 255, 220, 255)
@@ -609,7 +530,9 @@ func DrawCertificate() {
 	rl.DrawText("Export PDF", int32(exportRect.X+15), int32(exportRect.Y+9), 16, exportTextColor)
 
 	if rl.IsMouseButtonPressed(rl.MouseLeftButton) && exportHover {
+		certificateMu.Lock()
 		GeneratePDF(certificateLog)
+		certificateMu.Unlock()
 	}
 
 	closeWidth := float32(80)
@@ -637,13 +560,11 @@ func DrawCertificate() {
 	}
 }
 
-func GeneratePDF(log WipeLog) {
+// GeneratePDF renders the certificate into the pdfs folder and returns the
+// path of the written file.
+func GeneratePDF(log wipelog.Log) (string, error) {
 	os.Mkdir("pdfs", 0755)
 
-	jsonBytes, _ := json.Marshal(log)
-	hash := sha256.Sum256(jsonBytes)
-	log.Signature.LogHash = hex.EncodeToString(hash[:])
-
 	pdf := gofpdf.New("P", "mm", "A4", "")
 	pdf.AddPage()
 
@@ -678,6 +599,15 @@ func GeneratePDF(log WipeLog) {
 	pdf.CellFormat(95, 8, fmt.Sprintf("Serial: %s", log.Device.Serial), "1", 1, "L", false, 0, "")
 	pdf.CellFormat(95, 8, fmt.Sprintf("Size: %d GB", log.Device.SizeGB), "1", 0, "L", false, 0, "")
 	pdf.CellFormat(95, 8, fmt.Sprintf("Type: %s", log.Device.Type), "1", 1, "L", false, 0, "")
+	if log.Device.Path != "" && log.Device.Path != log.Device.Name {
+		pdf.CellFormat(190, 8, fmt.Sprintf("Path: %s", log.Device.Path), "1", 1, "L", false, 0, "")
+	}
+	if c := log.Device.Container; c != nil {
+		pdf.CellFormat(190, 8, fmt.Sprintf("On drive: %s (%s, serial %s)", c.Device, c.Model, c.Serial), "1", 1, "L", false, 0, "")
+	}
+	if log.Device.Fingerprint != "" {
+		pdf.CellFormat(190, 8, fmt.Sprintf("Drive ID: %s", log.Device.Fingerprint), "1", 1, "L", false, 0, "")
+	}
 	pdf.Ln(6)
 
 	
@@ -691,34 +621,93 @@ func GeneratePDF(log WipeLog) {
 	pdf.Ln(6)
 
 	
+	if h := log.Health; h != nil {
+		sectionHeader("Drive Health (before wipe)")
+		for _, line := range healthLines(*h) {
+			pdf.MultiCell(0, 8, line, "1", "L", false)
+		}
+		pdf.Ln(6)
+	}
+
+	if u := log.USB; u != nil {
+		sectionHeader("USB Identity")
+		for _, line := range usbLines(*u) {
+			pdf.MultiCell(0, 8, line, "1", "L", false)
+		}
+		pdf.Ln(6)
+	}
+
+	if c := log.Capacity; c != nil {
+		sectionHeader("Capacity Check (before wipe)")
+		if c.Fake() {
+			pdf.SetTextColor(200, 0, 0)
+		}
+		for _, line := range capacityLines(*c) {
+			pdf.MultiCell(0, 8, line, "1", "L", false)
+		}
+		pdf.SetTextColor(0, 0, 0)
+		pdf.Ln(6)
+	}
+
+	if fs := log.Filesystem; fs != nil {
+		sectionHeader("Filesystem")
+		for _, line := range filesystemLines(*fs) {
+			pdf.MultiCell(0, 8, line, "1", "L", false)
+		}
+		pdf.Ln(6)
+	}
+
+	if e := log.Execution; e != nil {
+		sectionHeader("Execution")
+		for _, line := range executionLines(*e) {
+			if strings.HasPrefix(line, "Error") {
+				pdf.SetTextColor(200, 0, 0)
+			}
+			pdf.MultiCell(0, 8, line, "1", "L", false)
+			pdf.SetTextColor(0, 0, 0)
+		}
+		pdf.Ln(6)
+	}
+
 	sectionHeader("System Information")
-	pdf.CellFormat(95, 8, fmt.Sprintf("Tool Version: %s", log.System.ToolVersion), "1", 0, "L", false, 0, "")
-	pdf.CellFormat(95, 8, fmt.Sprintf("Host OS: %s", log.System.HostOS), "1", 1, "L", false, 0, "")
+	for _, line := range systemLines(log.System.Info) {
+		pdf.MultiCell(0, 8, line, "1", "L", false)
+	}
 	pdf.CellFormat(190, 8, fmt.Sprintf("Executed By: %s", log.System.ExecutedBy), "1", 1, "L", false, 0, "")
 	pdf.Ln(6)
 
 	
 	sectionHeader("Signature & Verification")
 	pdf.MultiCell(0, 8, fmt.Sprintf("Algorithm: %s", log.Signature.Algorithm), "1", "L", false)
+	pdf.MultiCell(0, 8, fmt.Sprintf("Key ID: %s", log.Signature.KeyID), "1", "L", false)
 	pdf.MultiCell(0, 8, fmt.Sprintf("Signature: %s", log.Signature.Sig), "1", "L", false)
 	pdf.MultiCell(0, 8, fmt.Sprintf("Public Key Fingerprint: %s", log.Signature.PublicKeyFingerprint), "1", "L", false)
 	pdf.MultiCell(0, 8, fmt.Sprintf("Log Hash (SHA256): %s", log.Signature.LogHash), "1", "L", false)
+	for _, line := range chainLines(log.Signature.Chain) {
+		pdf.MultiCell(0, 8, line, "1", "L", false)
+	}
+	for _, line := range timestampLines(log.Signature.Timestamp) {
+		pdf.MultiCell(0, 8, line, "1", "L", false)
+	}
 	pdf.Ln(6)
 
 	
 	sectionHeader("QR Code - Verification")
-	qr, err := qrcode.New(string(jsonBytes), qrcode.Medium)
+	qr, url, err := verificationQR(log)
 	if err == nil {
-		qrImg := qr.Image(128)
-		qrPath := "temp_qr.png"
-		file, _ := os.Create(qrPath)
-		png.Encode(file, qrImg)
-		file.Close()
-		pdf.Image(qrPath, 80, pdf.GetY()+5, 50, 50, false, "", 0, "")
-		os.Remove(qrPath)
+		// Registered from memory: a file in the working directory would be
+		// shared by certificates generated at the same time
+		var qrPNG bytes.Buffer
+		png.Encode(&qrPNG, qr.Image(128))
+		qrOptions := gofpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader("qr", qrOptions, &qrPNG)
+		pdf.ImageOptions("qr", 80, pdf.GetY()+5, 50, 50, false, qrOptions, 0, "")
 		pdf.Ln(60)
 		pdf.SetFont("Arial", "I", 10)
 		pdf.CellFormat(0, 8, "Scan this QR to verify wipe log authenticity", "", 1, "C", false, 0, "")
+		base, _, _ := strings.Cut(url, "#")
+		pdf.SetFont("Arial", "", 8)
+		pdf.CellFormat(0, 6, "Verifier: "+base, "", 1, "C", false, 0, "")
 	}
 
 	
@@ -728,63 +717,258 @@ func GeneratePDF(log WipeLog) {
 	pdf.CellFormat(0, 10, "Confidential - Generated by Secure Wipe Tool", "", 0, "C", false, 0, "")
 
 	
-	fileName := fmt.Sprintf("pdfs/wipe_certificate_%s.pdf", strings.Replace(log.Wipe.StartedAt, ":", "-", -1))
-	err = pdf.OutputFileAndClose(fileName)
+	// Embed the signed payload where CertificateTOOL's verifier and
+	// cmd/verify look for it: the /CertPayload entry and an attached file
+	data, err := embedPayload(pdf, log)
 	if err != nil {
 		fmt.Printf("PDF generation failed: %v\n", err)
-	} else {
-		fmt.Printf("Saved PDF to %s\n", fileName)
+		return "", err
+	}
+	fileName, err := writeNewFile(fmt.Sprintf("pdfs/wipe_certificate_%s", strings.Replace(log.Wipe.StartedAt, ":", "-", -1)), ".pdf", data)
+	if err != nil {
+		fmt.Printf("PDF generation failed: %v\n", err)
+		return "", err
+	}
+	fmt.Printf("Saved PDF to %s\n", fileName)
+	return fileName, nil
+}
+
+// writeNewFile writes data to base+ext, or base_2+ext and so on when that
+// exists: wipes started in the same second must not overwrite each
+// other's certificates.
+func writeNewFile(base, ext string, data []byte) (string, error) {
+	name := base + ext
+	for n := 2; ; n++ {
+		file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			name = fmt.Sprintf("%s_%d%s", base, n, ext)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = file.Write(data)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return name, err
+	}
+}
+
+// embedPayload renders pdf with the log's signed payload attached and in
+// the document information
+func embedPayload(pdf *gofpdf.Fpdf, log wipelog.Log) ([]byte, error) {
+	payload, err := log.Payload()
+	if err != nil {
+		return nil, err
+	}
+	payloadJSON, err := certs.PayloadJSON(payload)
+	if err != nil {
+		return nil, err
+	}
+	pdf.SetAttachments([]gofpdf.Attachment{{Content: payloadJSON, Filename: certs.PayloadFileName}})
+	var rendered bytes.Buffer
+	if err := pdf.Output(&rendered); err != nil {
+		return nil, err
+	}
+	return certs.EmbedPayload(rendered.Bytes(), payload)
+}
+
+// healthLines summarises a health snapshot for the certificate dialog and PDF
+func healthLines(h drivers.HealthReport) []string {
+	status := "PASSED"
+	if !h.Passed {
+		status = "FAILED"
+	}
+	lines := []string{fmt.Sprintf("SMART self-assessment: %s (%s, checked %s)", status, h.Protocol, h.CheckedAt.Format(time.RFC3339))}
+	lines = append(lines, fmt.Sprintf("Power-on hours: %d, reallocated: %d, pending: %d, media errors: %d",
+		h.PowerOnHours, h.ReallocatedSectors, h.PendingSectors, h.MediaErrors))
+	if h.PercentageUsed >= 0 {
+		lines = append(lines, fmt.Sprintf("Endurance used: %d%%", h.PercentageUsed))
+	}
+	for _, w := range h.Warnings {
+		lines = append(lines, "Warning: "+w)
+	}
+	return lines
+}
+
+// usbLines summarises a USB identity for the certificate dialog and PDF
+func usbLines(u drivers.USBInfo) []string {
+	device := strings.TrimSpace(u.Manufacturer + " " + u.Product)
+	if device == "" {
+		device = "unnamed device"
+	}
+	lines := []string{fmt.Sprintf("USB ID %s: %s", u.ID(), device)}
+	link := u.SpeedName()
+	if u.Driver != "" {
+		link += ", driver " + u.Driver
+	}
+	lines = append(lines, "Link: "+link)
+	if u.Bridge != "" {
+		lines = append(lines, "Bridge/controller: "+u.Bridge)
+	}
+	if u.Serial != "" {
+		lines = append(lines, "USB serial: "+u.Serial)
+	}
+	return lines
+}
+
+// capacityLines summarises a fake-capacity probe for the certificate dialog
+// and PDF
+func capacityLines(c drivers.CapacityReport) []string {
+	if !c.Fake() {
+		return []string{fmt.Sprintf("Full reported capacity of %s holds data (%d blocks probed, %s)",
+			formatBytes(c.ReportedBytes), c.ProbedBlocks, c.CheckedAt.Format(time.RFC3339))}
+	}
+	return []string{
+		fmt.Sprintf("WARNING: FAKE CAPACITY. Reports %s but only %s is usable", formatBytes(c.ReportedBytes), formatBytes(c.UsableBytes)),
+		fmt.Sprintf("%d of %d probed blocks lost their data (%s)", c.BadBlocks, c.ProbedBlocks, c.CheckedAt.Format(time.RFC3339)),
+		"Writes past the usable size wrap onto earlier blocks; the overwrite cannot cover the advertised capacity",
+	}
+}
+
+// filesystemLines summarises a filesystem snapshot for the certificate
+// dialog and PDF
+func filesystemLines(fs drivers.FilesystemInfo) []string {
+	mount := fs.MountPoint
+	if fs.Type != "" {
+		mount += " (" + fs.Type + ")"
+	}
+	lines := []string{"Mounted at: " + mount}
+	lines = append(lines, fmt.Sprintf("Capacity: %s total, %s used, %s free", formatBytes(fs.Total), formatBytes(fs.Used), formatBytes(fs.Available)))
+	if fs.Inodes > 0 {
+		lines = append(lines, fmt.Sprintf("Inodes: %d of %d free", fs.InodesFree, fs.Inodes))
+	}
+	if len(fs.Options) > 0 {
+		lines = append(lines, "Options: "+strings.Join(fs.Options, ","))
+	}
+	return lines
+}
+
+// executionLines summarises what a wipe run did for the certificate dialog
+// and PDF. Failures come last, each starting with "Error".
+func executionLines(e wipelog.Execution) []string {
+	lines := []string{"Engines: " + strings.Join(e.Engines, ", ")}
+	for _, p := range e.Passes {
+		written := formatBytes(p.Bytes)
+		if p.Estimated {
+			written += " (file sizes)"
+		}
+		lines = append(lines, fmt.Sprintf("Pass %d (%s, %s): %s", p.Number, p.Engine, p.Pattern, written))
+	}
+	lines = append(lines, fmt.Sprintf("Items: %d files, %d directories, %d skipped", e.Files, e.Dirs, e.Skipped))
+	lines = append(lines, fmt.Sprintf("Ran as: %s, pid %d", e.Host.User, e.Host.PID))
+	for _, te := range e.Errors {
+		if te.Path != "" {
+			lines = append(lines, fmt.Sprintf("Error at %s: %s", te.Path, te.Error))
+		} else {
+			lines = append(lines, "Error: "+te.Error)
+		}
+	}
+	return lines
+}
+
+// chainLines says who certified the signing key, for the certificate
+// dialog and PDF. A key without a chain is only known by its ID.
+func chainLines(x5c []string) []string {
+	chain, err := certs.ParseChain(x5c)
+	if err != nil {
+		return []string{fmt.Sprintf("Certificate chain: unreadable (%v)", err)}
+	}
+	if len(chain) == 0 {
+		return []string{"Certified by: nobody (bare key; verifiers must trust the key ID)"}
+	}
+	leaf := chain[0]
+	return []string{
+		"Key Certificate: " + leaf.Subject.String(),
+		fmt.Sprintf("Certified by: %s, valid %s to %s", leaf.Issuer.String(), leaf.NotBefore.Format("2006-01-02"), leaf.NotAfter.Format("2006-01-02")),
 	}
 }
 
-// Helper function to create a complete sample log for demo
-func CreateSampleWipeLog() WipeLog {
-	return WipeLog{
-		Device: struct {
-			Name   string `json:"name"`
-			Serial string `json:"serial"`
-			SizeGB int    `json:"size_gb"`
-			Type   string `json:"type"`
-		}{
-			Name:   "", // Will be populated by DetectDeviceInfo
-			Serial: "", // Will be populated by DetectDeviceInfo
-			SizeGB: 0,  // Will be populated by DetectDeviceInfo
-			Type:   "", // Will be populated by DetectDeviceInfo
-		},
-		Wipe: struct {
-			Method      string `json:"method"`
-			NistLevel   string `json:"nist_level"`
-			Status      string `json:"status"`
-			StartedAt   string `json:"started_at"`
-			FinishedAt  string `json:"finished_at"`
-			DurationSec int    `json:"duration_sec"`
-		}{
-			Method:      "DoD 5220.22-M (3-pass)",
-			NistLevel:   "Clear",
-			Status:      "Completed Successfully",
-			StartedAt:   "2024-03-15T10:30:00Z",
-			FinishedAt:  "2024-03-15T14:45:30Z",
-			DurationSec: 15330,
-		},
-		System: struct {
-			ToolVersion string `json:"tool_version"`
-			HostOS      string `json:"host_os"`
-			ExecutedBy  string `json:"executed_by"`
-		}{
-			ToolVersion: "SecureWipe v2.1.0",
-			HostOS:      runtime.GOOS + " " + runtime.GOARCH,
-			ExecutedBy:  "admin",
-		},
-		Signature: struct {
-			Algorithm            string `json:"algorithm"`
-			Sig                  string `json:"sig"`
-			PublicKeyFingerprint string `json:"public_key_fingerprint"`
-			LogHash              string `json:"log_hash"`
-		}{
-			Algorithm:            "",
-			Sig:                  "",
-			PublicKeyFingerprint: "",
-			LogHash:              "",
-		},
-	}
-}
\ No newline at end of file
+// timestampLines says whether a TSA vouched for when the log was signed,
+// for the certificate dialog and PDF
+func timestampLines(ts *certs.Timestamp) []string {
+	if ts == nil {
+		return []string{"Timestamp: none; times are from the workstation clock"}
+	}
+	if ts.Status != certs.Stamped {
+		return []string{fmt.Sprintf("Timestamp: UNSTAMPED (%s); times are from the workstation clock", ts.Error)}
+	}
+	lines := []string{fmt.Sprintf("Timestamp: %s by %s (RFC 3161)", ts.Time, ts.TSA)}
+	token, err := base64.StdEncoding.DecodeString(ts.Token)
+	if err == nil {
+		var info *certs.TimestampInfo
+		if info, err = certs.ParseTimestamp(token); err == nil {
+			lines = append(lines, fmt.Sprintf("TSA Certificate: %s, token serial %s", info.Signer.Subject.String(), info.Serial))
+		}
+	}
+	if err != nil {
+		lines = append(lines, fmt.Sprintf("Timestamp token: unreadable (%v)", err))
+	}
+	return lines
+}
+
+// systemLines summarises the machine and build for the certificate dialog,
+// PDF and About screen
+func systemLines(info sysinfo.Info) []string {
+	version := info.ToolVersion
+	if info.Revision != "" {
+		revision := info.Revision[:min(12, len(info.Revision))]
+		if info.Modified {
+			revision += ", modified"
+		}
+		version += " (" + revision + ")"
+	}
+	if info.GoVersion != "" {
+		version += ", built with " + info.GoVersion
+	}
+	lines := []string{"Tool Version: " + version, "Host OS: " + info.HostOS}
+	if info.Kernel != "" {
+		lines = append(lines, "Kernel: "+info.Kernel)
+	}
+	lines = append(lines, fmt.Sprintf("Hostname: %s   Arch: %s", info.Hostname, info.Arch))
+	if info.MachineIDHash != "" {
+		lines = append(lines, "Machine ID (hashed): "+info.MachineIDHash)
+	}
+	return lines
+}
+
+// filesystemForLog snapshots the filesystem holding path for a wipe log.
+// A purged file is gone by the time its log is written, so its directory
+// stands in for it.
+func filesystemForLog(path string) *drivers.FilesystemInfo {
+	fs, err := drivers.FilesystemFor(path)
+	if err != nil {
+		if fs, err = drivers.FilesystemFor(filepath.Dir(path)); err != nil {
+			return nil
+		}
+	}
+	return &fs
+}
+
+// recordExecution stores a finished run's trace in log. The wipe method is
+// taken from the engines that ran, so a plain delete reads "unlink". err is
+// the run's outcome; it is added when it failed before any driver traced it.
+func recordExecution(log *wipelog.Log, trace drivers.Trace, err error) {
+	if err != nil && len(trace.Errors) == 0 {
+		trace.Errors = append(trace.Errors, drivers.TraceError{Path: log.Device.Path, Error: err.Error()})
+	}
+	log.Execution = &wipelog.Execution{Trace: trace, Host: collectHostFacts()}
+	log.Wipe.Method = trace.Method()
+}
+
+// collectHostFacts reads the facts of this process
+func collectHostFacts() wipelog.HostFacts {
+	facts := wipelog.HostFacts{User: os.Getenv("USER"), PID: os.Getpid()}
+	if u, err := user.Current(); err == nil {
+		facts.User = u.Username
+		facts.UID = u.Uid
+	}
+	return facts
+}
+
+// recordSystem describes this machine and build in log
+func recordSystem(log *wipelog.Log, executedBy string) {
+	log.System.Info = sysinfo.Collect()
+	log.System.ExecutedBy = executedBy
+}
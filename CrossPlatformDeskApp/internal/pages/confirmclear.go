@@ -1,11 +1,8 @@
 package pages
 
 import (
-	"crypto/ed25519"
-	"crypto/sha256"
 	"data_wiper/internal/drivers"
-	"encoding/base64"
-	"encoding/json"
+	"data_wiper/internal/wipelog"
 	"fmt"
 	"math"
 	"os"
@@ -262,81 +259,7 @@ func DrawConfirmClear() {
 	rl.DrawText("Clear Item", int32(clearRect.X+15), int32(clearRect.Y+9), 16, clearTextColor)
 
 	if rl.IsMouseButtonPressed(rl.MouseLeftButton) && clearHover && canClear {
-		start := time.Now()
-		err := drivers.ClearItem(clearTargetName)
-		status := "success"
-		if err != nil {
-			status = "failure"
-			fmt.Printf("Clear failed: %v\n", err)
-		}
-		finished := time.Now()
-		duration := int(finished.Sub(start).Seconds())
-
-		var log WipeLog
-		log.Wipe.Method = "overwrite"
-		log.Wipe.NistLevel = "clear"
-		log.Wipe.Status = status
-		log.Wipe.StartedAt = start.UTC().Format(time.RFC3339)
-		log.Wipe.FinishedAt = finished.UTC().Format(time.RFC3339)
-		log.Wipe.DurationSec = duration
-		log.System.ToolVersion = "v1.0"
-		log.System.HostOS = "Ubuntu 22.04"
-		log.System.ExecutedBy = os.Getenv("USER")
-
-		isDevice := strings.HasPrefix(clearTargetName, "/dev/")
-		if isDevice {
-			devInfo, err := getDeviceInfo(clearTargetName)
-			if err == nil {
-				log.Device.Name = devInfo["name"].(string)
-				log.Device.Serial = devInfo["serial"].(string)
-				log.Device.SizeGB = devInfo["size_gb"].(int)
-				log.Device.Type = devInfo["type"].(string)
-			}
-		} else {
-			fi, err := os.Stat(clearTargetName)
-			if err == nil {
-				log.Device.Name = clearTargetName
-				log.Device.Serial = ""
-				log.Device.SizeGB = int(fi.Size() / 1000000000)
-				log.Device.Type = "file"
-			}
-		}
-
-		
-		temp := struct {
-			Device struct {
-				Name   string `json:"name"`
-				Serial string `json:"serial"`
-				SizeGB int    `json:"size_gb"`
-				Type   string `json:"type"`
-			} `json:"device"`
-			Wipe struct {
-				Method      string `json:"method"`
-				NistLevel   string `json:"nist_level"`
-				Status      string `json:"status"`
-				StartedAt   string `json:"started_at"`
-				FinishedAt  string `json:"finished_at"`
-				DurationSec int    `json:"duration_sec"`
-			} `json:"wipe"`
-			System struct {
-				ToolVersion string `json:"tool_version"`
-				HostOS      string `json:"host_os"`
-				ExecutedBy  string `json:"executed_by"`
-			} `json:"system"`
-		}{
-			Device: log.Device,
-			Wipe:   log.Wipe,
-			System: log.System,
-		}
-		jsonBytes, _ := json.Marshal(temp)
-		sig := ed25519.Sign(privateKey, jsonBytes)
-		log.Signature.Algorithm = "Ed25519"
-		log.Signature.Sig = base64.StdEncoding.EncodeToString(sig)
-		hash := sha256.Sum256(publicKey)
-		log.Signature.PublicKeyFingerprint = fmt.Sprintf("%x", hash[:])
-
-		ShowCertificate(log)
-		HideConfirmClear()
+		executeClear()
 		return
 	}
 
@@ -344,80 +267,37 @@ func DrawConfirmClear() {
 		HideConfirmClear()
 	}
 	if rl.IsKeyPressed(rl.KeyEnter) && canClear {
-		start := time.Now()
-		err := drivers.ClearItem(clearTargetName)
-		status := "success"
-		if err != nil {
-			status = "failure"
-			fmt.Printf("Clear failed: %v\n", err)
-		}
-		finished := time.Now()
-		duration := int(finished.Sub(start).Seconds())
-
-		var log WipeLog
-		log.Wipe.Method = "overwrite"
-		log.Wipe.NistLevel = "clear"
-		log.Wipe.Status = status
-		log.Wipe.StartedAt = start.UTC().Format(time.RFC3339)
-		log.Wipe.FinishedAt = finished.UTC().Format(time.RFC3339)
-		log.Wipe.DurationSec = duration
-		log.System.ToolVersion = "v1.0"
-		log.System.HostOS = "Ubuntu 22.04"
-		log.System.ExecutedBy = os.Getenv("USER")
-
-		isDevice := strings.HasPrefix(clearTargetName, "/dev/")
-		if isDevice {
-			devInfo, err := getDeviceInfo(clearTargetName)
-			if err == nil {
-				log.Device.Name = devInfo["name"].(string)
-				log.Device.Serial = devInfo["serial"].(string)
-				log.Device.SizeGB = devInfo["size_gb"].(int)
-				log.Device.Type = devInfo["type"].(string)
-			}
-		} else {
-			fi, err := os.Stat(clearTargetName)
-			if err == nil {
-				log.Device.Name = clearTargetName
-				log.Device.Serial = ""
-				log.Device.SizeGB = int(fi.Size() / 1000000000)
-				log.Device.Type = "file"
-			}
-		}
+		executeClear()
+	}
+}
 
-		
-		temp := struct {
-			Device struct {
-				Name   string `json:"name"`
-				Serial string `json:"serial"`
-				SizeGB int    `json:"size_gb"`
-				Type   string `json:"type"`
-			} `json:"device"`
-			Wipe struct {
-				Method      string `json:"method"`
-				NistLevel   string `json:"nist_level"`
-				Status      string `json:"status"`
-				StartedAt   string `json:"started_at"`
-				FinishedAt  string `json:"finished_at"`
-				DurationSec int    `json:"duration_sec"`
-			} `json:"wipe"`
-			System struct {
-				ToolVersion string `json:"tool_version"`
-				HostOS      string `json:"host_os"`
-				ExecutedBy  string `json:"executed_by"`
-			} `json:"system"`
-		}{
-			Device: log.Device,
-			Wipe:   log.Wipe,
-			System: log.System,
-		}
-		jsonBytes, _ := json.Marshal(temp)
-		sig := ed25519.Sign(privateKey, jsonBytes)
-		log.Signature.Algorithm = "Ed25519"
-		log.Signature.Sig = base64.StdEncoding.EncodeToString(sig)
-		hash := sha256.Sum256(publicKey)
-		log.Signature.PublicKeyFingerprint = fmt.Sprintf("%x", hash[:])
-
-		ShowCertificate(log)
-		HideConfirmClear()
+// executeClear runs the confirmed clear, signs the resulting log and hands
+// it to the certificate dialog.
+func executeClear() {
+	// Described first: what a path names can change once it is wiped
+	var log wipelog.Log
+	describeTarget(&log, clearTargetName)
+
+	start := time.Now()
+	var trace drivers.Trace
+	err := drivers.ClearItemTraced(clearTargetName, &trace)
+	status := "success"
+	if err != nil {
+		status = "failure"
+		fmt.Printf("Clear failed: %v\n", err)
 	}
+	finished := time.Now()
+	duration := int(finished.Sub(start).Seconds())
+
+	recordExecution(&log, trace, err)
+	log.Wipe.NistLevel = "clear"
+	log.Wipe.Status = status
+	log.Wipe.StartedAt = start.UTC().Format(time.RFC3339)
+	log.Wipe.FinishedAt = finished.UTC().Format(time.RFC3339)
+	log.Wipe.DurationSec = duration
+	recordSystem(&log, os.Getenv("USER"))
+	log.Filesystem = filesystemForLog(clearTargetName)
+
+	ShowCertificate(log)
+	HideConfirmClear()
 }
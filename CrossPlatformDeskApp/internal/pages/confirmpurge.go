@@ -1,19 +1,14 @@
 package pages
 
 import (
-	"crypto/ed25519"
-	"crypto/sha256"
-	"encoding/base64"
-	"encoding/json"
+    "data_wiper/internal/drivers"
+	"data_wiper/internal/wipelog"
 	"fmt"
+	rl "github.com/gen2brain/raylib-go/raylib"
 	"math"
 	"os"
-	"os/exec"
-	"strconv"
 	"strings"
 	"time"
-    "data_wiper/internal/drivers"
-	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 var (
@@ -22,6 +17,8 @@ var (
 	purgeConfirmText   string
 	purgeTextActive    bool    = false
 	purgeAnimationTime float32 = 0
+	purgeProfile       *drivers.SweepProfile
+	purgeLinked        bool = true
 )
 
 const requiredPurgeText = "DELETE"
@@ -32,6 +29,14 @@ func ShowConfirmPurge(itemName string) {
 	purgeConfirmText = ""
 	purgeTextActive = false
 	purgeAnimationTime = 0
+	purgeProfile = nil
+	purgeLinked = true
+}
+
+// ShowConfirmSweep opens the purge dialog for a whole sweep profile.
+func ShowConfirmSweep(profile drivers.SweepProfile) {
+	ShowConfirmPurge("Sweep: " + profile.Name)
+	purgeProfile = &profile
 }
 
 func HideConfirmPurge() {
@@ -40,6 +45,7 @@ func HideConfirmPurge() {
 	purgeConfirmText = ""
 	purgeTextActive = false
 	purgeAnimationTime = 0
+	purgeProfile = nil
 }
 
 func IsConfirmPurgeActive() bool {
@@ -216,6 +222,20 @@ func DrawConfirmPurge() {
 	buttonY := inputY + 60
 	buttonHeight := float32(35)
 
+	if purgeProfile == nil {
+		checkRect := rl.NewRectangle(modalX+20, buttonY+8, 18, 18)
+		checkHover := rl.CheckCollisionPointRec(mouse, checkRect)
+		rl.DrawRectangleRoundedLines(checkRect, 0.2, 1, rl.NewColor(0, 255, 180, 255))
+		if purgeLinked {
+			rl.DrawRectangle(int32(checkRect.X+4), int32(checkRect.Y+4), 10, 10, rl.NewColor(0, 255, 180, 255))
+		}
+		rl.DrawText("Also purge thumbnails, recent", int32(checkRect.X+26), int32(checkRect.Y-4), 12, rl.NewColor(200, 200, 200, 255))
+		rl.DrawText("entries and editor backups", int32(checkRect.X+26), int32(checkRect.Y+10), 12, rl.NewColor(200, 200, 200, 255))
+		if rl.IsMouseButtonPressed(rl.MouseLeftButton) && checkHover {
+			purgeLinked = !purgeLinked
+		}
+	}
+
 	cancelWidth := float32(80)
 	cancelRect := rl.NewRectangle(modalX+modalWidth-cancelWidth-120, buttonY, cancelWidth, buttonHeight)
 	cancelHover := rl.CheckCollisionPointRec(mouse, cancelRect)
@@ -266,81 +286,7 @@ func DrawConfirmPurge() {
 	rl.DrawText("Purge Item", int32(purgeRect.X+15), int32(purgeRect.Y+9), 16, purgeTextColor)
 
 	if rl.IsMouseButtonPressed(rl.MouseLeftButton) && purgeHover && canPurge {
-		start := time.Now()
-		err := drivers.PurgeItem(purgeTargetName)
-		status := "success"
-		if err != nil {
-			status = "failure"
-			fmt.Printf("Purge failed: %v\n", err)
-		}
-		finished := time.Now()
-		duration := int(finished.Sub(start).Seconds())
-
-		var log WipeLog
-		log.Wipe.Method = "secure_erase"
-		log.Wipe.NistLevel = "purge"
-		log.Wipe.Status = status
-		log.Wipe.StartedAt = start.UTC().Format(time.RFC3339)
-		log.Wipe.FinishedAt = finished.UTC().Format(time.RFC3339)
-		log.Wipe.DurationSec = duration
-		log.System.ToolVersion = "v1.0"
-		log.System.HostOS = "Ubuntu 22.04"
-		log.System.ExecutedBy = os.Getenv("USER")
-
-		isDevice := strings.HasPrefix(purgeTargetName, "/dev/")
-		if isDevice {
-			devInfo, err := getDeviceInfo(purgeTargetName)
-			if err == nil {
-				log.Device.Name = devInfo["name"].(string)
-				log.Device.Serial = devInfo["serial"].(string)
-				log.Device.SizeGB = devInfo["size_gb"].(int)
-				log.Device.Type = devInfo["type"].(string)
-			}
-		} else {
-			fi, err := os.Stat(purgeTargetName)
-			if err == nil {
-				log.Device.Name = purgeTargetName
-				log.Device.Serial = ""
-				log.Device.SizeGB = int(fi.Size() / 1000000000)
-				log.Device.Type = "file"
-			}
-		}
-
-		
-		temp := struct {
-			Device struct {
-				Name   string `json:"name"`
-				Serial string `json:"serial"`
-				SizeGB int    `json:"size_gb"`
-				Type   string `json:"type"`
-			} `json:"device"`
-			Wipe struct {
-				Method      string `json:"method"`
-				NistLevel   string `json:"nist_level"`
-				Status      string `json:"status"`
-				StartedAt   string `json:"started_at"`
-				FinishedAt  string `json:"finished_at"`
-				DurationSec int    `json:"duration_sec"`
-			} `json:"wipe"`
-			System struct {
-				ToolVersion string `json:"tool_version"`
-				HostOS      string `json:"host_os"`
-				ExecutedBy  string `json:"executed_by"`
-			} `json:"system"`
-		}{
-			Device: log.Device,
-			Wipe:   log.Wipe,
-			System: log.System,
-		}
-		jsonBytes, _ := json.Marshal(temp)
-		sig := ed25519.Sign(privateKey, jsonBytes)
-		log.Signature.Algorithm = "Ed25519"
-		log.Signature.Sig = base64.StdEncoding.EncodeToString(sig)
-		hash := sha256.Sum256(publicKey)
-		log.Signature.PublicKeyFingerprint = fmt.Sprintf("%x", hash[:])
-
-		ShowCertificate(log)
-		HideConfirmPurge()
+		executePurge()
 		return
 	}
 
@@ -348,128 +294,53 @@ func DrawConfirmPurge() {
 		HideConfirmPurge()
 	}
 	if rl.IsKeyPressed(rl.KeyEnter) && canPurge {
-		start := time.Now()
-		err := drivers.PurgeItem(purgeTargetName)
-		status := "success"
-		if err != nil {
-			status = "failure"
-			fmt.Printf("Purge failed: %v\n", err)
-		}
-		finished := time.Now()
-		duration := int(finished.Sub(start).Seconds())
-
-		var log WipeLog
-		log.Wipe.Method = "secure_erase"
-		log.Wipe.NistLevel = "purge"
-		log.Wipe.Status = status
-		log.Wipe.StartedAt = start.UTC().Format(time.RFC3339)
-		log.Wipe.FinishedAt = finished.UTC().Format(time.RFC3339)
-		log.Wipe.DurationSec = duration
-		log.System.ToolVersion = "v1.0"
-		log.System.HostOS = "Ubuntu 22.04"
-		log.System.ExecutedBy = os.Getenv("USER")
-
-		isDevice := strings.HasPrefix(purgeTargetName, "/dev/")
-		if isDevice {
-			devInfo, err := getDeviceInfo(purgeTargetName)
-			if err == nil {
-				log.Device.Name = devInfo["name"].(string)
-				log.Device.Serial = devInfo["serial"].(string)
-				log.Device.SizeGB = devInfo["size_gb"].(int)
-				log.Device.Type = devInfo["type"].(string)
-			}
-		} else {
-			fi, err := os.Stat(purgeTargetName)
-			if err == nil {
-				log.Device.Name = purgeTargetName
-				log.Device.Serial = ""
-				log.Device.SizeGB = int(fi.Size() / 1000000000)
-				log.Device.Type = "file"
-			}
-		}
-
-		// Sign the log
-		temp := struct {
-			Device struct {
-				Name   string `json:"name"`
-				Serial string `json:"serial"`
-				SizeGB int    `json:"size_gb"`
-				Type   string `json:"type"`
-			} `json:"device"`
-			Wipe struct {
-				Method      string `json:"method"`
-				NistLevel   string `json:"nist_level"`
-				Status      string `json:"status"`
-				StartedAt   string `json:"started_at"`
-				FinishedAt  string `json:"finished_at"`
-				DurationSec int    `json:"duration_sec"`
-			} `json:"wipe"`
-			System struct {
-				ToolVersion string `json:"tool_version"`
-				HostOS      string `json:"host_os"`
-				ExecutedBy  string `json:"executed_by"`
-			} `json:"system"`
-		}{
-			Device: log.Device,
-			Wipe:   log.Wipe,
-			System: log.System,
-		}
-		jsonBytes, _ := json.Marshal(temp)
-		sig := ed25519.Sign(privateKey, jsonBytes)
-		log.Signature.Algorithm = "Ed25519"
-		log.Signature.Sig = base64.StdEncoding.EncodeToString(sig)
-		hash := sha256.Sum256(publicKey)
-		log.Signature.PublicKeyFingerprint = fmt.Sprintf("%x", hash[:])
-
-		ShowCertificate(log)
-		HideConfirmPurge()
+		executePurge()
 	}
 }
 
-func getDeviceInfo(path string) (map[string]interface{}, error) {
-	cmd := exec.Command("udevadm", "info", "--query=property", "--name", path)
-	out, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-	lines := strings.Split(string(out), "\n")
-	info := make(map[string]string)
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) == 2 {
-			info[parts[0]] = parts[1]
-		}
+// executePurge runs the confirmed purge, signs the resulting log and hands it
+// to the certificate dialog. A sweep profile, when set, is purged as a whole
+// and yields a single certificate.
+func executePurge() {
+	// Described first: a purged file cannot be looked up afterwards
+	var log wipelog.Log
+	if purgeProfile != nil {
+		log.Device.Name = purgeTargetName
+		log.Device.Type = "sweep profile"
+	} else {
+		describeTarget(&log, purgeTargetName)
+		log.Filesystem = filesystemForLog(purgeTargetName)
 	}
 
-	sizeCmd := exec.Command("lsblk", "-b", "-o", "SIZE", "-n", path)
-	sizeOut, err := sizeCmd.Output()
-	if err != nil {
-		return nil, err
+	start := time.Now()
+	var err error
+	var report drivers.PurgeReport
+	if purgeProfile != nil {
+		report, err = drivers.PurgeSweepProfile(*purgeProfile)
+		fmt.Printf("Sweep %s: %d purged, %d failed\n", purgeProfile.Name, len(report.Purged), len(report.Failures))
+		sweepLoaded = false
+	} else {
+		report, err = drivers.PurgeItemWithOptions(purgeTargetName, drivers.PurgeOptions{LinkedArtifacts: purgeLinked})
+		if len(report.Linked) > 0 {
+			fmt.Printf("Purged %d linked artifacts of %s\n", len(report.Linked), purgeTargetName)
+		}
 	}
-	sizeBytes, _ := strconv.ParseInt(strings.TrimSpace(string(sizeOut)), 10, 64)
-	sizeGB := int(sizeBytes / 1000000000)
-
-	rotaCmd := exec.Command("lsblk", "-o", "ROTA", "-n", path)
-	rotaOut, err := rotaCmd.Output()
+	status := "success"
 	if err != nil {
-		return nil, err
+		status = "failure"
+		fmt.Printf("Purge failed: %v\n", err)
 	}
-	rotaStr := strings.TrimSpace(string(rotaOut))
-	rotaInt, _ := strconv.Atoi(rotaStr)
-	typ := "ssd"
-	if rotaInt == 1 {
-		typ = "hdd"
-	}
-
-	return map[string]interface{}{
-		"name":    info["ID_MODEL"],
-		"serial":  info["ID_SERIAL_SHORT"],
-		"size_gb": sizeGB,
-		"type":    typ,
-	}, nil
+	finished := time.Now()
+	duration := int(finished.Sub(start).Seconds())
+
+	recordExecution(&log, report.Trace, err)
+	log.Wipe.NistLevel = "purge"
+	log.Wipe.Status = status
+	log.Wipe.StartedAt = start.UTC().Format(time.RFC3339)
+	log.Wipe.FinishedAt = finished.UTC().Format(time.RFC3339)
+	log.Wipe.DurationSec = duration
+	recordSystem(&log, os.Getenv("USER"))
+
+	ShowCertificate(log)
+	HideConfirmPurge()
 }
-
-
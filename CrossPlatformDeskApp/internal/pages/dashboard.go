@@ -21,7 +21,9 @@ var searchActive bool = false
 const (
 	TabDrives = iota
 	TabHistory
+	TabSweep
 	TabSettings
+	TabAbout
 )
 
 var activeTab = TabDrives
@@ -29,6 +31,14 @@ var activeTab = TabDrives
 var rotation float32 = 0
 
 func DrawDashboard() {
+    if jobScheduler == nil {
+        startScheduler()
+    }
+    if driveWatcher == nil {
+        startDriveWatcher()
+    }
+    applyDriveEvents()
+
     screenWidth := float32(rl.GetScreenWidth())
     screenHeight := float32(rl.GetScreenHeight())
 
@@ -54,7 +64,7 @@ func DrawDashboard() {
     tabHeight := float32(40)
     tabY := float32(80) 
     tabSpacing := float32(12)
-    tabs := []string{"Drives", "History"}
+    tabs := []string{"Drives", "History", "Sweep", "Settings", "About"}
     totalTabsWidth := float32(len(tabs))*tabWidth + float32(len(tabs)-1)*tabSpacing
     tabStartX := (screenWidth - totalTabsWidth) / 2 
 
@@ -89,11 +99,16 @@ func DrawDashboard() {
         drawDrivesTab(screenWidth, screenHeight)
     case TabHistory:
         drawHistoryTab()
+    case TabSweep:
+        drawSweepTab(screenWidth, screenHeight)
     case TabSettings:
-        drawSettingsTab()
+        drawSettingsTab(screenWidth, screenHeight)
+    case TabAbout:
+        drawAboutTab(screenWidth, screenHeight)
     }
     DrawConfirmClear()
     DrawConfirmPurge()
+    DrawConfirmDiskWipe()
     if IsCertificateActive() {
         DrawCertificate()
     }
@@ -107,7 +122,8 @@ func drawDrivesTab(screenWidth, screenHeight float32) {
 
     const margin = 30.0
     const spacing = 12.0
-    dialogsActive := IsConfirmPurgeActive() || IsConfirmClearActive() || IsCertificateActive()
+    drawDriveNotice(margin, 128)
+    dialogsActive := IsConfirmPurgeActive() || IsConfirmClearActive() || IsConfirmDiskWipeActive() || IsCertificateActive()
 
     if selectedDrive == nil {
         
@@ -184,16 +200,57 @@ func drawDrivesTab(screenWidth, screenHeight float32) {
             nameText := fmt.Sprintf("Drive: %s (%s)", d.Name, d.Device)
             rl.DrawText(nameText, int32(box.X+60), int32(box.Y+15), 20, rl.NewColor(0, 255, 180, 255))
 
+            identityText := wipeHistorySummary(d)
+            if d.Fingerprint != "" {
+                identityText = fmt.Sprintf("ID %s | %s", d.Fingerprint, identityText)
+            }
+            identityWidth := float32(rl.MeasureText(identityText, 14))
+            rl.DrawText(identityText, int32(box.X+box.Width-identityWidth-20), int32(box.Y+18), 14, rl.NewColor(255, 220, 100, 220))
+
+            if d.Usage != nil {
+                drawUsageBar(*d.Usage, box.X+box.Width-280, box.Y+44, 260)
+            }
+
             
             infoText := fmt.Sprintf("Type: %s", d.Type)
+            if d.Size > 0 {
+                infoText += fmt.Sprintf(" | %s", formatBytes(d.Size))
+            }
+            if d.Transport != "" {
+                infoText += fmt.Sprintf(" | %s", strings.ToUpper(d.Transport))
+            }
             if d.FileSystem != "" {
                 infoText += fmt.Sprintf(" | FS: %s", d.FileSystem)
             }
             if d.IsRemovable {
                 infoText += " | Removable"
             }
+            if d.USB != nil {
+                infoText += fmt.Sprintf(" | %s %s", d.USB.ID(), d.USB.SpeedName())
+            }
+            if currentPolicy().Allows(d, internalUnlocked.Load()) != nil {
+                infoText += " | Locked by policy"
+            }
+            if len(d.Partitions) > 0 {
+                infoText += fmt.Sprintf(" | %d partitions", len(d.Partitions))
+            }
+            if d.Path == "" {
+                infoText += " | Not mounted"
+            }
             rl.DrawText(infoText, int32(box.X+60), int32(box.Y+40), 14, rl.NewColor(0, 200, 150, 200))
 
+            partText := "Unformatted"
+            if len(d.Partitions) > 0 {
+                var parts []string
+                for _, p := range d.Partitions {
+                    parts = append(parts, "└ "+partitionSummary(p))
+                }
+                partText = strings.Join(parts, "    ")
+            } else if d.FileSystem != "" {
+                partText = "No partition table"
+            }
+            rl.DrawText(partText, int32(box.X+60), int32(box.Y+60), 12, rl.NewColor(0, 200, 150, 160))
+
             if !dialogsActive && rl.IsMouseButtonPressed(rl.MouseLeftButton) && hover {
                 selectedDrive = &cachedDrives[i]
                 driveContents, _ = drivers.GetDriveContents(selectedDrive.Path)
@@ -268,25 +325,52 @@ func drawDrivesTab(screenWidth, screenHeight float32) {
         }
 
      
-        purgeDriveBtn := rl.NewRectangle(margin+110+spacing, headerY, 100, buttonHeight)
-        drawGlowingButton(purgeDriveBtn, "Purge Drive", rl.NewColor(200, 50, 50, 255), rl.NewColor(255, 255, 255, 255))
-        if !dialogsActive && rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(rl.GetMousePosition(), purgeDriveBtn) {
-            ShowConfirmPurge(selectedDrive.Path)
+        // Purge and Clear work on the mounted filesystem's contents; Wipe
+        // Disk sanitizes the device itself.
+        if selectedDrive.Path != "" {
+            purgeDriveBtn := rl.NewRectangle(margin+110+spacing, headerY, 100, buttonHeight)
+            drawGlowingButton(purgeDriveBtn, "Purge Drive", rl.NewColor(200, 50, 50, 255), rl.NewColor(255, 255, 255, 255))
+            if !dialogsActive && rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(rl.GetMousePosition(), purgeDriveBtn) && allowWipe(*selectedDrive) {
+                ShowConfirmPurge(selectedDrive.Path)
+            }
+
+            clearDriveBtn := rl.NewRectangle(margin+220+2*spacing, headerY, 100, buttonHeight)
+            drawGlowingButton(clearDriveBtn, "Clear Drive", rl.NewColor(0, 255, 180, 255), rl.NewColor(5, 15, 20, 255))
+            if !dialogsActive && rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(rl.GetMousePosition(), clearDriveBtn) && allowWipe(*selectedDrive) {
+                ShowConfirmClear(selectedDrive.Path)
+            }
         }
 
-        clearDriveBtn := rl.NewRectangle(margin+220+2*spacing, headerY, 100, buttonHeight)
-        drawGlowingButton(clearDriveBtn, "Clear Drive", rl.NewColor(0, 255, 180, 255), rl.NewColor(5, 15, 20, 255))
-        if !dialogsActive && rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(rl.GetMousePosition(), clearDriveBtn) {
-            ShowConfirmClear(selectedDrive.Path)
+        if strings.HasPrefix(selectedDrive.Device, "/dev/") {
+            wipeDiskBtn := rl.NewRectangle(margin+330+3*spacing, headerY, 100, buttonHeight)
+            drawGlowingButton(wipeDiskBtn, "Wipe Disk", rl.NewColor(150, 20, 20, 255), rl.NewColor(255, 255, 255, 255))
+            if !dialogsActive && rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(rl.GetMousePosition(), wipeDiskBtn) && allowWipe(*selectedDrive) {
+                ShowConfirmDiskWipe(*selectedDrive)
+            }
         }
 
-       
-        driveInfoRect := rl.NewRectangle(margin+330+3*spacing, headerY, 300, buttonHeight)
+        driveInfoRect := rl.NewRectangle(margin+440+4*spacing, headerY, 300, buttonHeight)
         rl.DrawRectangleRounded(driveInfoRect, 0.2, 6, rl.NewColor(15, 60, 40, 180))
         rl.DrawRectangleRoundedLines(driveInfoRect, 0.2, 6,  rl.NewColor(0, 255, 180, 255))
         infoText := fmt.Sprintf("Drive: %s (%s)", selectedDrive.Name, selectedDrive.Device)
         rl.DrawText(infoText, int32(driveInfoRect.X+12), int32(driveInfoRect.Y+10), 16, rl.NewColor(0, 255, 180, 255))
 
+        identityX := int32(driveInfoRect.X + driveInfoRect.Width + spacing)
+        if selectedDrive.Fingerprint != "" {
+            rl.DrawText("ID "+selectedDrive.Fingerprint, identityX, int32(headerY+2), 14, rl.NewColor(0, 255, 180, 255))
+        }
+        rl.DrawText(wipeHistorySummary(*selectedDrive), identityX, int32(headerY+22), 14, rl.NewColor(255, 220, 100, 220))
+        if selectedDrive.Path != "" && stacksOn(*selectedDrive, drivers.NodeCrypt) {
+            rl.DrawText("Encrypted volume: Purge and Clear keep the LUKS header; Wipe Disk destroys it.", identityX, int32(headerY+42), 12, rl.NewColor(255, 180, 0, 220))
+        } else if selectedDrive.Usage != nil {
+            rl.DrawText(usageDetails(*selectedDrive), identityX, int32(headerY+42), 12, rl.NewColor(0, 200, 150, 200))
+        }
+
+        if selectedDrive.Path == "" {
+            drawPartitionTree(*selectedDrive, margin, headerY+buttonHeight+spacing+10, screenWidth-2*margin)
+            return
+        }
+
       
         searchY := headerY + buttonHeight + spacing + 10
         searchRect := rl.NewRectangle(margin, searchY, screenWidth-2*margin, searchHeight)
@@ -412,10 +496,10 @@ func drawDrivesTab(screenWidth, screenHeight float32) {
 
             if !dialogsActive && rl.IsMouseButtonPressed(rl.MouseLeftButton) {
                 mouse := rl.GetMousePosition()
-                if rl.CheckCollisionPointRec(mouse, purgeBtn) {
+                if rl.CheckCollisionPointRec(mouse, purgeBtn) && allowWipe(*selectedDrive) {
                     ShowConfirmPurge(f)
                 }
-                if rl.CheckCollisionPointRec(mouse, clearBtn) {
+                if rl.CheckCollisionPointRec(mouse, clearBtn) && allowWipe(*selectedDrive) {
                     ShowConfirmClear(f)
                 }
             }
@@ -484,3 +568,183 @@ func containsIgnoreCase(str, substr string) bool {
     return strings.Contains(strings.ToLower(str), strings.ToLower(substr))
 }
 
+
+// formatBytes renders a size in decimal units, the way drive vendors label
+// capacity.
+func formatBytes(n int64) string {
+    const unit = 1000
+    if n < unit {
+        return fmt.Sprintf("%d B", n)
+    }
+    div, exp := int64(unit), 0
+    for m := n / unit; m >= unit; m /= unit {
+        div *= unit
+        exp++
+    }
+    return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
+
+// drawUsageBar shows how full a mounted filesystem is, with the free space
+// underneath.
+func drawUsageBar(u drivers.Usage, x, y, width float32) {
+    fraction := float32(u.UsedFraction())
+    fill := rl.NewColor(0, 255, 180, 255)
+    switch {
+    case fraction >= 0.9:
+        fill = rl.NewColor(255, 80, 80, 255)
+    case fraction >= 0.75:
+        fill = rl.NewColor(255, 180, 0, 255)
+    }
+
+    bar := rl.NewRectangle(x, y, width, 8)
+    rl.DrawRectangleRounded(bar, 0.5, 4, rl.NewColor(20, 60, 40, 200))
+    if fraction > 0 {
+        rl.DrawRectangleRounded(rl.NewRectangle(x, y, width*fraction, 8), 0.5, 4, fill)
+    }
+    label := fmt.Sprintf("%s free of %s (%.0f%% used)", formatBytes(u.Available), formatBytes(u.Total), fraction*100)
+    rl.DrawText(label, int32(x), int32(y+12), 12, rl.NewColor(0, 200, 150, 200))
+}
+
+// usageDetails lists the capacity, inode and mount option figures of a
+// mounted drive on one line.
+func usageDetails(d drivers.Drive) string {
+    u := d.Usage
+    text := fmt.Sprintf("%s used, %s free of %s", formatBytes(u.Used), formatBytes(u.Available), formatBytes(u.Total))
+    if u.Inodes > 0 {
+        text += fmt.Sprintf(" | %d of %d inodes free", u.InodesFree, u.Inodes)
+    }
+    if len(d.MountOptions) > 0 {
+        text += " | " + strings.Join(d.MountOptions, ",")
+    }
+    return text
+}
+
+// partitionSummary is a one-line description of a partition and what is
+// stacked on it, for drive
+// cards and the partition tree.
+func partitionSummary(p drivers.Partition) string {
+    text := partitionLabel(p)
+    if len(p.Holders) > 0 {
+        text += "  → " + holderChain(p.Holders)
+    }
+    return text
+}
+
+func partitionLabel(p drivers.Partition) string {
+    fs := p.FileSystem
+    if fs == "" {
+        fs = "unformatted"
+    }
+    text := fmt.Sprintf("%s  %s  %s", p.Name, fs, formatBytes(p.Size))
+    if p.Label != "" {
+        text += fmt.Sprintf("  \"%s\"", p.Label)
+    }
+    if len(p.MountPoints) > 0 {
+        text += "  " + strings.Join(p.MountPoints, ", ")
+    }
+    return text
+}
+
+// holderSummary describes one stacked device: an unlocked LUKS volume, an
+// LVM logical volume, an md array or a loop device.
+func holderSummary(h drivers.Holder) string {
+    text := h.Name
+    switch h.Kind {
+    case drivers.NodeCrypt, drivers.NodeLVM, drivers.NodeDM:
+        text = h.Kind + " " + h.Label
+    case drivers.NodeRAID:
+        text = h.Name + " " + h.Label
+    }
+    if h.FileSystem != "" {
+        text += "  " + h.FileSystem
+    }
+    if len(h.MountPoints) > 0 {
+        text += "  " + strings.Join(h.MountPoints, ", ")
+    }
+    return text
+}
+
+// holderChain flattens a holder tree onto one line.
+func holderChain(holders []drivers.Holder) string {
+    var parts []string
+    for _, h := range holders {
+        text := holderSummary(h)
+        if len(h.Holders) > 0 {
+            text += "  → " + holderChain(h.Holders)
+        }
+        parts = append(parts, text)
+    }
+    return strings.Join(parts, ", ")
+}
+
+func appendHolderRows(rows []string, holders []drivers.Holder, indent string) []string {
+    for _, h := range holders {
+        rows = append(rows, indent+"└ "+holderSummary(h))
+        rows = appendHolderRows(rows, h.Holders, indent+"    ")
+    }
+    return rows
+}
+
+// stacksOn reports whether a device of the given kind is stacked anywhere
+// on d.
+func stacksOn(d drivers.Drive, kind string) bool {
+    var found func(holders []drivers.Holder) bool
+    found = func(holders []drivers.Holder) bool {
+        for _, h := range holders {
+            if h.Kind == kind || found(h.Holders) {
+                return true
+            }
+        }
+        return false
+    }
+    if found(d.Holders) {
+        return true
+    }
+    for _, p := range d.Partitions {
+        if found(p.Holders) {
+            return true
+        }
+    }
+    return false
+}
+
+// drawPartitionTree shows an unmounted disk's layout, and everything stacked
+// on it, in place of the file browser.
+func drawPartitionTree(d drivers.Drive, x, y, width float32) {
+    labelColor := rl.NewColor(0, 255, 180, 255)
+    textColor := rl.NewColor(200, 200, 200, 255)
+
+    rows := appendHolderRows(nil, d.Holders, "")
+    for i, p := range d.Partitions {
+        branch, indent := "├ ", "│   "
+        if i == len(d.Partitions)-1 {
+            branch, indent = "└ ", "    "
+        }
+        rows = append(rows, branch+partitionLabel(p))
+        rows = appendHolderRows(rows, p.Holders, indent)
+    }
+    if len(rows) == 0 {
+        text := "└ no partition table"
+        if d.FileSystem != "" {
+            text = "└ whole-disk filesystem: " + d.FileSystem
+        }
+        rows = append(rows, text)
+    }
+
+    rect := rl.NewRectangle(x, y, width, float32(70+22*len(rows)))
+    rl.DrawRectangleRounded(rect, 0.1, 8, rl.NewColor(10, 50, 30, 200))
+    rl.DrawRectangleRoundedLines(rect, 0.1, 8, labelColor)
+
+    header := fmt.Sprintf("%s  %s  %s", d.Device, formatBytes(d.Size), strings.ToUpper(d.Transport))
+    if d.Serial != "" {
+        header += "  S/N " + d.Serial
+    }
+    rl.DrawText(header, int32(x+12), int32(y+10), 16, labelColor)
+    rl.DrawText("Not mounted; use Wipe Disk to sanitize the whole device.", int32(x+12), int32(y+32), 14, rl.NewColor(0, 200, 150, 200))
+
+    rowY := y + 56
+    for _, row := range rows {
+        rl.DrawText(row, int32(x+24), int32(rowY), 14, textColor)
+        rowY += 22
+    }
+}
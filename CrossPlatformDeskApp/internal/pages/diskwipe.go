@@ -0,0 +1,452 @@
+package pages
+
+import (
+	"data_wiper/internal/drivers"
+	"data_wiper/internal/wipelog"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// diskHealthResult is the SMART pre-check run when the dialog opens.
+type diskHealthResult struct {
+	report drivers.HealthReport
+	err    error
+}
+
+// diskWipeOutcome is what the background wipe hands back to the UI thread.
+type diskWipeOutcome struct {
+	result   drivers.DiskWipeResult
+	capacity *drivers.CapacityReport
+	err      error
+	started  time.Time
+	finished time.Time
+}
+
+var (
+	diskWipeActive      bool
+	diskWipeDrive       drivers.Drive
+	diskWipeConfirmText string
+	diskWipeTextActive  bool
+	diskWipeVerify      = true
+	diskWipeProbe       bool
+	diskWipePlan        drivers.DiskWipePlan
+
+	diskWipeHealth        *diskHealthResult
+	diskWipeHealthPending chan diskHealthResult
+
+	diskWipeRunning bool
+	diskWipeCancel  chan struct{}
+	diskWipeDone    chan diskWipeOutcome
+
+	diskWipeMu    sync.Mutex
+	diskWipePhase string
+	diskWipeBytes int64
+	diskWipeTotal int64
+)
+
+// ShowConfirmDiskWipe opens the whole-disk wipe dialog for d.
+func ShowConfirmDiskWipe(d drivers.Drive) {
+	diskWipeActive = true
+	diskWipeDrive = d
+	diskWipeConfirmText = ""
+	diskWipeTextActive = false
+	diskWipeVerify = true
+	// Counterfeit capacity is a flash-stick problem; probing a large
+	// internal disk only costs time.
+	diskWipeProbe = d.IsRemovable
+	diskWipePlan = drivers.PlanDiskWipe(d)
+
+	// smartctl can take a few seconds on a sleeping disk; do not stall the
+	// frame loop for it.
+	diskWipeHealth = nil
+	pending := make(chan diskHealthResult, 1)
+	diskWipeHealthPending = pending
+	go func() {
+		report, err := drivers.CheckHealth(d.Device)
+		pending <- diskHealthResult{report, err}
+	}()
+}
+
+func HideConfirmDiskWipe() {
+	diskWipeActive = false
+	diskWipeConfirmText = ""
+	diskWipeTextActive = false
+}
+
+func IsConfirmDiskWipeActive() bool {
+	return diskWipeActive
+}
+
+// diskWipeConfirmed reports whether the typed text names this disk: its
+// serial or its model, or the kernel name when the disk reports neither.
+func diskWipeConfirmed() bool {
+	typed := strings.TrimSpace(diskWipeConfirmText)
+	if typed == "" {
+		return false
+	}
+	d := diskWipeDrive
+	if d.Serial == "" && d.Model == "" {
+		return typed == filepath.Base(d.Device)
+	}
+	return (d.Serial != "" && strings.EqualFold(typed, d.Serial)) ||
+		(d.Model != "" && strings.EqualFold(typed, d.Model))
+}
+
+func DrawConfirmDiskWipe() {
+	if !diskWipeActive {
+		return
+	}
+
+	screenWidth := float32(rl.GetScreenWidth())
+	screenHeight := float32(rl.GetScreenHeight())
+	rl.DrawRectangle(0, 0, int32(screenWidth), int32(screenHeight), rl.NewColor(0, 0, 0, 180))
+
+	modalWidth := float32(560)
+	modalHeight := float32(600)
+	modalX := (screenWidth - modalWidth) / 2
+	modalY := (screenHeight - modalHeight) / 2
+	modalRect := rl.NewRectangle(modalX, modalY, modalWidth, modalHeight)
+
+	rl.DrawRectangleGradientV(
+		int32(modalX), int32(modalY), int32(modalWidth), int32(modalHeight),
+		rl.NewColor(15, 25, 35, 250),
+		rl.NewColor(5, 15, 25, 250),
+	)
+	rl.DrawRectangleRoundedLines(modalRect, 0.1, 8, rl.NewColor(220, 50, 50, 255))
+
+	textColor := rl.NewColor(200, 200, 200, 255)
+	warnColor := rl.NewColor(255, 100, 100, 255)
+	mouse := rl.GetMousePosition()
+
+	rl.DrawText("Wipe Entire Disk", int32(modalX+20), int32(modalY+20), 20, warnColor)
+
+	d := diskWipeDrive
+	y := modalY + 60
+	targetRect := rl.NewRectangle(modalX+20, y, modalWidth-40, 54)
+	rl.DrawRectangleRounded(targetRect, 0.1, 6, rl.NewColor(40, 20, 20, 255))
+	rl.DrawRectangleRoundedLines(targetRect, 0.1, 1, rl.NewColor(220, 50, 50, 255))
+	rl.DrawText(fmt.Sprintf("%s (%s)  %s  %s", d.Name, d.Device, formatBytes(d.Size), strings.ToUpper(d.Transport)),
+		int32(targetRect.X+10), int32(y+10), 16, rl.NewColor(255, 150, 150, 255))
+	serial := d.Serial
+	if serial == "" {
+		serial = "not reported"
+	}
+	rl.DrawText(fmt.Sprintf("Serial: %s", serial), int32(targetRect.X+10), int32(y+32), 14, textColor)
+	y += 66
+
+	select {
+	case result := <-diskWipeHealthPending:
+		diskWipeHealth = &result
+	default:
+	}
+
+	if diskWipeRunning {
+		drawDiskWipeProgress(modalX, y, modalWidth)
+		return
+	}
+
+	rl.DrawText("Every sector will be overwritten, partition table included.", int32(modalX+20), int32(y), 14, textColor)
+	y += 20
+	if len(d.Partitions) == 0 {
+		rl.DrawText("No partitions", int32(modalX+30), int32(y), 14, textColor)
+		y += 18
+	}
+	for i, p := range d.Partitions {
+		if i == 4 {
+			rl.DrawText(fmt.Sprintf("... and %d more", len(d.Partitions)-i), int32(modalX+30), int32(y), 14, textColor)
+			y += 18
+			break
+		}
+		rl.DrawText(partitionSummary(p), int32(modalX+30), int32(y), 14, textColor)
+		y += 18
+	}
+	y = drawDiskWipePlan(modalX+20, y+4)
+	y += 8
+	y = drawDiskHealth(modalX+20, y)
+	y += 8
+
+	if diskWipePlan.Blocked != nil {
+		rl.DrawText(diskWipePlan.Blocked.Error(), int32(modalX+20), int32(y), 14, warnColor)
+	} else {
+		prompt := "Type the disk's serial or model to confirm:"
+		if d.Serial == "" && d.Model == "" {
+			prompt = fmt.Sprintf("Type %s to confirm:", filepath.Base(d.Device))
+		}
+		rl.DrawText(prompt, int32(modalX+20), int32(y), 16, textColor)
+	}
+	y += 26
+
+	inputRect := rl.NewRectangle(modalX+20, y, modalWidth-40, 40)
+	inputHover := rl.CheckCollisionPointRec(mouse, inputRect)
+	if rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+		diskWipeTextActive = inputHover
+	}
+	inputBorder := rl.NewColor(60, 120, 90, 255)
+	if diskWipeTextActive {
+		inputBorder = rl.NewColor(0, 255, 180, 255)
+	}
+	rl.DrawRectangleRounded(inputRect, 0.1, 6, rl.NewColor(15, 25, 35, 255))
+	rl.DrawRectangleRoundedLines(inputRect, 0.1, 1, inputBorder)
+
+	if diskWipeTextActive {
+		if rl.IsKeyPressed(rl.KeyBackspace) && len(diskWipeConfirmText) > 0 {
+			diskWipeConfirmText = diskWipeConfirmText[:len(diskWipeConfirmText)-1]
+		}
+		key := rl.GetCharPressed()
+		for key > 0 {
+			if key >= 32 && key <= 125 && len(diskWipeConfirmText) < 64 {
+				diskWipeConfirmText += string(rune(key))
+			}
+			key = rl.GetCharPressed()
+		}
+	}
+
+	canWipe := diskWipePlan.Blocked == nil && diskWipeConfirmed()
+	inputColor := rl.NewColor(255, 255, 255, 255)
+	if canWipe {
+		inputColor = rl.NewColor(100, 255, 100, 255)
+	}
+	rl.DrawText(diskWipeConfirmText, int32(inputRect.X+10), int32(y+12), 16, inputColor)
+	y += 56
+
+	checkRect := rl.NewRectangle(modalX+20, y+8, 18, 18)
+	rl.DrawRectangleRoundedLines(checkRect, 0.2, 1, rl.NewColor(0, 255, 180, 255))
+	if diskWipeVerify {
+		rl.DrawRectangle(int32(checkRect.X+4), int32(checkRect.Y+4), 10, 10, rl.NewColor(0, 255, 180, 255))
+	}
+	rl.DrawText("Read back and verify", int32(checkRect.X+26), int32(checkRect.Y+2), 14, textColor)
+	if rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(mouse, checkRect) {
+		diskWipeVerify = !diskWipeVerify
+	}
+
+	probeRect := rl.NewRectangle(modalX+20, y+34, 18, 18)
+	rl.DrawRectangleRoundedLines(probeRect, 0.2, 1, rl.NewColor(0, 255, 180, 255))
+	if diskWipeProbe {
+		rl.DrawRectangle(int32(probeRect.X+4), int32(probeRect.Y+4), 10, 10, rl.NewColor(0, 255, 180, 255))
+	}
+	rl.DrawText("Probe for fake capacity first", int32(probeRect.X+26), int32(probeRect.Y+2), 14, textColor)
+	if rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(mouse, probeRect) {
+		diskWipeProbe = !diskWipeProbe
+	}
+
+	cancelRect := rl.NewRectangle(modalX+modalWidth-250, y, 100, 35)
+	drawGlowingButton(cancelRect, "Cancel", rl.NewColor(60, 60, 60, 255), rl.NewColor(255, 255, 255, 255))
+	if rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(mouse, cancelRect) {
+		HideConfirmDiskWipe()
+		return
+	}
+
+	wipeRect := rl.NewRectangle(modalX+modalWidth-140, y, 120, 35)
+	wipeColor := rl.NewColor(60, 30, 30, 255)
+	if canWipe {
+		wipeColor = rl.NewColor(220, 50, 50, 255)
+	}
+	drawGlowingButton(wipeRect, "Wipe Disk", wipeColor, rl.NewColor(255, 255, 255, 255))
+	if canWipe && rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(mouse, wipeRect) {
+		startDiskWipe()
+	}
+
+	if rl.IsKeyPressed(rl.KeyEscape) {
+		HideConfirmDiskWipe()
+	}
+}
+
+// drawDiskWipePlan lists what has to be taken down before the wipe and what
+// it breaks beyond this disk, and returns the y below it.
+func drawDiskWipePlan(x, y float32) float32 {
+	textColor := rl.NewColor(200, 200, 200, 255)
+	warnColor := rl.NewColor(255, 180, 0, 255)
+
+	plan := diskWipePlan
+	if len(plan.Teardown) > 0 {
+		rl.DrawText("First, in this order:", int32(x), int32(y), 14, textColor)
+		y += 18
+		for i, step := range plan.Teardown {
+			if i == 4 {
+				rl.DrawText(fmt.Sprintf("... and %d more", len(plan.Teardown)-i), int32(x+10), int32(y), 14, textColor)
+				y += 18
+				break
+			}
+			rl.DrawText(fmt.Sprintf("%d. %s", i+1, step.Description), int32(x+10), int32(y), 14, textColor)
+			y += 18
+		}
+	}
+	for i, w := range plan.Warnings {
+		if i == 3 {
+			rl.DrawText(fmt.Sprintf("... and %d more warnings", len(plan.Warnings)-i), int32(x), int32(y), 14, warnColor)
+			y += 18
+			break
+		}
+		rl.DrawText("! "+w, int32(x), int32(y), 14, warnColor)
+		y += 18
+	}
+	return y
+}
+
+// drawDiskHealth shows the SMART pre-check and returns the y below it.
+func drawDiskHealth(x, y float32) float32 {
+	textColor := rl.NewColor(200, 200, 200, 255)
+	warnColor := rl.NewColor(255, 180, 0, 255)
+
+	switch {
+	case diskWipeHealth == nil:
+		rl.DrawText("Health: checking SMART status...", int32(x), int32(y), 14, textColor)
+		return y + 18
+	case diskWipeHealth.err != nil:
+		rl.DrawText("Health: unknown ("+diskWipeHealth.err.Error()+")", int32(x), int32(y), 14, textColor)
+		return y + 18
+	}
+
+	h := diskWipeHealth.report
+	if h.Healthy() {
+		rl.DrawText(fmt.Sprintf("Health: SMART passed, %d power-on hours, no warnings", h.PowerOnHours), int32(x), int32(y), 14, rl.NewColor(100, 255, 100, 255))
+		return y + 18
+	}
+	rl.DrawText("Health warnings; the wipe may be slow and cannot reach remapped sectors:", int32(x), int32(y), 14, warnColor)
+	y += 18
+	for i, w := range h.Warnings {
+		if i == 3 {
+			rl.DrawText(fmt.Sprintf("... and %d more", len(h.Warnings)-i), int32(x+10), int32(y), 14, warnColor)
+			y += 18
+			break
+		}
+		rl.DrawText("! "+w, int32(x+10), int32(y), 14, warnColor)
+		y += 18
+	}
+	return y
+}
+
+func drawDiskWipeProgress(modalX, y, modalWidth float32) {
+	select {
+	case outcome := <-diskWipeDone:
+		finishDiskWipe(outcome)
+		return
+	default:
+	}
+
+	diskWipeMu.Lock()
+	phase, done, total := diskWipePhase, diskWipeBytes, diskWipeTotal
+	diskWipeMu.Unlock()
+
+	label := "Releasing and opening device..."
+	fraction := float32(0)
+	if total > 0 {
+		fraction = float32(done) / float32(total)
+		label = fmt.Sprintf("%s: %s of %s (%.1f%%)", phase, formatBytes(done), formatBytes(total), fraction*100)
+		if phase == drivers.PhaseProbe {
+			label = fmt.Sprintf("Probing real capacity: block %d of %d (%.1f%%)", done, total, fraction*100)
+		}
+	}
+	rl.DrawText(label, int32(modalX+20), int32(y+10), 16, rl.NewColor(200, 200, 200, 255))
+
+	barRect := rl.NewRectangle(modalX+20, y+40, modalWidth-40, 24)
+	rl.DrawRectangleRounded(barRect, 0.3, 6, rl.NewColor(20, 60, 40, 200))
+	if fraction > 0 {
+		fill := rl.NewRectangle(barRect.X, barRect.Y, barRect.Width*fraction, barRect.Height)
+		rl.DrawRectangleRounded(fill, 0.3, 6, rl.NewColor(0, 255, 180, 255))
+	}
+
+	cancelRect := rl.NewRectangle(modalX+modalWidth-140, y+90, 120, 35)
+	drawGlowingButton(cancelRect, "Stop", rl.NewColor(60, 60, 60, 255), rl.NewColor(255, 255, 255, 255))
+	if diskWipeCancel != nil && rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(rl.GetMousePosition(), cancelRect) {
+		close(diskWipeCancel)
+		diskWipeCancel = nil
+	}
+}
+
+// startDiskWipe tears down the disk's stack and runs the wipe in the background; the
+// dialog polls for the outcome every frame.
+func startDiskWipe() {
+	d := diskWipeDrive
+	plan := diskWipePlan
+	probe := diskWipeProbe
+	opts := drivers.DiskWipeOptions{Verify: diskWipeVerify}
+	diskWipeCancel = make(chan struct{})
+	diskWipeDone = make(chan diskWipeOutcome, 1)
+	opts.Cancel = diskWipeCancel
+	opts.Progress = func(phase string, done, total int64) {
+		diskWipeMu.Lock()
+		diskWipePhase, diskWipeBytes, diskWipeTotal = phase, done, total
+		diskWipeMu.Unlock()
+	}
+	diskWipeMu.Lock()
+	diskWipePhase, diskWipeBytes, diskWipeTotal = "", 0, 0
+	diskWipeMu.Unlock()
+	diskWipeRunning = true
+
+	done := diskWipeDone
+	go func() {
+		outcome := diskWipeOutcome{started: time.Now()}
+		if err := drivers.RunTeardown(plan); err != nil {
+			outcome.err = err
+		} else {
+			if probe {
+				report, err := drivers.ProbeCapacity(d.Device, drivers.ProbeOptions{Progress: opts.Progress, Cancel: opts.Cancel})
+				if err == nil {
+					outcome.capacity = &report
+				}
+				outcome.err = err
+			}
+			// A fake stick is still overwritten as far as it goes; its
+			// verification pass then fails on the wrapped blocks.
+			if outcome.err == nil {
+				outcome.result, outcome.err = drivers.WipeDisk(d, opts)
+			}
+		}
+		outcome.finished = time.Now()
+		done <- outcome
+	}()
+}
+
+// finishDiskWipe records the outcome in a signed log and shows its
+// certificate. The drive list is rescanned since the partitions are gone.
+func finishDiskWipe(outcome diskWipeOutcome) {
+	diskWipeRunning = false
+	diskWipeCancel = nil
+	d := diskWipeDrive
+
+	if outcome.err != nil {
+		fmt.Printf("Disk wipe of %s failed: %v\n", d.Device, outcome.err)
+	}
+
+	var log wipelog.Log
+	log.Device.Name = d.Model
+	if log.Device.Name == "" {
+		log.Device.Name = d.Device
+	}
+	log.Device.Serial = orUnknown(d.Serial)
+	log.Device.Path = d.Device
+	log.Device.Fingerprint = d.Fingerprint
+	log.USB = d.USB
+	log.Device.SizeGB = int(d.Size / 1000000000)
+	log.Device.Type = d.MediaType()
+	recordExecution(&log, outcome.result.Trace, outcome.err)
+	if outcome.result.Verified {
+		log.Wipe.Method += "_verify"
+	}
+	log.Wipe.NistLevel = "clear"
+	if diskWipeHealth != nil && diskWipeHealth.err == nil {
+		health := diskWipeHealth.report
+		log.Health = &health
+	}
+	log.Capacity = outcome.capacity
+	log.Wipe.Status = "success"
+	if outcome.err != nil {
+		log.Wipe.Status = "failure"
+	}
+	log.Wipe.StartedAt = outcome.started.UTC().Format(time.RFC3339)
+	log.Wipe.FinishedAt = outcome.finished.UTC().Format(time.RFC3339)
+	log.Wipe.DurationSec = int(outcome.finished.Sub(outcome.started).Seconds())
+	recordSystem(&log, os.Getenv("USER"))
+
+	HideConfirmDiskWipe()
+	cachedDrives = nil
+	selectedDrive = nil
+	ShowCertificate(log)
+}
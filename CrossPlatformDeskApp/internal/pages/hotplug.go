@@ -0,0 +1,129 @@
+package pages
+
+import (
+	"data_wiper/internal/drivers"
+	"fmt"
+	"strings"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// driveNoticeDuration is how long a hotplug notice stays on the Drives tab.
+const driveNoticeDuration = 4 * time.Second
+
+var driveWatcher *drivers.DriveWatcher
+var driveNotice string
+var driveNoticeUntil time.Time
+
+// startDriveWatcher begins tracking drives being plugged and pulled. It is
+// called once, when the dashboard first opens.
+func startDriveWatcher() {
+	driveWatcher = drivers.WatchDrives(drivers.DefaultWatchInterval)
+}
+
+// applyDriveEvents folds pending hotplug events into the drive list. It runs
+// every frame and never blocks.
+func applyDriveEvents() {
+	for {
+		select {
+		case e := <-driveWatcher.Events():
+			applyDriveEvent(e)
+		default:
+			return
+		}
+	}
+}
+
+func applyDriveEvent(e drivers.DriveEvent) {
+	// Nothing is listed yet; the first draw of the Drives tab scans anyway.
+	if cachedDrives == nil {
+		return
+	}
+
+	key := drivers.DriveKey(e.Drive)
+	selectedKey := ""
+	if selectedDrive != nil {
+		selectedKey = drivers.DriveKey(*selectedDrive)
+	}
+
+	index := -1
+	for i, d := range cachedDrives {
+		if drivers.DriveKey(d) == key {
+			index = i
+			break
+		}
+	}
+
+	switch e.Kind {
+	case drivers.DriveAdded:
+		if index < 0 {
+			cachedDrives = append(cachedDrives, e.Drive)
+		}
+		showDriveNotice(fmt.Sprintf("Drive connected: %s (%s). %s", e.Drive.Name, e.Drive.Device, wipeHistorySummary(e.Drive)))
+	case drivers.DriveChanged:
+		if index >= 0 {
+			cachedDrives[index] = e.Drive
+		}
+	case drivers.DriveRemoved:
+		if index >= 0 {
+			cachedDrives = append(cachedDrives[:index], cachedDrives[index+1:]...)
+		}
+		showDriveNotice(fmt.Sprintf("Drive removed: %s (%s)", e.Drive.Name, e.Drive.Device))
+		closeDialogsForDrive(e.Drive)
+	}
+
+	// cachedDrives may have moved; point the selection at the new element.
+	if selectedDrive == nil {
+		return
+	}
+	previousPath := selectedDrive.Path
+	selectedDrive = nil
+	for i := range cachedDrives {
+		if drivers.DriveKey(cachedDrives[i]) == selectedKey {
+			selectedDrive = &cachedDrives[i]
+			break
+		}
+	}
+	if selectedDrive == nil {
+		driveContents = nil
+		searchQuery = ""
+		searchActive = false
+		scrollOffset = 0
+		return
+	}
+	if selectedDrive.Path != previousPath {
+		driveContents, _ = drivers.GetDriveContents(selectedDrive.Path)
+		scrollOffset = 0
+	}
+}
+
+// closeDialogsForDrive dismisses confirmations aimed at a drive that is
+// gone. A disk wipe already running is left to fail and certify the
+// failure.
+func closeDialogsForDrive(d drivers.Drive) {
+	if IsConfirmDiskWipeActive() && !diskWipeRunning && drivers.DriveKey(diskWipeDrive) == drivers.DriveKey(d) {
+		HideConfirmDiskWipe()
+	}
+	for _, mount := range d.MountPoints {
+		if IsConfirmPurgeActive() && strings.HasPrefix(purgeTargetName, mount) {
+			HideConfirmPurge()
+		}
+		if IsConfirmClearActive() && strings.HasPrefix(clearTargetName, mount) {
+			HideConfirmClear()
+		}
+	}
+}
+
+func showDriveNotice(text string) {
+	fmt.Println(text)
+	driveNotice = text
+	driveNoticeUntil = time.Now().Add(driveNoticeDuration)
+}
+
+func drawDriveNotice(x, y float32) {
+	if driveNotice == "" || time.Now().After(driveNoticeUntil) {
+		return
+	}
+	rl.DrawText(driveNotice, int32(x), int32(y), 16, rl.NewColor(255, 220, 100, 255))
+}
@@ -0,0 +1,158 @@
+package pages
+
+import (
+	"data_wiper/internal/db"
+	"data_wiper/internal/drivers"
+	"data_wiper/internal/wipelog"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// wipeHistory caches db.WipesFor per drive fingerprint; the drive cards ask
+// for it every frame. recordWipe, which also runs on the scheduler's
+// goroutine, drops the cache.
+var wipeHistory = make(map[string][]db.WipeRecord)
+var wipeHistoryMu sync.Mutex
+
+// recordWipe adds a signed log to the wipe history so the drive it names
+// is recognised when it is connected again. Logs without a drive
+// fingerprint cannot be correlated and are not recorded.
+func recordWipe(log wipelog.Log) {
+	if log.Device.Fingerprint == "" {
+		return
+	}
+	finished, err := time.Parse(time.RFC3339, log.Wipe.FinishedAt)
+	if err != nil {
+		finished = time.Now()
+	}
+	err = db.AppendWipe(db.WipeRecord{
+		Fingerprint: log.Device.Fingerprint,
+		Device:      log.Device.Name,
+		Serial:      log.Device.Serial,
+		Type:        log.Device.Type,
+		Method:      log.Wipe.Method,
+		NistLevel:   log.Wipe.NistLevel,
+		Status:      log.Wipe.Status,
+		FinishedAt:  finished,
+		LogHash:     log.Signature.LogHash,
+	})
+	if err != nil {
+		fmt.Printf("Failed to record wipe history: %v\n", err)
+	}
+	wipeHistoryMu.Lock()
+	wipeHistory = make(map[string][]db.WipeRecord)
+	wipeHistoryMu.Unlock()
+}
+
+// driveWipeHistory returns the recorded wipes of a drive, newest first.
+func driveWipeHistory(fingerprint string) []db.WipeRecord {
+	if fingerprint == "" {
+		return nil
+	}
+	wipeHistoryMu.Lock()
+	defer wipeHistoryMu.Unlock()
+	records, ok := wipeHistory[fingerprint]
+	if !ok {
+		records, _ = db.WipesFor(fingerprint)
+		wipeHistory[fingerprint] = records
+	}
+	return records
+}
+
+// wipeHistorySummary says whether a drive has been wiped before, and when
+// and at what NIST level. Wipes of files on the drive are reported
+// separately from wipes of the whole device.
+func wipeHistorySummary(d drivers.Drive) string {
+	if d.Fingerprint == "" {
+		return "No stable ID (drive reports no serial)"
+	}
+	var fileWipes []db.WipeRecord
+	for _, r := range driveWipeHistory(d.Fingerprint) {
+		if r.Status != "success" {
+			continue
+		}
+		if !isDeviceLevel(r.Type) {
+			fileWipes = append(fileWipes, r)
+			continue
+		}
+		return fmt.Sprintf("Wiped before: %s, NIST %s (%s)", r.FinishedAt.Local().Format("2006-01-02 15:04"), r.NistLevel, r.Method)
+	}
+	if len(fileWipes) > 0 {
+		return fmt.Sprintf("Never wiped as a whole; files wiped %d times, last %s", len(fileWipes), fileWipes[0].FinishedAt.Local().Format("2006-01-02 15:04"))
+	}
+	return "Never wiped with this tool"
+}
+
+// isDeviceLevel reports whether a log's device type names a whole drive
+// rather than files on it.
+func isDeviceLevel(deviceType string) bool {
+	switch deviceType {
+	case "file", "sweep profile", "free space", "retention batch":
+		return false
+	}
+	return true
+}
+
+// unknownValue stands in for device details that could not be read.
+// Certificates never fill them in with guesses.
+const unknownValue = "unknown"
+
+// describeTarget records in log what is about to be wiped at path: a
+// device or partition with its model, serial and size, or a file with the
+// drive it is on. It runs before the wipe, since a purged file can no
+// longer be looked up.
+func describeTarget(log *wipelog.Log, path string) {
+	log.Device.Path = path
+	log.Device.Name = path
+	if strings.HasPrefix(path, "/dev/") {
+		log.Device.Type = unknownValue
+		log.Device.Serial = unknownValue
+		if details, err := drivers.LookupDevice(path); err == nil {
+			// Partitions keep their path; the disk is the container
+			if details.Model != "" && details.Type != "part" {
+				log.Device.Name = details.Model
+			}
+			log.Device.Serial = orUnknown(details.Serial)
+			log.Device.SizeGB = int(details.Size / 1000000000)
+			log.Device.Type = orUnknown(details.MediaType())
+		} else {
+			fmt.Printf("Could not identify %s: %v\n", path, err)
+		}
+	} else {
+		log.Device.Type = "file"
+		if fi, err := os.Stat(path); err == nil {
+			log.Device.SizeGB = int(fi.Size() / 1000000000)
+		}
+	}
+	identifyDrive(log, path)
+}
+
+// identifyDrive records the fingerprint and USB identity of the drive
+// holding path in log, when the drive can be found, and names that drive
+// when path is a file or partition on it.
+func identifyDrive(log *wipelog.Log, path string) {
+	d, ok := drivers.DriveForPath(path)
+	if !ok {
+		return
+	}
+	log.Device.Fingerprint = d.Fingerprint
+	log.USB = d.USB
+	if d.Device != path {
+		log.Device.Container = &wipelog.ContainingDevice{
+			Device: orUnknown(d.Device),
+			Model:  orUnknown(d.Model),
+			Serial: orUnknown(d.Serial),
+		}
+	}
+}
+
+// orUnknown marks an empty device detail as unknown.
+func orUnknown(s string) string {
+	if s == "" {
+		return unknownValue
+	}
+	return s
+}
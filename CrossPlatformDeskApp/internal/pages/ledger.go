@@ -0,0 +1,65 @@
+package pages
+
+import (
+	"data_wiper/internal/certs"
+	"data_wiper/internal/ledger"
+	"data_wiper/internal/wipelog"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// wipeLedger is the certificate ledger every signed log is appended to.
+// It is shared so appends from the UI and the scheduler are serialised.
+var wipeLedger *ledger.Ledger
+
+func init() {
+	l, err := ledger.Default()
+	if err != nil {
+		fmt.Printf("Certificate ledger unavailable: %v\n", err)
+		return
+	}
+	wipeLedger = l
+}
+
+// appendToLedger signs the log with its ledger link and appends it along
+// with a signed tree head over the ledger
+func appendToLedger(log *wipelog.Log) error {
+	if wipeLedger == nil {
+		return fmt.Errorf("no ledger")
+	}
+	_, err := wipeLedger.Append(signingKey, func(link ledger.Link) ([]byte, error) {
+		log.Ledger = &link
+		if err := signLog(log); err != nil {
+			return nil, err
+		}
+		return json.Marshal(log)
+	})
+	if errors.Is(err, ledger.ErrHeadNotKept) {
+		// The certificate is in the ledger with its link; the History tab
+		// reports the missing head
+		fmt.Printf("Failed to keep ledger tree head: %v\n", err)
+		return nil
+	}
+	return err
+}
+
+// ledgerSummary verifies the ledger against this machine's keys for the
+// History tab, and reports whether it is intact
+func ledgerSummary() (string, bool) {
+	if wipeLedger == nil {
+		return "Ledger: unavailable", false
+	}
+	store, err := certs.LoadTrustStore(nil, nil, "")
+	if err != nil {
+		return fmt.Sprintf("Ledger: cannot load trusted keys (%v)", err), false
+	}
+	report, err := wipeLedger.Verify(store, nil)
+	if err != nil {
+		return fmt.Sprintf("Ledger: unreadable (%v)", err), false
+	}
+	if !report.OK() {
+		return fmt.Sprintf("Ledger: %d problems, first: %s", len(report.Problems), report.Problems[0]), false
+	}
+	return fmt.Sprintf("Ledger: %d certificates, %d signed tree heads, intact", report.Entries, report.Heads), true
+}
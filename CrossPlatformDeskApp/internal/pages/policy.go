@@ -0,0 +1,191 @@
+package pages
+
+import (
+	"data_wiper/internal/auth"
+	"data_wiper/internal/config"
+	"data_wiper/internal/drivers"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// policyTTL is how long the policy is cached before the config file is
+// read again, so edits by an administrator apply without a restart.
+const policyTTL = 5 * time.Second
+
+var (
+	// policyMu guards the cached policy, which wipes running off the UI
+	// thread read through the drivers' target guard.
+	policyMu     sync.Mutex
+	policy       config.Policy
+	policySource string
+	policyLoaded time.Time
+
+	// internalUnlocked is set once an administrator enters the PIN, and
+	// lasts until the app exits or the disks are locked again.
+	internalUnlocked atomic.Bool
+
+	policyPIN       string
+	policyPINActive bool
+	policyMessage   string
+)
+
+// The policy is enforced by the drivers themselves, so no dialog, sweep,
+// retention rule or scheduled job can wipe a drive it locks.
+func init() {
+	drivers.SetTargetGuard(func(d drivers.Drive) error {
+		return currentPolicy().Allows(d, internalUnlocked.Load())
+	})
+}
+
+func currentPolicy() config.Policy {
+	p, _ := loadPolicy()
+	return p
+}
+
+// loadPolicy returns the policy and the system policy file it was set in,
+// or "" when it comes from the user's config file.
+func loadPolicy() (config.Policy, string) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	if time.Since(policyLoaded) > policyTTL {
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Printf("Failed to load config: %v\n", err)
+		}
+		policy = cfg.Policy
+		policySource = cfg.PolicySource
+		policyLoaded = time.Now()
+	}
+	return policy, policySource
+}
+
+// allowWipe reports whether the policy lets d be wiped, and shows the
+// reason on the dashboard when it does not.
+func allowWipe(d drivers.Drive) bool {
+	if err := currentPolicy().Allows(d, internalUnlocked.Load()); err != nil {
+		showDriveNotice(err.Error())
+		return false
+	}
+	return true
+}
+
+// setRemovableOnly turns the policy on or off in the config file.
+func setRemovableOnly(on bool) {
+	cfg, err := config.Load()
+	if err != nil {
+		policyMessage = err.Error()
+		return
+	}
+	if cfg.PolicySource != "" {
+		policyMessage = "The policy is set by the administrator in " + cfg.PolicySource
+		return
+	}
+	cfg.Policy.RemovableOnly = on
+	if err := cfg.Save(); err != nil {
+		policyMessage = err.Error()
+		return
+	}
+	policyMu.Lock()
+	policyLoaded = time.Time{}
+	policyMu.Unlock()
+	internalUnlocked.Store(false)
+	policyMessage = ""
+}
+
+// drawPolicyPanel shows the device policy on the Settings tab, with the
+// PIN prompt that unlocks internal disks, and returns the y below it.
+func drawPolicyPanel(x, y, width float32) float32 {
+	labelColor := rl.NewColor(0, 255, 180, 255)
+	textColor := rl.NewColor(200, 200, 200, 255)
+	warnColor := rl.NewColor(255, 180, 0, 255)
+	mouse := rl.GetMousePosition()
+	clicked := func(r rl.Rectangle) bool {
+		return rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(mouse, r)
+	}
+
+	p, source := loadPolicy()
+	rect := rl.NewRectangle(x, y, width, 120)
+	rl.DrawRectangleRounded(rect, 0.1, 8, rl.NewColor(10, 50, 30, 200))
+	rl.DrawRectangleRoundedLines(rect, 0.1, 8, labelColor)
+	rl.DrawText("Device Policy", int32(x+12), int32(y+10), 16, labelColor)
+
+	rowY := y + 36
+	buttonRect := rl.NewRectangle(x+width-160, y+10, 148, 32)
+	if source != "" {
+		rl.DrawText("Set by the administrator in "+source+".", int32(x+24), int32(rowY+64), 14, textColor)
+	}
+	switch {
+	case !p.RemovableOnly && source != "":
+		rl.DrawText("All drives may be wiped, internal disks included.", int32(x+24), int32(rowY), 14, textColor)
+
+	case !p.RemovableOnly:
+		rl.DrawText("All drives may be wiped, internal disks included.", int32(x+24), int32(rowY), 14, textColor)
+		drawGlowingButton(buttonRect, "Removable only", rl.NewColor(0, 255, 180, 255), rl.NewColor(5, 15, 20, 255))
+		if clicked(buttonRect) {
+			setRemovableOnly(true)
+		}
+
+	case internalUnlocked.Load():
+		rl.DrawText("Removable media only. Internal disks are unlocked by an administrator until the app closes.", int32(x+24), int32(rowY), 14, warnColor)
+		drawGlowingButton(buttonRect, "Lock", rl.NewColor(0, 255, 180, 255), rl.NewColor(5, 15, 20, 255))
+		if clicked(buttonRect) {
+			internalUnlocked.Store(false)
+		}
+		if source != "" {
+			break
+		}
+		offRect := rl.NewRectangle(x+width-160, y+50, 148, 32)
+		drawGlowingButton(offRect, "Turn policy off", rl.NewColor(200, 50, 50, 255), rl.NewColor(255, 255, 255, 255))
+		if clicked(offRect) {
+			setRemovableOnly(false)
+		}
+
+	default:
+		rl.DrawText("Removable media only. Internal disks are locked.", int32(x+24), int32(rowY), 14, textColor)
+		if p.AdminPINHash == "" {
+			rl.DrawText("No administrator PIN is set; run the app with -set-admin-pin to set one.", int32(x+24), int32(rowY+24), 14, warnColor)
+			break
+		}
+
+		inputRect := rl.NewRectangle(x+24, rowY+40, 240, 40)
+		if rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+			policyPINActive = rl.CheckCollisionPointRec(mouse, inputRect)
+		}
+		if policyPINActive {
+			if rl.IsKeyPressed(rl.KeyBackspace) && len(policyPIN) > 0 {
+				policyPIN = policyPIN[:len(policyPIN)-1]
+			}
+			for key := rl.GetCharPressed(); key > 0; key = rl.GetCharPressed() {
+				if key >= 32 && key <= 125 && len(policyPIN) < 64 {
+					policyPIN += string(rune(key))
+				}
+			}
+		}
+		drawInputField(inputRect, "Admin PIN:", policyPIN, policyPINActive, true)
+
+		unlockRect := rl.NewRectangle(inputRect.X+inputRect.Width+12, inputRect.Y+4, 100, 32)
+		drawGlowingButton(unlockRect, "Unlock", rl.NewColor(200, 50, 50, 255), rl.NewColor(255, 255, 255, 255))
+		if clicked(unlockRect) || (policyPINActive && rl.IsKeyPressed(rl.KeyEnter)) {
+			ok, err := auth.CheckPIN(p.AdminPINHash, policyPIN)
+			switch {
+			case err != nil:
+				policyMessage = err.Error()
+			case !ok:
+				policyMessage = "Wrong PIN"
+			default:
+				internalUnlocked.Store(true)
+				policyMessage = ""
+			}
+			policyPIN = ""
+		}
+	}
+
+	if policyMessage != "" {
+		rl.DrawText(policyMessage, int32(x+width-400), int32(y+96), 14, rl.NewColor(255, 120, 120, 255))
+	}
+	return y + rect.Height + 16
+}
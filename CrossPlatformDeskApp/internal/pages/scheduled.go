@@ -0,0 +1,166 @@
+package pages
+
+import (
+	"data_wiper/internal/config"
+	"data_wiper/internal/drivers"
+	"data_wiper/internal/scheduler"
+	"data_wiper/internal/wipelog"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var jobScheduler *scheduler.Scheduler
+
+// startScheduler loads the configured jobs and starts running them in the
+// background. It is called once, when the dashboard first opens.
+func startScheduler() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+	}
+	jobScheduler, err = scheduler.New(cfg.ScheduledJobs(), runScheduledJob)
+	if err != nil {
+		fmt.Printf("Scheduler: %v\n", err)
+	}
+	jobScheduler.Start()
+}
+
+// runScheduledJob executes a job through the same drivers calls the dialogs
+// use, then signs the log and writes its certificate PDF.
+func runScheduledJob(job config.Job) (string, error) {
+	start := time.Now()
+	cfg, err := config.Load()
+	if err != nil {
+		return "", err
+	}
+	if err := checkJobPolicy(cfg, job); err != nil {
+		return "", err
+	}
+
+	var log wipelog.Log
+	if job.Action != config.JobSweep && job.Action != config.JobRetention {
+		describeTarget(&log, job.Target)
+		// Taken before the wipe: a purged target no longer exists afterwards
+		// and a free-space wipe is certified by the space it had to fill.
+		log.Filesystem = filesystemForLog(job.Target)
+	}
+
+	var trace drivers.Trace
+	switch job.Action {
+	case config.JobPurge:
+		var report drivers.PurgeReport
+		report, err = drivers.PurgeItemWithOptions(job.Target, drivers.PurgeOptions{LinkedArtifacts: true})
+		trace = report.Trace
+		log.Wipe.NistLevel = "purge"
+	case config.JobClear:
+		err = drivers.ClearItemTraced(job.Target, &trace)
+		log.Wipe.NistLevel = "clear"
+	case config.JobSweep:
+		profile, ok := cfg.SweepProfile(job.Target)
+		if !ok {
+			return "", fmt.Errorf("unknown sweep profile %q", job.Target)
+		}
+		var report drivers.PurgeReport
+		report, err = drivers.PurgeSweepProfile(profile)
+		trace = report.Trace
+		log.Wipe.NistLevel = "purge"
+		log.Device.Name = "Sweep: " + profile.Name
+		log.Device.Type = "sweep profile"
+	case config.JobFreeSpace:
+		_, err = drivers.WipeFreeSpaceTraced(job.Target, &trace)
+		log.Wipe.NistLevel = "clear"
+		log.Device.Type = "free space"
+	case config.JobRetention:
+		rule, ok := cfg.RetentionRule(job.Target)
+		if !ok {
+			return "", fmt.Errorf("unknown retention rule %q", job.Target)
+		}
+		expired, listErr := drivers.ExpiredFiles(rule, start)
+		if listErr != nil {
+			return "", listErr
+		}
+		// Nothing old enough yet; there is nothing to certify.
+		if len(expired) == 0 {
+			return "", nil
+		}
+		var report drivers.PurgeReport
+		report, err = drivers.ApplyRetention(rule, start)
+		fmt.Printf("Retention %s: %d removed, %d failed\n", rule.Name, len(report.Purged), len(report.Failures))
+		trace = report.Trace
+		log.Wipe.NistLevel = "purge"
+		if rule.Scheme == drivers.SchemeClear {
+			log.Wipe.NistLevel = "clear"
+		}
+		log.Device.Name = fmt.Sprintf("Retention: %s (%d files older than %d days)", rule.Path, len(expired), rule.MaxAgeDays)
+		log.Device.Type = "retention batch"
+		identifyDrive(&log, rule.Path)
+		log.Filesystem = filesystemForLog(rule.Path)
+	default:
+		return "", fmt.Errorf("unknown job action %q", job.Action)
+	}
+
+	finished := time.Now()
+	recordExecution(&log, trace, err)
+	log.Wipe.Status = "success"
+	if err != nil {
+		log.Wipe.Status = "failure"
+	}
+	log.Wipe.StartedAt = start.UTC().Format(time.RFC3339)
+	log.Wipe.FinishedAt = finished.UTC().Format(time.RFC3339)
+	log.Wipe.DurationSec = int(finished.Sub(start).Seconds())
+	recordSystem(&log, "scheduler:"+os.Getenv("USER"))
+
+	certificateMu.Lock()
+	defer certificateMu.Unlock()
+	signErr := signWipeLog(&log)
+	recordWipe(log)
+	if signErr != nil {
+		return "", errors.Join(err, fmt.Errorf("certificate not signed: %v", signErr))
+	}
+	certificate, pdfErr := GeneratePDF(log)
+	if err != nil {
+		return certificate, err
+	}
+	return certificate, pdfErr
+}
+
+// checkJobPolicy applies the device policy to what a job would destroy, as
+// the dashboard does before a wipe. An administrator's unlock is for the
+// person at the screen, so unattended jobs never have it.
+func checkJobPolicy(cfg *config.Config, job config.Job) error {
+	paths := []string{job.Target}
+	switch job.Action {
+	case config.JobSweep:
+		profile, ok := cfg.SweepProfile(job.Target)
+		if !ok {
+			return fmt.Errorf("unknown sweep profile %q", job.Target)
+		}
+		plan, err := drivers.ExpandSweepProfile(profile)
+		if err != nil {
+			return err
+		}
+		paths = plan
+	case config.JobRetention:
+		rule, ok := cfg.RetentionRule(job.Target)
+		if !ok {
+			return fmt.Errorf("unknown retention rule %q", job.Target)
+		}
+		paths = []string{rule.Path}
+	}
+
+	for _, path := range paths {
+		d, ok := drivers.DriveForPath(path)
+		if !ok {
+			if cfg.Policy.RemovableOnly {
+				return fmt.Errorf("cannot tell which drive holds %s; policy allows only removable media", path)
+			}
+			continue
+		}
+		if err := cfg.Policy.Allows(d, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
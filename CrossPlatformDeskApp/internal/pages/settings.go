@@ -1,7 +1,195 @@
 package pages
 
-import rl "github.com/gen2brain/raylib-go/raylib"
+import (
+	"data_wiper/internal/config"
+	"data_wiper/internal/drivers"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
 
-func drawSettingsTab() {
-	rl.DrawText("Settings tab - coming soon", 100, 150, 25, rl.Lime)
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// retentionPreview is a dry run of every retention rule at its next
+// activation, refreshed at most every retentionPreviewTTL.
+type retentionPreview struct {
+	rule  drivers.RetentionRule
+	at    time.Time
+	files []string
+	err   error
+}
+
+const retentionPreviewTTL = 30 * time.Second
+
+// The preview walks every retention folder, so it is computed off the
+// render thread and swapped in when done.
+var (
+	retentionPreviewMu      sync.Mutex
+	retentionPreviews       []retentionPreview
+	retentionPreviewTime    time.Time
+	retentionPreviewRunning bool
+)
+
+// refreshRetentionPreview starts a new dry run in the background unless
+// the last one is recent or still running.
+func refreshRetentionPreview() {
+	retentionPreviewMu.Lock()
+	defer retentionPreviewMu.Unlock()
+	if retentionPreviewRunning || time.Since(retentionPreviewTime) <= retentionPreviewTTL {
+		return
+	}
+	retentionPreviewRunning = true
+
+	go func() {
+		previews := computeRetentionPreview()
+		retentionPreviewMu.Lock()
+		retentionPreviews = previews
+		retentionPreviewTime = time.Now()
+		retentionPreviewRunning = false
+		retentionPreviewMu.Unlock()
+	}()
+}
+
+func computeRetentionPreview() []retentionPreview {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+	}
+
+	next := make(map[string]time.Time)
+	if jobScheduler != nil {
+		for _, u := range jobScheduler.Upcoming() {
+			if u.Job.Action == config.JobRetention {
+				next[u.Job.Target] = u.At
+			}
+		}
+	}
+
+	var previews []retentionPreview
+	for _, rule := range cfg.RetentionRules {
+		at, ok := next[rule.Name]
+		if !ok {
+			at = time.Now()
+		}
+		files, err := drivers.ExpiredFiles(rule, at)
+		previews = append(previews, retentionPreview{rule: rule, at: at, files: files, err: err})
+	}
+	return previews
+}
+
+func drawSettingsTab(screenWidth, screenHeight float32) {
+	const margin = 30.0
+	labelColor := rl.NewColor(0, 255, 180, 255)
+	textColor := rl.NewColor(200, 200, 200, 255)
+	mutedColor := rl.NewColor(0, 200, 150, 200)
+
+	y := float32(150)
+	y = drawPolicyPanel(margin, y, screenWidth-2*margin)
+
+	rl.DrawText("Scheduled Jobs", int32(margin), int32(y), 20, labelColor)
+	y += 30
+
+	if path, err := config.Path(); err == nil {
+		rl.DrawText(fmt.Sprintf("Jobs are defined under \"jobs\" in %s", path), int32(margin), int32(y), 14, mutedColor)
+		y += 24
+	}
+
+	if jobScheduler == nil {
+		return
+	}
+
+	panelWidth := screenWidth - 2*margin
+	upcoming := jobScheduler.Upcoming()
+	upcomingRect := rl.NewRectangle(margin, y, panelWidth, float32(40+20*max(1, len(upcoming))))
+	rl.DrawRectangleRounded(upcomingRect, 0.1, 8, rl.NewColor(10, 50, 30, 200))
+	rl.DrawRectangleRoundedLines(upcomingRect, 0.1, 8, labelColor)
+	rl.DrawText("Upcoming", int32(margin+12), int32(y+10), 16, labelColor)
+	rowY := y + 32
+	if len(upcoming) == 0 {
+		rl.DrawText("No jobs scheduled", int32(margin+24), int32(rowY), 14, textColor)
+	}
+	for _, u := range upcoming {
+		line := fmt.Sprintf("%s  %-20s %s %s", u.At.Format("2006-01-02 15:04"), u.Job.Name, u.Job.Action, u.Job.Target)
+		if u.Job.Action == config.JobFreeSpace {
+			if bytes, seconds, err := drivers.EstimateFreeSpaceWipe(u.Job.Target); err == nil {
+				line += fmt.Sprintf("  (%s to fill, about %s)", formatBytes(bytes), time.Duration(seconds)*time.Second)
+			}
+		}
+		rl.DrawText(line, int32(margin+24), int32(rowY), 14, textColor)
+		rowY += 20
+	}
+	y += upcomingRect.Height + 16
+
+	refreshRetentionPreview()
+	retentionPreviewMu.Lock()
+	previews := retentionPreviews
+	retentionPreviewMu.Unlock()
+	if len(previews) > 0 {
+		const maxFilesShown = 3
+		rows := 0
+		for _, p := range previews {
+			rows += 1 + min(len(p.files), maxFilesShown)
+		}
+		retentionRect := rl.NewRectangle(margin, y, panelWidth, float32(40+20*rows))
+		rl.DrawRectangleRounded(retentionRect, 0.1, 8, rl.NewColor(10, 50, 30, 200))
+		rl.DrawRectangleRoundedLines(retentionRect, 0.1, 8, labelColor)
+		rl.DrawText("Retention (dry run of next evaluation)", int32(margin+12), int32(y+10), 16, labelColor)
+		rowY = y + 32
+		for _, p := range previews {
+			line := fmt.Sprintf("%s: %d files in %s older than %d days at %s", p.rule.Name, len(p.files), p.rule.Path, p.rule.MaxAgeDays, p.at.Format("2006-01-02 15:04"))
+			color := textColor
+			if p.err != nil {
+				line = fmt.Sprintf("%s: %v", p.rule.Name, p.err)
+				color = rl.NewColor(255, 120, 120, 255)
+			}
+			rl.DrawText(line, int32(margin+24), int32(rowY), 14, color)
+			rowY += 20
+			for i, f := range p.files {
+				if i == maxFilesShown {
+					break
+				}
+				name := f
+				if i == maxFilesShown-1 && len(p.files) > maxFilesShown {
+					name = fmt.Sprintf("%s (+%d more)", f, len(p.files)-maxFilesShown)
+				}
+				rl.DrawText(name, int32(margin+44), int32(rowY), 12, mutedColor)
+				rowY += 20
+			}
+		}
+		y += retentionRect.Height + 16
+	}
+
+	history := jobScheduler.History()
+	maxRows := int((screenHeight - y - 80) / 20)
+	if maxRows < 1 {
+		maxRows = 1
+	}
+	if len(history) > maxRows {
+		history = history[:maxRows]
+	}
+
+	historyRect := rl.NewRectangle(margin, y, panelWidth, float32(40+20*max(1, len(history))))
+	rl.DrawRectangleRounded(historyRect, 0.1, 8, rl.NewColor(10, 50, 30, 200))
+	rl.DrawRectangleRoundedLines(historyRect, 0.1, 8, labelColor)
+	rl.DrawText("Past Runs", int32(margin+12), int32(y+10), 16, labelColor)
+	rowY = y + 32
+	if len(history) == 0 {
+		rl.DrawText("No runs yet", int32(margin+24), int32(rowY), 14, textColor)
+	}
+	for _, r := range history {
+		line := fmt.Sprintf("%s  %-20s %s", r.Started.Format("2006-01-02 15:04"), r.Job, r.Status)
+		if r.CatchUp {
+			line += " (catch-up)"
+		}
+		if r.Certificate != "" {
+			line += "  " + filepath.Base(r.Certificate)
+		}
+		color := textColor
+		if r.Status != "success" {
+			color = rl.NewColor(255, 120, 120, 255)
+		}
+		rl.DrawText(line, int32(margin+24), int32(rowY), 14, color)
+		rowY += 20
+	}
 }
@@ -0,0 +1,111 @@
+package pages
+
+import (
+	"data_wiper/internal/config"
+	"data_wiper/internal/drivers"
+	"fmt"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var sweepProfiles []drivers.SweepProfile
+var sweepPlans map[string][]string
+var sweepLoaded bool = false
+var sweepScrollOffset int
+
+// loadSweepProfiles reads the user's profiles from the config file and
+// expands every profile so the cards can show how much each would remove.
+func loadSweepProfiles() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+	}
+	sweepProfiles = cfg.AllSweepProfiles()
+	sweepPlans = make(map[string][]string)
+	for _, p := range sweepProfiles {
+		plan, err := drivers.ExpandSweepProfile(p)
+		if err != nil {
+			fmt.Printf("Failed to expand sweep profile %s: %v\n", p.Name, err)
+			continue
+		}
+		sweepPlans[p.Name] = plan
+	}
+	sweepLoaded = true
+}
+
+func drawSweepTab(screenWidth, screenHeight float32) {
+	if !sweepLoaded {
+		loadSweepProfiles()
+	}
+
+	const margin = 30.0
+	dialogsActive := IsConfirmPurgeActive() || IsConfirmClearActive() || IsCertificateActive()
+
+	startY := float32(150.0)
+	cardHeight := float32(80.0)
+	cardSpacing := float32(90.0)
+
+	availableHeight := screenHeight - startY - 60
+	maxVisibleProfiles := int(availableHeight / cardSpacing)
+	if maxVisibleProfiles < 1 {
+		maxVisibleProfiles = 1
+	}
+
+	totalProfiles := len(sweepProfiles)
+	if totalProfiles > maxVisibleProfiles {
+		maxScroll := totalProfiles - maxVisibleProfiles
+		wheelMove := rl.GetMouseWheelMove()
+		if wheelMove < 0 && sweepScrollOffset < maxScroll {
+			sweepScrollOffset++
+		} else if wheelMove > 0 && sweepScrollOffset > 0 {
+			sweepScrollOffset--
+		}
+	} else {
+		sweepScrollOffset = 0
+	}
+
+	clipRect := rl.NewRectangle(margin, startY, screenWidth-2*margin, availableHeight)
+	rl.BeginScissorMode(int32(clipRect.X), int32(clipRect.Y), int32(clipRect.Width), int32(clipRect.Height))
+
+	visibleCount := 0
+	for i := sweepScrollOffset; i < totalProfiles && visibleCount < maxVisibleProfiles; i++ {
+		p := sweepProfiles[i]
+		y := startY + float32(visibleCount)*cardSpacing
+
+		boxWidth := screenWidth - 2*margin - 15
+		box := rl.NewRectangle(margin, y, boxWidth, cardHeight)
+
+		mouse := rl.GetMousePosition()
+		hover := rl.CheckCollisionPointRec(mouse, box)
+		if hover {
+			rl.DrawRectangleRounded(box, 0.2, 10, rl.NewColor(15, 70, 45, 220))
+			rl.DrawRectangleRoundedLines(box, 0.2, 10, rl.NewColor(50, 255, 200, 255))
+		} else {
+			rl.DrawRectangleRounded(box, 0.2, 10, rl.NewColor(10, 50, 30, 200))
+			rl.DrawRectangleRoundedLines(box, 0.2, 10, rl.NewColor(0, 255, 180, 255))
+		}
+
+		rl.DrawText("🧹", int32(box.X+20), int32(box.Y+25), 24, rl.NewColor(0, 255, 180, 255))
+		rl.DrawText(p.Name, int32(box.X+60), int32(box.Y+15), 20, rl.NewColor(0, 255, 180, 255))
+
+		plan := sweepPlans[p.Name]
+		infoText := fmt.Sprintf("%s | %d items", p.Description, len(plan))
+		rl.DrawText(infoText, int32(box.X+60), int32(box.Y+40), 14, rl.NewColor(0, 200, 150, 200))
+
+		sweepBtn := rl.NewRectangle(box.X+boxWidth-120, box.Y+22, 100, 36)
+		drawGlowingButton(sweepBtn, "Sweep", rl.NewColor(200, 50, 50, 255), rl.NewColor(255, 255, 255, 255))
+		if !dialogsActive && len(plan) > 0 && rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(mouse, sweepBtn) {
+			ShowConfirmSweep(p)
+		}
+
+		visibleCount++
+	}
+
+	rl.EndScissorMode()
+
+	refreshBtn := rl.NewRectangle(screenWidth-margin-100, startY-50, 100, 40)
+	drawGlowingButton(refreshBtn, "🔄 Refresh", rl.NewColor(0, 180, 255, 255), rl.NewColor(255, 255, 255, 255))
+	if !dialogsActive && rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(rl.GetMousePosition(), refreshBtn) {
+		sweepLoaded = false
+	}
+}
@@ -0,0 +1,145 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseScheduleErrors(t *testing.T) {
+	for expr, want := range map[string]string{
+		"":                "must have 5 fields",
+		"* * * *":         "must have 5 fields",
+		"* * * * * *":     "must have 5 fields",
+		"60 * * * *":      "minute",
+		"* 24 * * *":      "hour",
+		"* * 0 * *":       "day of month",
+		"* * 32 * *":      "day of month",
+		"* * * 13 *":      "month",
+		"* * * foo *":     "month",
+		"* * * * 8":       "day of week",
+		"*/0 * * * *":     "bad step",
+		"*/x * * * *":     "bad step",
+		"5-1 * * * *":     "outside",
+		"1,,2 * * * *":    "bad value",
+		"@fortnightly":    "must have 5 fields",
+		"* * * jan-xyz *": "bad value",
+	} {
+		if _, err := ParseSchedule(expr); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseSchedule(%q) = %v, want an error about %q", expr, err, want)
+		}
+	}
+}
+
+func mustParse(t *testing.T, expr string) *Schedule {
+	t.Helper()
+	s, err := ParseSchedule(expr)
+	if err != nil {
+		t.Fatalf("ParseSchedule(%q): %v", expr, err)
+	}
+	return s
+}
+
+// activations lists the first n activations after from.
+func activations(s *Schedule, from time.Time, n int) []string {
+	var got []string
+	for t := from; len(got) < n; {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		got = append(got, t.Format("2006-01-02 15:04 MST"))
+	}
+	return got
+}
+
+func TestNext(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) // a Thursday
+	cases := []struct {
+		expr string
+		want []string
+	}{
+		{"*/20 * * * *", []string{"2026-01-01 00:20 UTC", "2026-01-01 00:40 UTC", "2026-01-01 01:00 UTC"}},
+		// lo/n runs from lo to the end of the range
+		{"50/5 9 * * *", []string{"2026-01-01 09:50 UTC", "2026-01-01 09:55 UTC", "2026-01-02 09:50 UTC"}},
+		{"0 0-12/6 * * *", []string{"2026-01-01 06:00 UTC", "2026-01-01 12:00 UTC", "2026-01-02 00:00 UTC"}},
+		{"30 2 * * *", []string{"2026-01-01 02:30 UTC", "2026-01-02 02:30 UTC"}},
+		{"0 0 * jan,mar sun", []string{"2026-01-04 00:00 UTC", "2026-01-11 00:00 UTC", "2026-01-18 00:00 UTC", "2026-01-25 00:00 UTC", "2026-03-01 00:00 UTC"}},
+		// Sunday as 7
+		{"0 12 * * 7", []string{"2026-01-04 12:00 UTC"}},
+		{"@monthly", []string{"2026-02-01 00:00 UTC", "2026-03-01 00:00 UTC"}},
+		// Day of month or day of week when both are restricted...
+		{"0 0 13 * fri", []string{"2026-01-02 00:00 UTC", "2026-01-09 00:00 UTC", "2026-01-13 00:00 UTC", "2026-01-16 00:00 UTC"}},
+		// ...but both when one of them is *
+		{"0 0 */10 * sat", []string{"2026-01-31 00:00 UTC", "2026-02-21 00:00 UTC"}},
+		{"0 0 29 2 *", []string{"2028-02-29 00:00 UTC"}},
+		// Impossible dates never fire
+		{"0 0 31 4 *", nil},
+		{"0 0 30 2 *", nil},
+	}
+	for _, c := range cases {
+		got := activations(mustParse(t, c.expr), from, len(c.want)+1)
+		if len(c.want) == 0 {
+			if len(got) != 0 {
+				t.Errorf("%q fired at %v", c.expr, got)
+			}
+			continue
+		}
+		if strings.Join(got[:len(c.want)], ", ") != strings.Join(c.want, ", ") {
+			t.Errorf("%q: %v, want %v", c.expr, got, c.want)
+		}
+	}
+
+	// Strictly after, from any second of the minute
+	s := mustParse(t, "30 2 * * *")
+	if got := s.Next(time.Date(2026, 1, 1, 2, 30, 59, 0, time.UTC)); !got.Equal(time.Date(2026, 1, 2, 2, 30, 0, 0, time.UTC)) {
+		t.Errorf("Next from within an activation minute = %v", got)
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Clocks went from 01:59 EST to 03:00 EDT on 2026-03-08, and from
+	// 01:59 EDT back to 01:00 EST on 2026-11-01
+	spring := time.Date(2026, 3, 7, 12, 0, 0, 0, ny)
+	autumn := time.Date(2026, 10, 31, 12, 0, 0, 0, ny)
+	cases := []struct {
+		expr string
+		from time.Time
+		want []string
+	}{
+		{"30 2 * * *", spring, []string{"2026-03-08 03:00 EDT", "2026-03-09 02:30 EDT"}},
+		{"0 4 * * *", spring, []string{"2026-03-08 04:00 EDT", "2026-03-09 04:00 EDT"}},
+		{"0 1 * * *", spring, []string{"2026-03-08 01:00 EST", "2026-03-09 01:00 EDT"}},
+		{"30 1 * * *", autumn, []string{"2026-11-01 01:30 EDT", "2026-11-02 01:30 EST"}},
+		{"0 3 * * *", autumn, []string{"2026-11-01 03:00 EST", "2026-11-02 03:00 EST"}},
+		// Hourly jobs run in both copies of the repeated hour
+		{"15 * * * *", time.Date(2026, 11, 1, 0, 30, 0, 0, ny), []string{"2026-11-01 01:15 EDT", "2026-11-01 01:15 EST", "2026-11-01 02:15 EST"}},
+	}
+	for _, c := range cases {
+		done := make(chan []string)
+		go func() { done <- activations(mustParse(t, c.expr), c.from, len(c.want)) }()
+		select {
+		case got := <-done:
+			if strings.Join(got, ", ") != strings.Join(c.want, ", ") {
+				t.Errorf("%q from %v: %v, want %v", c.expr, c.from, got, c.want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q from %v: Next does not return", c.expr, c.from)
+		}
+	}
+
+	// Midnight skipped, as in Santiago on 2026-09-06
+	scl, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := mustParse(t, "0 12 * * *")
+	if got := s.Next(time.Date(2026, 9, 5, 13, 0, 0, 0, scl)); got.Day() != 6 || got.Hour() != 12 {
+		t.Errorf("across a skipped midnight: %v", got)
+	}
+}
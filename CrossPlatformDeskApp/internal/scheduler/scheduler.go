@@ -0,0 +1,281 @@
+package scheduler
+
+import (
+	"data_wiper/internal/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxHistory caps how many past runs are kept in the state file.
+const maxHistory = 200
+
+// tickInterval is how often due jobs are looked for.
+const tickInterval = 30 * time.Second
+
+// RunFunc executes a job through the wipe engine and returns the path of the
+// certificate it produced.
+type RunFunc func(job config.Job) (certificate string, err error)
+
+// Run is the record of one job execution.
+type Run struct {
+	Job         string    `json:"job"`
+	Scheduled   time.Time `json:"scheduled"`
+	Started     time.Time `json:"started"`
+	Finished    time.Time `json:"finished"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Certificate string    `json:"certificate,omitempty"`
+	// CatchUp marks a run started at launch for an activation that was
+	// missed while the app was not running.
+	CatchUp bool `json:"catch_up,omitempty"`
+}
+
+// Upcoming is the next planned activation of a job.
+type Upcoming struct {
+	Job config.Job
+	At  time.Time
+}
+
+// state is what survives restarts: when each job last ran and the history.
+type state struct {
+	LastRun map[string]time.Time `json:"last_run"`
+	Runs    []Run                `json:"runs"`
+}
+
+// Scheduler runs config jobs on their cron schedules.
+type Scheduler struct {
+	mu        sync.Mutex
+	jobs      []config.Job
+	schedules map[string]*Schedule
+	state     state
+	statePath string
+	run       RunFunc
+	stop      chan struct{}
+	// now is the clock jobs are scheduled by; time.Now outside tests.
+	now func() time.Time
+}
+
+// New prepares a scheduler for the enabled jobs, keeping its state in the
+// config directory. Jobs with an invalid schedule are left out and reported
+// in the returned error; the scheduler is still usable for the rest.
+func New(jobs []config.Job, run RunFunc) (*Scheduler, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		s, jobsErr := open(jobs, run, "")
+		return s, errors.Join(jobsErr, err)
+	}
+	return open(jobs, run, filepath.Join(dir, "schedule.json"))
+}
+
+// open is New with the state kept at statePath, or nowhere when it is "".
+func open(jobs []config.Job, run RunFunc, statePath string) (*Scheduler, error) {
+	s := &Scheduler{
+		schedules: make(map[string]*Schedule),
+		state:     state{LastRun: make(map[string]time.Time)},
+		statePath: statePath,
+		run:       run,
+		now:       time.Now,
+	}
+
+	var errs []error
+	for _, job := range jobs {
+		if job.Disabled {
+			continue
+		}
+		sched, err := ParseSchedule(job.Schedule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("job %s: %v", job.Name, err))
+			continue
+		}
+		s.jobs = append(s.jobs, job)
+		s.schedules[job.Name] = sched
+	}
+
+	if s.statePath != "" {
+		if err := s.load(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return s, errors.Join(errs...)
+}
+
+// Start catches up on activations missed while the app was closed and then
+// checks for due jobs in the background until Stop is called.
+func (s *Scheduler) Start() {
+	stop := make(chan struct{})
+	s.mu.Lock()
+	s.stop = stop
+	s.mu.Unlock()
+	go func() {
+		s.catchUp()
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.tick()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the background loop. A job already running is allowed to finish.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// catchUp runs once every job whose activation passed while the app was not
+// running. Several missed activations collapse into a single run. A job seen
+// for the first time starts counting from now instead.
+func (s *Scheduler) catchUp() {
+	now := s.now()
+	for _, job := range s.jobs {
+		s.mu.Lock()
+		last, seen := s.state.LastRun[job.Name]
+		if !seen {
+			s.state.LastRun[job.Name] = now
+		}
+		s.mu.Unlock()
+		if !seen {
+			continue
+		}
+
+		due := s.schedules[job.Name].Next(last)
+		if !due.IsZero() && !due.After(now) {
+			s.execute(job, due, true)
+		}
+	}
+	s.save()
+}
+
+func (s *Scheduler) tick() {
+	now := s.now()
+	for _, job := range s.jobs {
+		s.mu.Lock()
+		last := s.state.LastRun[job.Name]
+		s.mu.Unlock()
+
+		due := s.schedules[job.Name].Next(last)
+		if !due.IsZero() && !due.After(now) {
+			s.execute(job, due, false)
+		}
+	}
+}
+
+func (s *Scheduler) execute(job config.Job, scheduled time.Time, catchUp bool) {
+	run := Run{
+		Job:       job.Name,
+		Scheduled: scheduled,
+		Started:   s.now(),
+		CatchUp:   catchUp,
+	}
+
+	s.mu.Lock()
+	s.state.LastRun[job.Name] = run.Started
+	s.mu.Unlock()
+
+	fmt.Printf("Running scheduled job %s (%s %s)\n", job.Name, job.Action, job.Target)
+	cert, err := s.run(job)
+	run.Finished = s.now()
+	run.Certificate = cert
+	run.Status = "success"
+	if err != nil {
+		run.Status = "failure"
+		run.Error = err.Error()
+		fmt.Printf("Scheduled job %s failed: %v\n", job.Name, err)
+	}
+
+	s.mu.Lock()
+	s.state.Runs = append(s.state.Runs, run)
+	if len(s.state.Runs) > maxHistory {
+		s.state.Runs = s.state.Runs[len(s.state.Runs)-maxHistory:]
+	}
+	s.mu.Unlock()
+	s.save()
+}
+
+// Upcoming lists the next activation of every job, soonest first.
+func (s *Scheduler) Upcoming() []Upcoming {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var list []Upcoming
+	for _, job := range s.jobs {
+		from := now
+		if last, ok := s.state.LastRun[job.Name]; ok && last.Before(now) {
+			from = last
+		}
+		next := s.schedules[job.Name].Next(from)
+		if next.IsZero() {
+			continue
+		}
+		list = append(list, Upcoming{Job: job, At: next})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].At.Before(list[j].At) })
+	return list
+}
+
+// History returns past runs, newest first.
+func (s *Scheduler) History() []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := make([]Run, len(s.state.Runs))
+	for i, r := range s.state.Runs {
+		runs[len(runs)-1-i] = r
+	}
+	return runs
+}
+
+func (s *Scheduler) load() error {
+	data, err := os.ReadFile(s.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read schedule state: %v", err)
+	}
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		return fmt.Errorf("failed to parse schedule state: %v", err)
+	}
+	if s.state.LastRun == nil {
+		s.state.LastRun = make(map[string]time.Time)
+	}
+	return nil
+}
+
+func (s *Scheduler) save() {
+	if s.statePath == "" {
+		return
+	}
+
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.statePath), 0700); err != nil {
+		fmt.Printf("Failed to save schedule state: %v\n", err)
+		return
+	}
+	if err := os.WriteFile(s.statePath, data, 0600); err != nil {
+		fmt.Printf("Failed to save schedule state: %v\n", err)
+	}
+}
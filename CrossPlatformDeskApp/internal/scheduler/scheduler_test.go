@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"data_wiper/internal/config"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock the test moves by hand.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+// testScheduler opens a scheduler over jobs with its state file in a temp
+// directory, seeded with last, and a runner that records the jobs run.
+func testScheduler(t *testing.T, jobs []config.Job, last map[string]time.Time, clock *fakeClock) (*Scheduler, *[]string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	if last != nil {
+		data, _ := json.Marshal(state{LastRun: last})
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var ran []string
+	s, err := open(jobs, func(job config.Job) (string, error) {
+		ran = append(ran, job.Name)
+		if job.Action == "fail" {
+			return "", errors.New("target is gone")
+		}
+		return "pdfs/" + job.Name + ".pdf", nil
+	}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = clock.now
+	return s, &ran
+}
+
+var nightly = config.Job{Name: "nightly", Schedule: "0 2 * * *", Action: config.JobPurge, Target: "/tmp/x"}
+
+func TestCatchUpCollapsesMissedRuns(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC)
+	// Closed from Monday's run until Friday morning: four runs missed
+	clock := &fakeClock{time.Date(2026, 3, 6, 10, 0, 0, 0, time.UTC)}
+	s, ran := testScheduler(t, []config.Job{nightly}, map[string]time.Time{"nightly": monday}, clock)
+
+	s.catchUp()
+	runs := s.History()
+	if len(*ran) != 1 || len(runs) != 1 {
+		t.Fatalf("ran %q, history %+v; want a single catch-up run", *ran, runs)
+	}
+	if r := runs[0]; !r.CatchUp || !r.Scheduled.Equal(monday.AddDate(0, 0, 1)) || r.Status != "success" || r.Certificate != "pdfs/nightly.pdf" {
+		t.Errorf("catch-up run %+v", r)
+	}
+
+	// Nothing more is due until the next night
+	s.tick()
+	clock.t = time.Date(2026, 3, 7, 1, 59, 0, 0, time.UTC)
+	s.tick()
+	if len(*ran) != 1 {
+		t.Fatalf("ran %q before the next activation", *ran)
+	}
+	clock.t = time.Date(2026, 3, 7, 2, 0, 30, 0, time.UTC)
+	s.tick()
+	if runs := s.History(); len(runs) != 2 || runs[0].CatchUp || !runs[0].Scheduled.Equal(time.Date(2026, 3, 7, 2, 0, 0, 0, time.UTC)) {
+		t.Errorf("history after the next night %+v", runs)
+	}
+}
+
+func TestCatchUpNewJob(t *testing.T) {
+	clock := &fakeClock{time.Date(2026, 3, 6, 10, 0, 0, 0, time.UTC)}
+	s, ran := testScheduler(t, []config.Job{nightly}, nil, clock)
+
+	s.catchUp()
+	if len(*ran) != 0 {
+		t.Fatalf("a job seen for the first time ran: %q", *ran)
+	}
+	// It counts from now, which is remembered across restarts
+	reopened, _ := open([]config.Job{nightly}, nil, s.statePath)
+	if last := reopened.state.LastRun["nightly"]; !last.Equal(clock.t) {
+		t.Errorf("saved last run %s, want %s", last, clock.t)
+	}
+	reopened.now = clock.now
+	if up := reopened.Upcoming(); len(up) != 1 || !up[0].At.Equal(time.Date(2026, 3, 7, 2, 0, 0, 0, time.UTC)) {
+		t.Errorf("Upcoming = %+v", up)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	failing := config.Job{Name: "failing", Schedule: "* * * * *", Action: "fail"}
+	clock := &fakeClock{time.Date(2026, 3, 6, 10, 0, 0, 0, time.UTC)}
+	s, _ := testScheduler(t, []config.Job{failing}, map[string]time.Time{"failing": clock.t}, clock)
+
+	for i := 0; i < maxHistory+5; i++ {
+		clock.t = clock.t.Add(time.Minute)
+		s.tick()
+	}
+	runs := s.History()
+	if len(runs) != maxHistory {
+		t.Fatalf("%d runs kept, want %d", len(runs), maxHistory)
+	}
+	// Newest first, the oldest dropped
+	if !runs[0].Scheduled.Equal(clock.t) || !runs[len(runs)-1].Scheduled.Equal(clock.t.Add(-(maxHistory-1)*time.Minute)) {
+		t.Errorf("history runs from %s to %s", runs[0].Scheduled, runs[len(runs)-1].Scheduled)
+	}
+	if r := runs[0]; r.Status != "failure" || !strings.Contains(r.Error, "target is gone") {
+		t.Errorf("failed run recorded as %+v", r)
+	}
+
+	reopened, _ := open([]config.Job{failing}, nil, s.statePath)
+	if len(reopened.History()) != maxHistory {
+		t.Errorf("%d runs saved", len(reopened.History()))
+	}
+}
+
+func TestUpcoming(t *testing.T) {
+	weekly := config.Job{Name: "weekly", Schedule: "30 3 * * 0", Action: config.JobSweep, Target: "Trash"}
+	hourly := config.Job{Name: "hourly", Schedule: "@hourly", Action: config.JobClear, Target: "/tmp/y"}
+	broken := config.Job{Name: "broken", Schedule: "61 * * * *"}
+	off := config.Job{Name: "off", Schedule: "* * * * *", Disabled: true}
+
+	clock := &fakeClock{time.Date(2026, 3, 6, 10, 15, 0, 0, time.UTC)}
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	s, err := open([]config.Job{weekly, nightly, broken, hourly, off}, nil, path)
+	if err == nil || !strings.Contains(err.Error(), "job broken") {
+		t.Errorf("open err = %v, want the broken job reported", err)
+	}
+	s.now = clock.now
+
+	var got []string
+	for _, u := range s.Upcoming() {
+		got = append(got, u.Job.Name+" "+u.At.Format("Mon 15:04"))
+	}
+	want := "hourly Fri 11:00, nightly Sat 02:00, weekly Sun 03:30"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("Upcoming = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,13 @@
+package sysinfo
+
+import "syscall"
+
+// platformRelease returns the Darwin kernel release and the macOS version,
+// since macOS has no os-release.
+func platformRelease() (kernel, platform string) {
+	kernel, _ = syscall.Sysctl("kern.osrelease")
+	if v, err := syscall.Sysctl("kern.osproductversion"); err == nil && v != "" {
+		platform = "macOS " + v
+	}
+	return kernel, platform
+}
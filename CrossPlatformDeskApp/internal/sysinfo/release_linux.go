@@ -0,0 +1,26 @@
+package sysinfo
+
+import "syscall"
+
+// platformRelease returns the kernel release from uname(2). Linux names
+// its distribution in os-release instead.
+func platformRelease() (kernel, platform string) {
+	var u syscall.Utsname
+	if err := syscall.Uname(&u); err != nil {
+		return "", ""
+	}
+	return utsString(u.Release[:]), ""
+}
+
+// utsString reads a NUL-terminated utsname field, whose element type
+// differs between architectures.
+func utsString[T int8 | uint8](field []T) string {
+	b := make([]byte, 0, len(field))
+	for _, c := range field {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	return string(b)
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package sysinfo
+
+import "runtime"
+
+// platformRelease has no kernel release to report on other systems.
+func platformRelease() (kernel, platform string) {
+	return "", runtime.GOOS
+}
@@ -0,0 +1,153 @@
+// Package sysinfo describes the machine and build a wipe runs on, for the
+// System block of every certificate and the About screen.
+package sysinfo
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// appID keys the machine ID hash, so the hash identifies a machine across
+// this tool's certificates without being reusable as the ID itself.
+const appID = "nullbyters-certificates"
+
+// Info is what Collect reports. The JSON names tool_version and host_os are
+// the ones certificates have always used.
+type Info struct {
+	ToolVersion string `json:"tool_version"`
+	// Revision is the VCS commit the binary was built from; Modified is
+	// set when the working tree had uncommitted changes
+	Revision  string `json:"vcs_revision,omitempty"`
+	Modified  bool   `json:"vcs_modified,omitempty"`
+	GoVersion string `json:"go_version,omitempty"`
+	// HostOS is the distribution's pretty name, e.g. "Ubuntu 22.04.4 LTS"
+	HostOS    string `json:"host_os"`
+	OSID      string `json:"os_id,omitempty"`
+	OSVersion string `json:"os_version,omitempty"`
+	Kernel    string `json:"kernel,omitempty"`
+	Hostname  string `json:"hostname"`
+	// MachineIDHash is an HMAC-SHA256 of the machine ID, as systemd
+	// recommends for IDs that leave the machine
+	MachineIDHash string `json:"machine_id_hash,omitempty"`
+	Arch          string `json:"arch"`
+}
+
+// Collect reads the facts of this machine and binary. Facts that cannot be
+// read are left empty.
+func Collect() Info {
+	return collect("/")
+}
+
+// collect reads the files it needs below root, so tests can supply their own.
+func collect(root string) Info {
+	info := Info{Arch: runtime.GOARCH}
+	info.Hostname, _ = os.Hostname()
+	readBuildInfo(&info)
+
+	kernel, platform := platformRelease()
+	info.Kernel = kernel
+	rel := osRelease(root)
+	info.OSID, info.OSVersion = rel["ID"], rel["VERSION_ID"]
+	switch {
+	case rel["PRETTY_NAME"] != "":
+		info.HostOS = rel["PRETTY_NAME"]
+	case rel["NAME"] != "":
+		info.HostOS = strings.TrimSpace(rel["NAME"] + " " + rel["VERSION"])
+	case platform != "":
+		info.HostOS = platform
+	default:
+		info.HostOS = runtime.GOOS
+	}
+	info.MachineIDHash = machineIDHash(root)
+	return info
+}
+
+// readBuildInfo fills the version fields from the module and VCS data the
+// Go toolchain stamps into the binary.
+func readBuildInfo(info *Info) {
+	info.ToolVersion = "devel"
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	info.GoVersion = bi.GoVersion
+	if v := bi.Main.Version; v != "" && v != "(devel)" {
+		info.ToolVersion = v
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	if info.ToolVersion == "devel" && info.Revision != "" {
+		info.ToolVersion += "-" + info.Revision[:min(12, len(info.Revision))]
+	}
+}
+
+// osRelease parses /etc/os-release, or /usr/lib/os-release where it is
+// missing, into its variables with quotes and escapes removed.
+func osRelease(root string) map[string]string {
+	vars := make(map[string]string)
+	for _, path := range []string{"etc/os-release", "usr/lib/os-release"} {
+		data, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || strings.HasPrefix(line, "#") {
+				continue
+			}
+			vars[key] = unquote(value)
+		}
+		break
+	}
+	return vars
+}
+
+// unquote undoes the shell-style quoting os-release allows.
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if s, err := strconv.Unquote(value); err == nil {
+			return s
+		}
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// machineIDHash hashes the systemd or D-Bus machine ID, or returns "" where
+// there is none.
+func machineIDHash(root string) string {
+	for _, path := range []string{"etc/machine-id", "var/lib/dbus/machine-id"} {
+		data, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			continue
+		}
+		id := strings.TrimSpace(string(data))
+		if id == "" || id == "uninitialized" {
+			continue
+		}
+		mac := hmac.New(sha256.New, []byte(id))
+		mac.Write([]byte(appID))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	return ""
+}
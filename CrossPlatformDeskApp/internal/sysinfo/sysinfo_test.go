@@ -0,0 +1,84 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, root, path, data string) {
+	t.Helper()
+	path = filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectOSRelease(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "etc/os-release", `PRETTY_NAME="Ubuntu 22.04.4 LTS"
+NAME="Ubuntu"
+VERSION_ID="22.04"
+# a comment
+ID=ubuntu
+`)
+	writeFile(t, root, "etc/machine-id", "4c4c4544004b4a1080335ac04f4e5031\n")
+
+	info := collect(root)
+	if info.HostOS != "Ubuntu 22.04.4 LTS" || info.OSID != "ubuntu" || info.OSVersion != "22.04" {
+		t.Errorf("os = %q, id %q, version %q", info.HostOS, info.OSID, info.OSVersion)
+	}
+	if info.Arch != runtime.GOARCH || info.ToolVersion == "" {
+		t.Errorf("arch %q, tool version %q", info.Arch, info.ToolVersion)
+	}
+	// The hash is stable and does not give the ID away
+	if len(info.MachineIDHash) != 64 || strings.Contains(info.MachineIDHash, "4c4c4544") || info.MachineIDHash != collect(root).MachineIDHash {
+		t.Errorf("machine ID hash = %q", info.MachineIDHash)
+	}
+}
+
+func TestOSReleaseFallbacks(t *testing.T) {
+	tests := []struct {
+		files map[string]string
+		want  string
+	}{
+		// /usr/lib/os-release stands in for a missing /etc/os-release
+		{map[string]string{"usr/lib/os-release": "NAME='Fedora Linux'\nVERSION=\"40 (Workstation Edition)\"\n"}, "Fedora Linux 40 (Workstation Edition)"},
+		{map[string]string{"etc/os-release": `PRETTY_NAME="Debian \"trixie\""`}, `Debian "trixie"`},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		for path, data := range tt.files {
+			writeFile(t, root, path, data)
+		}
+		if got := collect(root).HostOS; got != tt.want {
+			t.Errorf("HostOS = %q, want %q", got, tt.want)
+		}
+	}
+
+	// Without os-release the platform names itself
+	if got := collect(t.TempDir()).HostOS; got == "" {
+		t.Error("HostOS is empty without os-release")
+	}
+}
+
+func TestMachineIDHash(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	writeFile(t, a, "etc/machine-id", "4c4c4544004b4a1080335ac04f4e5031\n")
+	writeFile(t, b, "var/lib/dbus/machine-id", "4c4c4544004b4a1080335ac04f4e5031")
+	if machineIDHash(a) != machineIDHash(b) {
+		t.Error("the D-Bus machine ID hashes differently")
+	}
+	writeFile(t, b, "etc/machine-id", "uninitialized\n")
+	if machineIDHash(a) != machineIDHash(b) {
+		t.Error("an uninitialized machine-id was hashed")
+	}
+	if h := machineIDHash(t.TempDir()); h != "" {
+		t.Errorf("hash without a machine ID = %q", h)
+	}
+}
@@ -0,0 +1,153 @@
+// Package wipelog holds the wipe certificate the app signs, and signs it so
+// that the saved certificate verifies.
+package wipelog
+
+import (
+	"crypto"
+	"data_wiper/internal/certs"
+	"data_wiper/internal/drivers"
+	"data_wiper/internal/ledger"
+	"data_wiper/internal/sysinfo"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Log is a wipe certificate: what was wiped, how, on which machine, and
+// the signature over all of it.
+type Log struct {
+	Device struct {
+		Name        string `json:"name"`
+		Serial      string `json:"serial"`
+		SizeGB      int    `json:"size_gb"`
+		Type        string `json:"type"`
+		Fingerprint string `json:"fingerprint,omitempty"`
+		// Path is the device node or file that was wiped
+		Path string `json:"path,omitempty"`
+		// Container is the drive holding a wiped file or partition
+		Container *ContainingDevice `json:"containing_device,omitempty"`
+	} `json:"device"`
+	Wipe struct {
+		Method      string `json:"method"`
+		NistLevel   string `json:"nist_level"`
+		Status      string `json:"status"`
+		StartedAt   string `json:"started_at"`
+		FinishedAt  string `json:"finished_at"`
+		DurationSec int    `json:"duration_sec"`
+	} `json:"wipe"`
+	// System is collected when the log is written
+	System struct {
+		sysinfo.Info
+		ExecutedBy string `json:"executed_by"`
+	} `json:"system"`
+	// Health is the drive's SMART snapshot taken before a device wipe
+	Health *drivers.HealthReport `json:"health,omitempty"`
+	// Filesystem is the capacity of the filesystem a file or free-space
+	// wipe ran on
+	Filesystem *drivers.FilesystemInfo `json:"filesystem,omitempty"`
+	// Capacity is the fake-capacity probe run before a device wipe
+	Capacity *drivers.CapacityReport `json:"capacity,omitempty"`
+	// USB identifies the USB device or bridge the drive was attached through
+	USB *drivers.USBInfo `json:"usb,omitempty"`
+	// Execution is what the wipe actually did, as its drivers traced it
+	Execution *Execution `json:"execution,omitempty"`
+	// Ledger is the log's place in the certificate ledger, signed with it
+	Ledger    *ledger.Link `json:"ledger,omitempty"`
+	Signature struct {
+		Algorithm            string `json:"algorithm"`
+		KeyID                string `json:"key_id"`
+		Sig                  string `json:"sig"`
+		PublicKeyFingerprint string `json:"public_key_fingerprint"`
+		LogHash              string `json:"log_hash"`
+		// Chain is the signing key's X.509 chain, leaf first, in base64 DER
+		Chain []string `json:"x5c,omitempty"`
+		// Timestamp is a TSA's RFC 3161 token over the log and its
+		// signature, or why there is none
+		Timestamp *certs.Timestamp `json:"timestamp,omitempty"`
+	} `json:"signature"`
+}
+
+// ContainingDevice identifies the drive a wiped file or partition is on.
+type ContainingDevice struct {
+	Device string `json:"device"`
+	Model  string `json:"model"`
+	Serial string `json:"serial"`
+}
+
+// Execution records the engines, passes, item counts and errors of a wipe
+// run and the host it ran on.
+type Execution struct {
+	drivers.Trace
+	Host HostFacts `json:"host"`
+}
+
+// HostFacts describes the process a wipe ran in, read when it ran. The
+// machine itself is described by Log.System.
+type HostFacts struct {
+	User string `json:"user"`
+	UID  string `json:"uid,omitempty"`
+	PID  int    `json:"pid"`
+}
+
+// Sign signs everything in the log but its signature block with key and
+// fills the block in. stamp, when set, time-stamps the signed payload. The
+// log is then saved and read back as JSON and verified against key: a log
+// that would not verify is left unsigned and the error says why.
+func (l *Log) Sign(key *certs.Key, stamp func(certs.Payload) *certs.Timestamp) error {
+	if key == nil {
+		return errors.New("no signing key")
+	}
+	unsigned, err := l.Unsigned()
+	if err != nil {
+		return err
+	}
+	payload, err := certs.Sign(key, unsigned)
+	if err != nil {
+		return err
+	}
+	hash, _ := payload.Hash()
+
+	l.Signature.Algorithm = payload.Alg
+	l.Signature.KeyID = payload.KeyID
+	l.Signature.PublicKeyFingerprint = payload.KeyID
+	l.Signature.Sig = payload.Sig
+	l.Signature.LogHash = hash
+	l.Signature.Chain = payload.Chain
+	if stamp != nil {
+		l.Signature.Timestamp = stamp(payload)
+	}
+
+	// Catch fields that would not survive a trip through the saved JSON
+	saved, err := l.Payload()
+	if err == nil {
+		err = certs.Verify(saved, []crypto.PublicKey{key.Public()})
+	}
+	if err != nil {
+		l.Signature = Log{}.Signature
+		return fmt.Errorf("certificate for %s would not verify: %v", l.Device.Name, err)
+	}
+	return nil
+}
+
+// Unsigned is the log as signed: its JSON without the signature block.
+func (l Log) Unsigned() (json.RawMessage, error) {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "signature")
+	return json.Marshal(fields)
+}
+
+// Payload rebuilds the signed payload of a signed log.
+func (l Log) Payload() (certs.Payload, error) {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return certs.Payload{}, err
+	}
+	return certs.ParseJSON(data)
+}
@@ -0,0 +1,122 @@
+package wipelog
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"data_wiper/internal/certs"
+	"data_wiper/internal/drivers"
+	"data_wiper/internal/ledger"
+	"data_wiper/internal/sysinfo"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKey(t *testing.T) *certs.Key {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &certs.Key{ID: certs.KeyID(pub), Private: priv}
+}
+
+// fullLog is a disk wipe certificate with every optional section filled.
+func fullLog() Log {
+	var l Log
+	l.Device.Name = "Ultra Fit"
+	l.Device.Serial = "4C530001230712114275"
+	l.Device.SizeGB = 30
+	l.Device.Type = "usb"
+	l.Device.Fingerprint = "usb:0781:5583:4C530001230712114275"
+	l.Device.Path = "/dev/sdc"
+	l.Device.Container = &ContainingDevice{Device: "/dev/sdc", Model: "Ultra Fit", Serial: "4C530001230712114275"}
+	l.Wipe.Method = "overwrite_verify"
+	l.Wipe.NistLevel = "clear"
+	l.Wipe.Status = "failure"
+	l.Wipe.StartedAt = "2026-03-01T10:00:00Z"
+	l.Wipe.FinishedAt = "2026-03-01T10:42:17Z"
+	l.Wipe.DurationSec = 2537
+	l.System.Info = sysinfo.Info{ToolVersion: "1.4.0", HostOS: "Ubuntu 22.04.4 LTS", Kernel: "6.5.0-21-generic", Hostname: "bench-3"}
+	l.System.ExecutedBy = "alex"
+	// Local times with nanoseconds, as the drivers record them
+	checked := time.Date(2026, 3, 1, 11, 0, 0, 123456789, time.FixedZone("CET", 3600))
+	l.Health = &drivers.HealthReport{Device: "/dev/sdc", Model: "Ultra Fit", Passed: true, PowerOnHours: 812, CheckedAt: checked}
+	l.Filesystem = &drivers.FilesystemInfo{MountPoint: "/media/alex/My Stick", Type: "vfat", Options: []string{"rw", "nosuid"}, Usage: drivers.Usage{Total: 30752636928, Used: 1 << 30}}
+	l.Capacity = &drivers.CapacityReport{Device: "/dev/sdc", ReportedBytes: 128000000000, UsableBytes: 8 << 30, ProbedBlocks: 1057, BadBlocks: 12, CheckedAt: checked}
+	l.USB = &drivers.USBInfo{VendorID: "0781", ProductID: "5583", Manufacturer: "SanDisk", Product: "Ultra Fit", Serial: "4C530001230712114275", SpeedMbps: 5000, Version: "3.20", Driver: "usb-storage"}
+	l.Execution = &Execution{
+		Trace: drivers.Trace{
+			Engines: []string{"overwrite"},
+			Passes:  []drivers.Pass{{Engine: "overwrite", Number: 1, Pattern: "random (AES-CTR keystream)", Bytes: 30752636928}},
+			Errors:  []drivers.TraceError{{Path: "/dev/sdc", Error: "write failed at byte 8589934592 of /dev/sdc: input/output error \"EIO\" <&>"}},
+		},
+		Host: HostFacts{User: "root", UID: "0", PID: 4242},
+	}
+	l.Ledger = &ledger.Link{Index: 17, PrevHash: strings.Repeat("ab", 32)}
+	return l
+}
+
+func TestSign(t *testing.T) {
+	key := testKey(t)
+	log := fullLog()
+	stamped := false
+	err := log.Sign(key, func(p certs.Payload) *certs.Timestamp {
+		stamped = true
+		return &certs.Timestamp{Status: certs.Unstamped, Error: "time-stamping is turned off"}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stamped || log.Signature.Sig == "" || log.Signature.KeyID != key.ID || log.Signature.Timestamp == nil {
+		t.Fatalf("signature block %+v", log.Signature)
+	}
+
+	// Saved and loaded again, as the JSON and PDF certificates are
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Log
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	for name, p := range map[string]func() (certs.Payload, error){
+		"saved JSON":  func() (certs.Payload, error) { return certs.ParseJSON(data) },
+		"loaded log":  loaded.Payload,
+		"signed copy": log.Payload,
+	} {
+		payload, err := p()
+		if err == nil {
+			err = certs.Verify(payload, []crypto.PublicKey{key.Public()})
+		}
+		if err != nil {
+			t.Errorf("%s does not verify: %v", name, err)
+		}
+	}
+
+	loaded.Capacity.UsableBytes = loaded.Capacity.ReportedBytes
+	payload, _ := loaded.Payload()
+	if err := certs.Verify(payload, []crypto.PublicKey{key.Public()}); err == nil {
+		t.Error("altered certificate verifies")
+	}
+}
+
+func TestSignRefusesUnverifiable(t *testing.T) {
+	// A key whose ID is not its own makes a signature no one can check
+	key := testKey(t)
+	key.ID = testKey(t).ID
+	log := fullLog()
+	if err := log.Sign(key, nil); err == nil || !strings.Contains(err.Error(), "would not verify") {
+		t.Fatalf("Sign = %v, want a refusal", err)
+	}
+	if log.Signature.Sig != "" || log.Signature.KeyID != "" {
+		t.Errorf("unverifiable log keeps signature %+v", log.Signature)
+	}
+
+	if err := log.Sign(nil, nil); err == nil {
+		t.Error("Sign without a key succeeded")
+	}
+}